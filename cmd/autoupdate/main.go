@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -18,6 +19,10 @@ import (
 	"github.com/openslides/openslides-autoupdate-service/internal/restrict"
 )
 
+// restricterBackoff is the time to wait before the first retry of the
+// restricter.
+const restricterBackoff = 100 * time.Millisecond
+
 func main() {
 	listenAddr := getEnv("AUTOUPDATE_HOST", "") + ":" + getEnv("AUTOUPDATE_PORT", "9012")
 	keepAliveRaw := getEnv("KEEP_ALIVE_DURATION", "30")
@@ -31,18 +36,58 @@ func main() {
 	}
 	fmt.Printf("Keep Alive Interval: %s\n", msg)
 
+	writeTimeoutRaw := getEnv("WRITE_TIMEOUT_DURATION", "0")
+	writeTimeout, err := strconv.Atoi(writeTimeoutRaw)
+	if err != nil {
+		log.Fatalf("Invalid value for WRITE_TIMEOUT_DURATION, got %s, expected an int: %v", writeTimeoutRaw, err)
+	}
+
+	restricterRetriesRaw := getEnv("RESTRICTER_RETRIES", "3")
+	restricterRetries, err := strconv.Atoi(restricterRetriesRaw)
+	if err != nil {
+		log.Fatalf("Invalid value for RESTRICTER_RETRIES, got %s, expected an int: %v", restricterRetriesRaw, err)
+	}
+
 	authService := buildAuth()
 	datastoreService, err := buildDatastore()
 	if err != nil {
 		log.Fatalf("Can not create datastore service: %v", err)
 	}
 
-	service := autoupdate.New(datastoreService, new(restrict.Restricter))
+	var service *autoupdate.Autoupdate
+	if configFile := getEnv("AUTOUPDATE_CONFIG", ""); configFile != "" {
+		service, err = autoupdate.NewServiceFromConfig(configFile, datastoreService, new(restrict.Restricter))
+		if err != nil {
+			log.Fatalf("Can not create autoupdate service: %v", err)
+		}
+	} else {
+		service = autoupdate.New(
+			datastoreService,
+			new(restrict.Restricter),
+			autoupdate.WithRestricterRetries(restricterRetries, restricterBackoff),
+		)
+	}
 
-	handler := autoupdateHttp.New(service, authService, time.Duration(keepAlive)*time.Second)
-	srv := &http.Server{Addr: listenAddr, Handler: handler}
+	handler := autoupdateHttp.New(
+		service,
+		authService,
+		time.Duration(keepAlive)*time.Second,
+		autoupdateHttp.WithWriteTimeout(time.Duration(writeTimeout)*time.Second),
+	)
+	srv := &http.Server{
+		Addr:        listenAddr,
+		Handler:     handler,
+		ConnContext: autoupdateHttp.ConnContext,
+	}
 	defer func() {
-		service.Close()
+		if err := service.Close(); err != nil {
+			log.Printf("Error on autoupdate service shutdown: %v", err)
+		}
+		if closer, ok := datastoreService.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.Printf("Error on datastore shutdown: %v", err)
+			}
+		}
 		if err := srv.Shutdown(context.Background()); err != nil {
 			log.Printf("Error on HTTP server shutdown: %v", err)
 		}
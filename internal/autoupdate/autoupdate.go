@@ -12,8 +12,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
 	"github.com/ostcar/topic"
 )
 
@@ -28,47 +31,174 @@ const pruneTime = time.Minute
 // The service updates its data in the background. To stop this background job,
 // the service has to be closed in the end with the Close()-method.
 type Autoupdate struct {
+	// activeNext is used with atomic and counts the calls to Connection.Next()
+	// that have not returned. When it gets zero, nextDone is signaled.
+	activeNext int32
+	nextDone   chan struct{}
+
+	// runningJobs is used with atomic and counts the running background
+	// jobs. It is used by LivenessProbe.
+	runningJobs int32
+
+	// lastDatastoreSuccess and lastDatastoreFailure are used with atomic and
+	// hold the unix time in nanoseconds of the last successful and failed
+	// call to the datastore. They are used by ReadinessProbe.
+	lastDatastoreSuccess int64
+	lastDatastoreFailure int64
+
 	datastore  Datastore
 	restricter Restricter
 	closed     chan struct{}
+	closeOnce  sync.Once
 	topic      *topic.Topic
+
+	// objects holds the key of the field id of all objects, that the
+	// service has seen. It is used by WatchCollection.
+	objects key.KeyIndex
+
+	// rawFeeds holds the shared connections of SubscribeRaw.
+	rawMu    sync.Mutex
+	rawFeeds map[string]*rawFeed
+
+	// subscribers holds the active subscriptions.
+	subscribers SubscriberRegistry
+
+	// computed holds the computed fields by their key.
+	computed map[string]ComputedField
+
+	pauseMu        sync.Mutex
+	paused         bool
+	pauseQueue     [][]string
+	pauseQueueSize int
+
+	batchUpdates      bool
+	notifyOnEmpty     bool
+	recurringInterval time.Duration
+	maxKeyRange       int
+	readinessWindow   time.Duration
+	readYourWrites    bool
+	idleTimeout       time.Duration
+	restricterRetries int
+	restricterBackoff time.Duration
+	errorPolicy       ErrorPolicy
+	updateFilter      SubscriptionFilter
+	clock             Clock
+
+	onSubscribe   func(userID int, keys []string)
+	onUpdate      func(userID int, numKeys int)
+	onUnsubscribe func(userID int)
 }
 
 // New creates a new autoupdate service.
 //
 // After the service is not needed anymore, it has to be closed with s.Close().
-func New(datastore Datastore, restricter Restricter) *Autoupdate {
+func New(datastore Datastore, restricter Restricter, options ...Option) *Autoupdate {
 	s := &Autoupdate{
 		datastore:  datastore,
 		restricter: restricter,
 		closed:     make(chan struct{}),
+		nextDone:   make(chan struct{}, 1),
+		clock:      systemClock{},
+
+		pauseQueueSize: defaultPauseQueueSize,
+		maxKeyRange:    key.DefaultMaxRange,
+
+		readinessWindow: defaultReadinessWindow,
+	}
+	for _, o := range options {
+		o(s)
+	}
+
+	if s.restricterRetries > 0 {
+		s.restricter = &retryableRestricter{
+			inner:      restricter,
+			maxRetries: s.restricterRetries,
+			backoff:    s.restricterBackoff,
+			closed:     s.closed,
+		}
+	}
+
+	if s.errorPolicy == FailKey {
+		s.restricter = &keyErrorRestricter{inner: s.restricter}
 	}
+
 	s.topic = topic.New(topic.WithClosed(s.closed))
 
+	atomic.AddInt32(&s.runningJobs, backgroundJobs)
 	go s.receiveKeyChanges()
 	go s.pruneTopic()
 
 	return s
 }
 
-// Close calls the shutdown logic of the service. This method is not save for
-// concourent use. It not allowed to call it more then once. Only the caller of
-// New() should call Close().
-func (a *Autoupdate) Close() {
-	close(a.closed)
+// closeTimeout is the time Close() waits for the connections.
+const closeTimeout = 5 * time.Second
+
+// Close calls the shutdown logic of the service like Shutdown() but waits at
+// most five seconds. Only the caller of New() should call Close().
+func (a *Autoupdate) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	return a.Shutdown(ctx)
+}
+
+// Shutdown stops the service. All connections get closed. It blocks until all
+// calls to Connection.Next() returned or the context is done. In the second
+// case, the error of the context is returned.
+//
+// It can be called more then once.
+func (a *Autoupdate) Shutdown(ctx context.Context) error {
+	a.closeOnce.Do(func() { close(a.closed) })
+
+	for atomic.LoadInt32(&a.activeNext) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-a.nextDone:
+		}
+	}
+	return nil
+}
+
+// startNext has to be called at the beginning of Connection.Next(). The
+// returned function has to be called, when Next() returns.
+func (a *Autoupdate) startNext() func() {
+	atomic.AddInt32(&a.activeNext, 1)
+	return func() {
+		if atomic.AddInt32(&a.activeNext, -1) > 0 {
+			return
+		}
+
+		// Wake up Shutdown(). If there is already a signal, it does not
+		// have to be sent again.
+		select {
+		case a.nextDone <- struct{}{}:
+		default:
+		}
+	}
 }
 
 // Connect has to be called by a client to register to the service. The method
 // returns a Connection object, that can be used to receive the data.
 //
 // There is no need to "close" the Connection object.
+//
+// If kb is a KeyExpirer, the expiring keys are removed from the connection
+// after their duration.
 func (a *Autoupdate) Connect(userID int, kb KeysBuilder, tid uint64) *Connection {
-	return &Connection{
+	c := &Connection{
 		autoupdate: a,
 		uid:        userID,
 		kb:         kb,
 		tid:        tid,
+		startedAt:  time.Now(),
 	}
+
+	if expiry := newExpiringKeys(kb, c.startedAt); expiry != nil {
+		c.expiry = expiry
+		c.kb = expiry
+	}
+	return c
 }
 
 // Value decodes the restricted value for the given key.
@@ -99,9 +229,95 @@ func (a *Autoupdate) LastID() uint64 {
 	return a.topic.LastID()
 }
 
+// UpdateKeys sets new values without the datastore-service and informs all
+// connections that requested one of the keys.
+//
+// The values can only be set, if the datastore has a SetIfExist method. In
+// other cases, the connections fetch the values from the datastore.
+func (a *Autoupdate) UpdateKeys(data map[string]json.RawMessage) {
+	type setter interface {
+		SetIfExist(data map[string]json.RawMessage)
+	}
+	if s, ok := a.datastore.(setter); ok {
+		s.SetIfExist(data)
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	a.publish(keys)
+}
+
+// defaultPauseQueueSize is the number of updates that are queued while the
+// service is paused.
+const defaultPauseQueueSize = 1000
+
+// Pause stops the delivery of updates to the connections without closing
+// them. This can be used for maintenance, for example a database migration.
+//
+// While the service is paused, updates are queued. If the queue is full, new
+// updates are merged into the last queued update so no changed key gets lost.
+func (a *Autoupdate) Pause() {
+	a.pauseMu.Lock()
+	defer a.pauseMu.Unlock()
+
+	a.paused = true
+}
+
+// Resume sends all queued updates to the connections and continues the
+// delivery of new updates.
+func (a *Autoupdate) Resume() {
+	a.pauseMu.Lock()
+	defer a.pauseMu.Unlock()
+
+	for _, keys := range a.pauseQueue {
+		a.topic.Publish(keys...)
+	}
+	a.pauseQueue = nil
+	a.paused = false
+}
+
+// publish sends the keys to the connections or queues them, if the service is
+// paused.
+func (a *Autoupdate) publish(keys []string) {
+	a.pauseMu.Lock()
+	defer a.pauseMu.Unlock()
+
+	a.indexObjects(keys)
+	if computed := a.computedKeys(keys); len(computed) > 0 {
+		keys = append(keys[:len(keys):len(keys)], computed...)
+	}
+
+	if !a.paused {
+		a.topic.Publish(keys...)
+		return
+	}
+
+	if len(a.pauseQueue) < a.pauseQueueSize || len(a.pauseQueue) == 0 {
+		a.pauseQueue = append(a.pauseQueue, keys)
+		return
+	}
+
+	last := a.pauseQueue[len(a.pauseQueue)-1]
+	known := make(map[string]bool, len(last))
+	for _, key := range last {
+		known[key] = true
+	}
+	for _, key := range keys {
+		if !known[key] {
+			last = append(last, key)
+			known[key] = true
+		}
+	}
+	a.pauseQueue[len(a.pauseQueue)-1] = last
+}
+
 // pruneTopic removes old data from the topic. Blocks until the service is
 // closed.
 func (a *Autoupdate) pruneTopic() {
+	defer atomic.AddInt32(&a.runningJobs, -1)
+
 	tick := time.NewTicker(time.Second)
 	defer tick.Stop()
 
@@ -118,6 +334,8 @@ func (a *Autoupdate) pruneTopic() {
 // receiveKeyChanges listens for updates and saves then into the topic. This
 // function blocks until the service is closed.
 func (a *Autoupdate) receiveKeyChanges() {
+	defer atomic.AddInt32(&a.runningJobs, -1)
+
 	for {
 		select {
 		case <-a.closed:
@@ -126,29 +344,42 @@ func (a *Autoupdate) receiveKeyChanges() {
 		}
 
 		keys, err := a.datastore.KeysChanged()
+		a.observeDatastore(context.Background(), err)
 		if err != nil {
 			log.Printf("Could not update keys: %v\n", err)
 			time.Sleep(time.Second)
 			continue
 		}
 
-		a.topic.Publish(keys...)
+		a.publish(keys)
 	}
 }
 
+// expandKeys expands keys with an id range like user/[1-100]/name.
+func (a *Autoupdate) expandKeys(keys []string) ([]string, error) {
+	return key.ExpandRanges(keys, a.maxKeyRange)
+}
+
 // restrictedData returns a map containing the restricted values for the given
 // keys.
 func (a *Autoupdate) restrictedData(ctx context.Context, uid int, keys ...string) (map[string]json.RawMessage, error) {
-	values, err := a.datastore.Get(ctx, keys...)
+	values, err := a.getWithComputed(ctx, keys...)
 	if err != nil {
 		return nil, fmt.Errorf("get values for keys `%v` from datastore: %w", keys, err)
 	}
 
 	data := make(map[string]json.RawMessage, len(keys))
+	var found []string
 	for i, key := range keys {
 		data[key] = values[i]
+		if len(values[i]) > 0 {
+			found = append(found, key)
+		}
 	}
+	a.indexObjects(found)
 
-	a.restricter.Restrict(uid, data)
+	if err := a.restricter.Restrict(uid, data); err != nil {
+		return nil, fmt.Errorf("restrict data for user %d: %w", uid, err)
+	}
 	return data, nil
 }
@@ -0,0 +1,64 @@
+package autoupdate_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+var _ io.Closer = new(autoupdate.Autoupdate)
+
+func TestClose(t *testing.T) {
+	datastore := &blockingDatastore{MockDatastore: test.NewMockDatastore()}
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+
+	waiting, release := datastore.blockNext()
+	go s.Connect(1, mockKeysBuilder{keys: test.Str("user/1/name")}, 0).Next(context.Background())
+	<-waiting
+
+	closeDone := make(chan error, 1)
+	go func() {
+		closeDone <- s.Close()
+	}()
+
+	select {
+	case <-closeDone:
+		t.Fatalf("Close() returned before the connection was closed")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+
+	select {
+	case err := <-closeDone:
+		if err != nil {
+			t.Errorf("Close() returned an unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Close() did not return after the connection was closed")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	datastore := &blockingDatastore{MockDatastore: test.NewMockDatastore()}
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+
+	waiting, release := datastore.blockNext()
+	defer release()
+
+	go s.Connect(1, mockKeysBuilder{keys: test.Str("user/1/name")}, 0).Next(context.Background())
+	<-waiting
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() returned `%v`, expected context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,34 @@
+package autoupdate
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// SubscribeRequest is one subscription for BatchSubscribe().
+type SubscribeRequest struct {
+	UserID int
+	Keys   []string
+}
+
+// BatchSubscribe opens many subscriptions like SubscribeReader(). The
+// subscriptions are opened atomically: If one of them fails, the others are
+// closed and the error is returned.
+//
+// The returned readers have the same order as the requests. All of them have
+// to be closed.
+func (a *Autoupdate) BatchSubscribe(ctx context.Context, requests []SubscribeRequest) ([]io.ReadCloser, error) {
+	readers := make([]io.ReadCloser, 0, len(requests))
+	for i, req := range requests {
+		r, err := a.SubscribeReader(ctx, req.UserID, req.Keys)
+		if err != nil {
+			for _, r := range readers {
+				r.Close()
+			}
+			return nil, fmt.Errorf("subscription %d: %w", i, err)
+		}
+		readers = append(readers, r)
+	}
+	return readers, nil
+}
@@ -0,0 +1,58 @@
+package autoupdate_test
+
+import (
+	"bufio"
+	"context"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestBatchSubscribe(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	readers, err := s.BatchSubscribe(context.Background(), []autoupdate.SubscribeRequest{
+		{UserID: 1, Keys: test.Str("user/1/name")},
+		{UserID: 2, Keys: test.Str("user/2/name")},
+	})
+	if err != nil {
+		t.Fatalf("BatchSubscribe() returned an unexpected error: %v", err)
+	}
+
+	if len(readers) != 2 {
+		t.Fatalf("Got %d readers, expected 2", len(readers))
+	}
+
+	for i, expect := range []string{"{\"user/1/name\":\"Hello World\"}\n", "{\"user/2/name\":\"Hello World\"}\n"} {
+		defer readers[i].Close()
+
+		line, err := bufio.NewReader(readers[i]).ReadString('\n')
+		if err != nil {
+			t.Fatalf("Can not read from reader %d: %v", i, err)
+		}
+
+		if line != expect {
+			t.Errorf("Reader %d returned %q, expected %q", i, line, expect)
+		}
+	}
+}
+
+func TestBatchSubscribeError(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	_, err := s.BatchSubscribe(context.Background(), []autoupdate.SubscribeRequest{
+		{UserID: 1, Keys: test.Str("user/1/name")},
+		{UserID: 1, Keys: test.Str("error/1/name")},
+	})
+
+	if err == nil {
+		t.Errorf("BatchSubscribe() did not return an error")
+	}
+}
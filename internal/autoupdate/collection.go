@@ -0,0 +1,135 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+// WatchCollection is like SubscribeReader but for the given fields of all
+// objects of a collection. Objects that are created later are added to the
+// stream. When an object is deleted, its fields are sent one last time with
+// the value null.
+//
+// The existing objects are fetched from the datastore, if it is a
+// CollectionLister. Otherwise, the service only knows the objects, that were
+// changed or requested since it was started. An object exists, as long as its
+// field id has a value.
+func (a *Autoupdate) WatchCollection(ctx context.Context, uid int, collection string, fields []string) (io.ReadCloser, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields")
+	}
+
+	for _, field := range fields {
+		if err := key.Validate(collection + "/1/" + field); err != nil {
+			return nil, err
+		}
+	}
+
+	if lister, ok := a.datastore.(CollectionLister); ok {
+		ids, err := lister.CollectionIDs(ctx, collection)
+		if err != nil {
+			return nil, fmt.Errorf("get ids of collection %s: %w", collection, err)
+		}
+
+		for _, id := range ids {
+			a.objects.Add(idKey(collection, id))
+		}
+	}
+
+	kb := &collectionKeys{
+		ctx:        ctx,
+		a:          a,
+		collection: collection,
+		fields:     fields,
+	}
+	if err := kb.Update(); err != nil {
+		return nil, fmt.Errorf("find objects of collection %s: %w", collection, err)
+	}
+
+	return connectionReader(ctx, a.Connect(uid, kb, a.LastID()))
+}
+
+// indexObjects adds the objects of the given keys to the object index of the
+// service.
+func (a *Autoupdate) indexObjects(keys []string) {
+	for _, k := range keys {
+		parsed, err := key.Parse(k)
+		if err != nil {
+			continue
+		}
+		a.objects.Add(idKey(parsed.Collection, parsed.ID))
+	}
+}
+
+// idKey returns the key of the field id of an object.
+func idKey(collection string, id int) string {
+	return collection + "/" + strconv.Itoa(id) + "/id"
+}
+
+// collectionKeys is a KeysBuilder for fields of all objects of a collection.
+type collectionKeys struct {
+	ctx        context.Context
+	a          *Autoupdate
+	collection string
+	fields     []string
+
+	keys []string
+
+	// deleted are the keys of objects, that were deleted by the last update.
+	// They are returned one more time, so the client gets the null values.
+	deleted []string
+}
+
+// Update looks for objects, that were created or deleted.
+func (c *collectionKeys) Update() error {
+	idKeys := c.a.objects.Range(c.collection + "/")
+
+	var values []json.RawMessage
+	if len(idKeys) > 0 {
+		var err error
+		values, err = c.a.getValues(c.ctx, idKeys...)
+		if err != nil {
+			return fmt.Errorf("get ids: %w", err)
+		}
+	}
+
+	exists := make(map[string]bool, len(c.keys))
+	var keys []string
+	for i, idKey := range idKeys {
+		if len(values[i]) == 0 || string(values[i]) == "null" {
+			continue
+		}
+
+		object := strings.TrimSuffix(idKey, "id")
+		for _, field := range c.fields {
+			k := object + field
+			keys = append(keys, k)
+			exists[k] = true
+		}
+	}
+
+	c.deleted = c.deleted[:0]
+	for _, k := range c.keys {
+		if !exists[k] {
+			c.deleted = append(c.deleted, k)
+		}
+	}
+
+	c.keys = keys
+	return nil
+}
+
+// Keys returns the keys of all existing objects and the keys of the objects
+// that were deleted by the last update.
+func (c *collectionKeys) Keys() []string {
+	if len(c.deleted) == 0 {
+		return c.keys
+	}
+	return append(append([]string{}, c.keys...), c.deleted...)
+}
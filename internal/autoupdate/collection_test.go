@@ -0,0 +1,99 @@
+package autoupdate_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestWatchCollection(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.OnlyData = true
+	datastore.Data = map[string]json.RawMessage{
+		"user/1/id":   []byte(`1`),
+		"user/1/name": []byte(`"hugo"`),
+	}
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	// user/1 was never requested. It is found by the ids of the collection.
+	r, err := s.WatchCollection(context.Background(), 1, "user", []string{"name"})
+	if err != nil {
+		t.Fatalf("WatchCollection() returned an unexpected error: %v", err)
+	}
+	defer r.Close()
+	stream := bufio.NewReader(r)
+
+	readFrame := func() map[string]json.RawMessage {
+		t.Helper()
+		line, err := stream.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("Can not read from stream: %v", err)
+		}
+
+		var data map[string]json.RawMessage
+		if err := json.Unmarshal(line, &data); err != nil {
+			t.Fatalf("Got invalid json: %v", err)
+		}
+		return data
+	}
+
+	if got := readFrame(); string(got["user/1/name"]) != `"hugo"` || len(got) != 1 {
+		t.Errorf("Got first frame %v, expected only user/1/name", got)
+	}
+
+	t.Run("create object", func(t *testing.T) {
+		datastore.Update(map[string]json.RawMessage{
+			"user/2/id":   []byte(`2`),
+			"user/2/name": []byte(`"emma"`),
+		})
+		datastore.Send(test.Str("user/2/id", "user/2/name"))
+
+		if got := readFrame(); string(got["user/2/name"]) != `"emma"` {
+			t.Errorf("Got frame %v, expected user/2/name", got)
+		}
+	})
+
+	t.Run("delete object", func(t *testing.T) {
+		datastore.Update(map[string]json.RawMessage{
+			"user/1/id":   nil,
+			"user/1/name": nil,
+		})
+		datastore.Send(test.Str("user/1/id", "user/1/name"))
+
+		got := readFrame()
+		if value, ok := got["user/1/name"]; !ok || string(value) != "null" {
+			t.Errorf("Got frame %v, expected user/1/name with null", got)
+		}
+	})
+}
+
+func TestWatchCollectionInvalid(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	for _, tt := range []struct {
+		name       string
+		collection string
+		fields     []string
+	}{
+		{"no fields", "user", nil},
+		{"no collection", "", []string{"name"}},
+		{"invalid field", "user", []string{"first/name"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := s.WatchCollection(context.Background(), 1, tt.collection, tt.fields)
+			if err == nil {
+				r.Close()
+				t.Errorf("WatchCollection() did not return an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+// ComputedField is a key, that does not exist in the datastore. Its value is
+// computed from the values of a field of all objects of a collection.
+//
+// Source is a key with the placeholder * as id, for example motion/*/id.
+// Aggregator is the name of the function, that computes the value. Possible
+// values are count, sum and max.
+//
+// The service only knows the objects, that were changed or requested since it
+// was started. Objects found by WatchCollection are also known.
+type ComputedField struct {
+	Key        string
+	Source     string
+	Aggregator string
+}
+
+// aggregators are the functions, that can be used by a ComputedField. They get
+// all non empty values of the source field and return the computed value.
+var aggregators = map[string]func(values []json.RawMessage) (json.RawMessage, error){
+	"count": aggregateCount,
+	"sum":   aggregateSum,
+	"max":   aggregateMax,
+}
+
+// aggregateCount returns the number of values.
+func aggregateCount(values []json.RawMessage) (json.RawMessage, error) {
+	return []byte(strconv.Itoa(len(values))), nil
+}
+
+// aggregateSum returns the sum of all values. The values have to be numbers.
+func aggregateSum(values []json.RawMessage) (json.RawMessage, error) {
+	var sum float64
+	for _, value := range values {
+		var n float64
+		if err := json.Unmarshal(value, &n); err != nil {
+			return nil, fmt.Errorf("decode value %s as number: %w", value, err)
+		}
+		sum += n
+	}
+	return json.Marshal(sum)
+}
+
+// aggregateMax returns the biggest value. The values have to be numbers. If
+// there are no values, null is returned.
+func aggregateMax(values []json.RawMessage) (json.RawMessage, error) {
+	if len(values) == 0 {
+		return []byte("null"), nil
+	}
+
+	var max float64
+	for i, value := range values {
+		var n float64
+		if err := json.Unmarshal(value, &n); err != nil {
+			return nil, fmt.Errorf("decode value %s as number: %w", value, err)
+		}
+		if i == 0 || n > max {
+			max = n
+		}
+	}
+	return json.Marshal(max)
+}
+
+// computedSource returns the collection and the field of the source of a
+// computed field.
+func computedSource(source string) (collection, field string, err error) {
+	parts := strings.Split(source, "/")
+	if len(parts) != 3 || parts[1] != "*" {
+		return "", "", fmt.Errorf("invalid source %s, expected collection/*/field", source)
+	}
+	return parts[0], parts[2], nil
+}
+
+// computedKeys returns the keys of all computed fields, that have one of the
+// given keys as source.
+func (a *Autoupdate) computedKeys(keys []string) []string {
+	if len(a.computed) == 0 {
+		return nil
+	}
+
+	var computed []string
+	for _, cf := range a.computed {
+		collection, field, err := computedSource(cf.Source)
+		if err != nil {
+			continue
+		}
+
+		for _, k := range keys {
+			parsed, err := key.Parse(k)
+			if err != nil {
+				continue
+			}
+
+			if parsed.Collection == collection && parsed.Field == field {
+				computed = append(computed, cf.Key)
+				break
+			}
+		}
+	}
+	return computed
+}
+
+// computeValue computes the value of a computed field.
+func (a *Autoupdate) computeValue(ctx context.Context, cf ComputedField) (json.RawMessage, error) {
+	aggregate, ok := aggregators[cf.Aggregator]
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregator %s", cf.Aggregator)
+	}
+
+	collection, field, err := computedSource(cf.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	idKeys := a.objects.Range(collection + "/")
+	if len(idKeys) == 0 {
+		return aggregate(nil)
+	}
+
+	sourceKeys := make([]string, len(idKeys))
+	for i, idKey := range idKeys {
+		sourceKeys[i] = strings.TrimSuffix(idKey, "id") + field
+	}
+
+	values, err := a.getValues(ctx, sourceKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("get values of %s: %w", cf.Source, err)
+	}
+
+	found := values[:0]
+	for _, value := range values {
+		if len(value) == 0 || string(value) == "null" {
+			continue
+		}
+		found = append(found, value)
+	}
+	return aggregate(found)
+}
+
+// getWithComputed is like datastore.Get() but computes the values of computed
+// fields instead of fetching them from the datastore.
+func (a *Autoupdate) getWithComputed(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	if len(a.computed) == 0 {
+		return a.getValues(ctx, keys...)
+	}
+
+	var stored []string
+	var storedIdx []int
+	values := make([]json.RawMessage, len(keys))
+	for i, k := range keys {
+		cf, ok := a.computed[k]
+		if !ok {
+			stored = append(stored, k)
+			storedIdx = append(storedIdx, i)
+			continue
+		}
+
+		value, err := a.computeValue(ctx, cf)
+		if err != nil {
+			return nil, fmt.Errorf("compute %s: %w", k, err)
+		}
+		values[i] = value
+	}
+
+	if len(stored) == 0 {
+		return values, nil
+	}
+
+	storedValues, err := a.getValues(ctx, stored...)
+	if err != nil {
+		return nil, err
+	}
+	for i, idx := range storedIdx {
+		values[idx] = storedValues[i]
+	}
+	return values, nil
+}
@@ -0,0 +1,111 @@
+package autoupdate_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestComputedFields(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		aggregator string
+		source     string
+		expect     string
+		expectNext string
+	}{
+		{"count", "count", "motion/*/id", `2`, `3`},
+		{"sum", "sum", "motion/*/weight", `5`, `12`},
+		{"max", "max", "motion/*/weight", `3`, `7`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			datastore := test.NewMockDatastore()
+			defer datastore.Close()
+			datastore.OnlyData = true
+			datastore.Data = map[string]json.RawMessage{
+				"motion/1/id":     []byte(`1`),
+				"motion/1/weight": []byte(`2`),
+				"motion/2/id":     []byte(`2`),
+				"motion/2/weight": []byte(`3`),
+			}
+
+			s := autoupdate.New(
+				datastore,
+				new(test.MockRestricter),
+				autoupdate.WithComputedFields(autoupdate.ComputedField{
+					Key:        "stats/1/motion_" + tt.name,
+					Source:     tt.source,
+					Aggregator: tt.aggregator,
+				}),
+			)
+			defer s.Close()
+
+			// The service only knows objects it has seen.
+			var ignore json.RawMessage
+			for _, k := range test.Str("motion/1/id", "motion/2/id") {
+				if err := s.Value(context.Background(), 1, k, &ignore); err != nil {
+					t.Fatalf("Value() returned an unexpected error: %v", err)
+				}
+			}
+
+			r, err := s.SubscribeReader(context.Background(), 1, test.Str("stats/1/motion_"+tt.name))
+			if err != nil {
+				t.Fatalf("SubscribeReader() returned an unexpected error: %v", err)
+			}
+			defer r.Close()
+			stream := bufio.NewReader(r)
+
+			readValue := func() string {
+				t.Helper()
+				line, err := stream.ReadBytes('\n')
+				if err != nil {
+					t.Fatalf("Can not read from stream: %v", err)
+				}
+
+				var data map[string]json.RawMessage
+				if err := json.Unmarshal(line, &data); err != nil {
+					t.Fatalf("Got invalid json: %v", err)
+				}
+				return string(data["stats/1/motion_"+tt.name])
+			}
+
+			if got := readValue(); got != tt.expect {
+				t.Errorf("Got %s, expected %s", got, tt.expect)
+			}
+
+			datastore.Update(map[string]json.RawMessage{
+				"motion/3/id":     []byte(`3`),
+				"motion/3/weight": []byte(`7`),
+			})
+			datastore.Send(test.Str("motion/3/id", "motion/3/weight"))
+
+			if got := readValue(); got != tt.expectNext {
+				t.Errorf("Got %s after update, expected %s", got, tt.expectNext)
+			}
+		})
+	}
+}
+
+func TestComputedFieldsUnknownAggregator(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+
+	s := autoupdate.New(
+		datastore,
+		new(test.MockRestricter),
+		autoupdate.WithComputedFields(autoupdate.ComputedField{
+			Key:        "stats/1/motion_avg",
+			Source:     "motion/*/id",
+			Aggregator: "avg",
+		}),
+	)
+	defer s.Close()
+
+	if _, err := s.SubscribeReader(context.Background(), 1, test.Str("stats/1/motion_avg")); err == nil {
+		t.Errorf("SubscribeReader() returned no error for an unknown aggregator")
+	}
+}
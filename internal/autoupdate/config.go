@@ -0,0 +1,110 @@
+package autoupdate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// ServiceConfig holds the options of the service, that can be read from a
+// json file. Durations are strings like "30s", that can be parsed with
+// time.ParseDuration. Empty or zero values mean, that the option is not set.
+type ServiceConfig struct {
+	BatchUpdates            bool   `json:"batch_updates"`
+	NotifyOnEmpty           bool   `json:"notify_on_empty"`
+	RecurringUpdateInterval string `json:"recurring_update_interval"`
+	IdleTimeout             string `json:"idle_timeout"`
+	RestricterRetries       int    `json:"restricter_retries"`
+	RestricterBackoff       string `json:"restricter_backoff"`
+	PauseQueueSize          int    `json:"pause_queue_size"`
+}
+
+// LoadConfig reads a ServiceConfig from the json file at path. Unknown fields
+// return an error, so a typo in the file is not ignored.
+func LoadConfig(path string) (ServiceConfig, error) {
+	var config ServiceConfig
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("read config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("decode config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Options returns the options for autoupdate.New() from the config.
+func (c ServiceConfig) Options() ([]Option, error) {
+	options := []Option{
+		WithBatchUpdates(c.BatchUpdates),
+		WithNotifyOnEmpty(c.NotifyOnEmpty),
+	}
+
+	durations := []struct {
+		name  string
+		value string
+		set   func(time.Duration)
+	}{
+		{"recurring_update_interval", c.RecurringUpdateInterval, func(d time.Duration) {
+			options = append(options, WithRecurringUpdateInterval(d))
+		}},
+		{"idle_timeout", c.IdleTimeout, func(d time.Duration) {
+			options = append(options, WithIdleTimeout(d))
+		}},
+	}
+
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", d.name, err)
+		}
+		d.set(parsed)
+	}
+
+	if c.RestricterRetries > 0 {
+		var backoff time.Duration
+		if c.RestricterBackoff != "" {
+			var err error
+			backoff, err = time.ParseDuration(c.RestricterBackoff)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for restricter_backoff: %w", err)
+			}
+		}
+		options = append(options, WithRestricterRetries(c.RestricterRetries, backoff))
+	}
+
+	if c.PauseQueueSize > 0 {
+		options = append(options, WithPauseQueueSize(c.PauseQueueSize))
+	}
+
+	return options, nil
+}
+
+// NewServiceFromConfig is like New() but reads the options from the json file
+// at path. See ServiceConfig for the format of the file.
+//
+// The given options are used after the options from the file. They can be
+// used for options that can not be set in a file, like hooks.
+func NewServiceFromConfig(path string, datastore Datastore, restricter Restricter, options ...Option) (*Autoupdate, error) {
+	config, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	configOptions, err := config.Options()
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return New(datastore, restricter, append(configOptions, options...)...), nil
+}
@@ -0,0 +1,95 @@
+package autoupdate_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+// writeConfig writes content into a file in a new temporary directory and
+// returns its path and a function to remove the directory.
+func writeConfig(t *testing.T, content string) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "autoupdate-config")
+	if err != nil {
+		t.Fatalf("Can not create temp dir: %v", err)
+	}
+
+	path := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Can not write config file: %v", err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestNewServiceFromConfig(t *testing.T) {
+	path, cleanup := writeConfig(t, `{"idle_timeout": "1m", "restricter_retries": 2, "restricter_backoff": "10ms", "pause_queue_size": 5}`)
+	defer cleanup()
+
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+
+	s, err := autoupdate.NewServiceFromConfig(path, datastore, new(test.MockRestricter))
+	if err != nil {
+		t.Fatalf("NewServiceFromConfig() returned an unexpected error: %v", err)
+	}
+	defer s.Close()
+}
+
+func TestLoadConfig(t *testing.T) {
+	path, cleanup := writeConfig(t, `{"batch_updates": true, "idle_timeout": "1m", "pause_queue_size": 5}`)
+	defer cleanup()
+
+	config, err := autoupdate.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() returned an unexpected error: %v", err)
+	}
+
+	expect := autoupdate.ServiceConfig{BatchUpdates: true, IdleTimeout: "1m", PauseQueueSize: 5}
+	if config != expect {
+		t.Errorf("Got config %+v, expected %+v", config, expect)
+	}
+}
+
+func TestSampleConfig(t *testing.T) {
+	config, err := autoupdate.LoadConfig("../../config.json")
+	if err != nil {
+		t.Fatalf("LoadConfig() returned an unexpected error: %v", err)
+	}
+
+	if _, err := config.Options(); err != nil {
+		t.Errorf("Options() returned an unexpected error: %v", err)
+	}
+}
+
+func TestNewServiceFromConfigErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+	}{
+		{"invalid json", `{"batch_updates": tru`},
+		{"unknown field", `{"batch_update": true}`},
+		{"invalid duration", `{"idle_timeout": "one minute"}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path, cleanup := writeConfig(t, tt.content)
+			defer cleanup()
+
+			if _, err := autoupdate.NewServiceFromConfig(path, nil, nil); err == nil {
+				t.Errorf("NewServiceFromConfig() did not return an error")
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := autoupdate.NewServiceFromConfig("does/not/exist.json", nil, nil); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("NewServiceFromConfig() returned %v, expected a not exist error", err)
+		}
+	})
+}
@@ -3,50 +3,290 @@ package autoupdate
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 )
 
 // Connection holds the state of a client. It has to be created by colling
 // Connect() on a autoupdate.Service instance.
 type Connection struct {
+	// updatesSent, bytesWritten and lastUpdateAt are used with atomic and have
+	// to be at the beginning of the struct to be 64 bit aligned on 32 bit
+	// systems.
+	updatesSent  uint64
+	bytesWritten uint64
+	lastUpdateAt int64
+
 	autoupdate *Autoupdate
 	uid        int
 	kb         KeysBuilder
 	tid        uint64
 	filter     *filter
+
+	// expiry is the KeysBuilder of the connection, if it has keys that
+	// expire. In this case, it is also used as kb.
+	expiry *expiringKeys
+
+	// err is returned by each call to Next, if it is set.
+	err error
+
+	// emptyCycles counts the update cycles in a row, where all keys were
+	// empty.
+	emptyCycles int
+
+	// lastSent is the time, when Next returned data the last time.
+	lastSent time.Time
+
+	// stopIdle stops the idle timer, that was started, when Next returned the
+	// last time.
+	stopIdle func() bool
+
+	// idleMu protects idle and onIdle. They are set by the idle timer.
+	idleMu sync.Mutex
+	idle   bool
+	onIdle func()
+
+	// startedAt is the time, when the connection was created.
+	startedAt time.Time
+
+	// subscribed is true, after the OnSubscribe hook was called.
+	// unsubscribed is true, after the OnUnsubscribe hook was called.
+	subscribed   bool
+	unsubscribed bool
 }
 
 // Next returns the next data for the user.
 //
 // Next blocks until there are new data or the context or the server closes. In
 // this case, nil is returned.
-func (c *Connection) Next(ctx context.Context) (map[string]json.RawMessage, error) {
+func (c *Connection) Next(ctx context.Context) (data map[string]json.RawMessage, err error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	defer c.autoupdate.startNext()()
+	defer func(first bool) { c.callHooks(first, data, err) }(c.filter == nil)
+
+	if c.stopIdle != nil {
+		stopped := c.stopIdle()
+		c.stopIdle = nil
+		if !stopped {
+			c.err = IdleTimeoutError{}
+			return nil, c.err
+		}
+	}
+
+	if timeout := c.autoupdate.idleTimeout; timeout > 0 {
+		defer func() {
+			if err == nil {
+				c.stopIdle = c.autoupdate.clock.AfterFunc(timeout, c.idleTimeout)
+			}
+		}()
+	}
+
 	if c.filter == nil {
 		// First time called
-		c.filter = new(filter)
+		if err := c.autoupdate.waitForWriteVersion(ctx); err != nil {
+			return nil, fmt.Errorf("wait for the datastore: %w", err)
+		}
+
+		c.filter = &filter{update: c.autoupdate.updateFilter}
 		if c.tid == 0 {
 			c.tid = c.autoupdate.topic.LastID()
 		}
 
-		data, err := c.autoupdate.restrictedData(ctx, c.uid, c.kb.Keys()...)
+		data, err := c.allData(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("get first time restricted data: %w", err)
+			return nil, fmt.Errorf("get first time data: %w", err)
+		}
+
+		if err := c.filter.filter(data); err != nil {
+			return nil, fmt.Errorf("filter data for the first time: %w", err)
 		}
 
-		// Delete empty values in first responce.
-		for k, v := range data {
-			if len(v) == 0 {
-				delete(data, k)
+		c.lastSent = time.Now()
+		c.updateSent(c.lastSent)
+		return data, nil
+	}
+
+	data, err = c.next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.lastSent = time.Now()
+	c.updateSent(c.lastSent)
+	return data, nil
+}
+
+// idleTimeout is called by the idle timer.
+func (c *Connection) idleTimeout() {
+	c.idleMu.Lock()
+	c.idle = true
+	f := c.onIdle
+	c.idleMu.Unlock()
+
+	if f != nil {
+		f()
+	}
+}
+
+// setOnIdle sets a function, that is called, when the idle timer runs out. If
+// it already ran out, f is called immediately.
+func (c *Connection) setOnIdle(f func()) {
+	c.idleMu.Lock()
+	idle := c.idle
+	if !idle {
+		c.onIdle = f
+	}
+	c.idleMu.Unlock()
+
+	if idle {
+		f()
+	}
+}
+
+// callHooks calls the hooks of the service after Next returned.
+//
+// The first data is reported with OnSubscribe and each later data with
+// OnUpdate. The first error after the subscription is reported with
+// OnUnsubscribe. After that, no hook is called for the connection.
+func (c *Connection) callHooks(first bool, data map[string]json.RawMessage, err error) {
+	a := c.autoupdate
+	if c.unsubscribed {
+		return
+	}
+
+	if err != nil {
+		if c.subscribed {
+			c.unsubscribed = true
+			if a.onUnsubscribe != nil {
+				a.onUnsubscribe(c.uid)
 			}
 		}
+		return
+	}
 
-		if err := c.filter.filter(data); err != nil {
-			return nil, fmt.Errorf("filter data for the first time: %w", err)
+	if first {
+		c.subscribed = true
+		if a.onSubscribe != nil {
+			a.onSubscribe(c.uid, c.kb.Keys())
 		}
+		return
+	}
 
+	if a.onUpdate != nil {
+		a.onUpdate(c.uid, len(data))
+	}
+}
+
+// Version returns the version of the data, that was returned by the last call
+// of Next.
+//
+// The version is the id of the last update of the service, that was handled.
+// It is the same for all connections of a service and increases with each
+// update. So a client can compare versions, but a gap does not mean, that the
+// client missed data for its keys.
+//
+// It must not be called at the same time as Next.
+func (c *Connection) Version() uint64 {
+	return c.tid
+}
+
+// next returns the data after the first call of Next.
+func (c *Connection) next(ctx context.Context) (map[string]json.RawMessage, error) {
+	for {
+		data, err := c.receiveOrExpire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if data == nil {
+			// No data. Try again.
+			continue
+		}
+
+		if c.autoupdate.batchUpdates {
+			// Merge all updates, that are already available, into this
+			// response.
+			for c.autoupdate.topic.LastID() > c.tid {
+				more, err := c.receive(ctx)
+				if err != nil {
+					return nil, err
+				}
+
+				for k, v := range more {
+					data[k] = v
+				}
+			}
+		}
+
+		if c.autoupdate.notifyOnEmpty {
+			if !c.allEmpty() {
+				c.emptyCycles = 0
+				return data, nil
+			}
+
+			c.emptyCycles++
+			if c.emptyCycles >= 2 {
+				return nil, DeletedError{}
+			}
+		}
+		return data, nil
+	}
+}
+
+// receiveOrRefresh calls receive. If a recurring update interval is set and
+// there was no data for this interval, the values of all keys are returned.
+func (c *Connection) receiveOrRefresh(ctx context.Context) (map[string]json.RawMessage, error) {
+	interval := c.autoupdate.recurringInterval
+	if interval <= 0 {
+		return c.receive(ctx)
+	}
+
+	receiveCtx, cancel := context.WithDeadline(ctx, c.lastSent.Add(interval))
+	defer cancel()
+
+	data, err := c.receive(receiveCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		data, err = c.allData(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get data for recurring update: %w", err)
+		}
 		return data, nil
 	}
+	return data, err
+}
+
+// allData returns the restricted values of all keys. Empty values are removed.
+func (c *Connection) allData(ctx context.Context) (map[string]json.RawMessage, error) {
+	data, err := c.autoupdate.restrictedData(ctx, c.uid, c.kb.Keys()...)
+	if err != nil {
+		return nil, fmt.Errorf("get restricted data: %w", err)
+	}
+
+	for k, v := range data {
+		if len(v) == 0 {
+			delete(data, k)
+		}
+	}
+	return data, nil
+}
+
+// allEmpty returns true, if the last values of all requested keys are empty.
+func (c *Connection) allEmpty() bool {
+	for _, key := range c.kb.Keys() {
+		if c.filter.history[key] != 0 {
+			return false
+		}
+	}
+	return true
+}
 
+// receive waits for the next update from the topic and returns the changed
+// data for the connection. If no requested key has changed, nil is returned.
+func (c *Connection) receive(ctx context.Context) (map[string]json.RawMessage, error) {
 	var err error
 	var changedKeys []string
 
@@ -80,10 +320,15 @@ func (c *Connection) Next(ctx context.Context) (map[string]json.RawMessage, erro
 	}
 
 	if len(keys) == 0 {
-		// No data. Try again.
-		return c.Next(ctx)
+		return nil, nil
 	}
 
+	return c.changedData(ctx, keys...)
+}
+
+// changedData returns the restricted values of the given keys, that are
+// different from the values, that where sent to the client before.
+func (c *Connection) changedData(ctx context.Context, keys ...string) (map[string]json.RawMessage, error) {
 	data, err := c.autoupdate.restrictedData(ctx, c.uid, keys...)
 	if err != nil {
 		return nil, fmt.Errorf("restrict data: %w", err)
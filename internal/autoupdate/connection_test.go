@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
 	"github.com/openslides/openslides-autoupdate-service/internal/test"
@@ -67,6 +69,28 @@ func TestConnectionReadNewData(t *testing.T) {
 	}
 }
 
+func TestConnectionVersion(t *testing.T) {
+	c, datastore, close := getConnection()
+	defer close()
+
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+	version := c.Version()
+
+	for i := 1; i <= 3; i++ {
+		datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(fmt.Sprintf(`"value %d"`, i))})
+		datastore.Send(test.Str("user/1/name"))
+		if _, err := c.Next(context.Background()); err != nil {
+			t.Fatalf("c.Next() returned an error: %v", err)
+		}
+
+		if got := c.Version(); got != version+uint64(i) {
+			t.Errorf("Got version %d after update %d, expected %d", got, i, version+uint64(i))
+		}
+	}
+}
+
 func TestConnectionEmptyData(t *testing.T) {
 	const (
 		doesNotExistKey = "doesnot/1/exist"
@@ -284,3 +308,188 @@ func BenchmarkFilterNotChanging(b *testing.B) {
 		datastore.Send(keys)
 	}
 }
+
+func TestConnectionBatchUpdates(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		batch  bool
+		expect []string
+	}{
+		{"without batch", false, test.Str("user/1/name")},
+		{"with batch", true, test.Str("user/1/name", "user/2/name")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			datastore := &blockingDatastore{MockDatastore: test.NewMockDatastore()}
+			defer datastore.Close()
+			s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithBatchUpdates(tt.batch))
+			defer s.Close()
+			kb := mockKeysBuilder{keys: test.Str("user/1/name", "user/2/name")}
+			c := s.Connect(1, kb, 0)
+			if _, err := c.Next(context.Background()); err != nil {
+				t.Fatalf("c.Next() returned an error: %v", err)
+			}
+
+			// Block the connection after it received the first update, so
+			// the second update is available when it sends the data.
+			waiting, release := datastore.blockNext()
+			datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(`"new1"`)})
+			datastore.Send(test.Str("user/1/name"))
+
+			type result struct {
+				data map[string]json.RawMessage
+				err  error
+			}
+			done := make(chan result)
+			go func() {
+				data, err := c.Next(context.Background())
+				done <- result{data, err}
+			}()
+
+			<-waiting
+			lastID := s.LastID()
+			datastore.Update(map[string]json.RawMessage{"user/2/name": []byte(`"new2"`)})
+			datastore.Send(test.Str("user/2/name"))
+			waitForID(t, s, lastID+1)
+			release()
+
+			r := <-done
+			if r.err != nil {
+				t.Fatalf("c.Next() returned an error: %v", r.err)
+			}
+
+			got := make([]string, 0, len(r.data))
+			for k := range r.data {
+				got = append(got, k)
+			}
+			sort.Strings(got)
+			if !test.CmpSlice(got, tt.expect) {
+				t.Errorf("c.Next() returned keys %v, expected %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+// waitForID blocks until the autoupdate service has received the update with
+// the given id.
+func waitForID(t *testing.T, s *autoupdate.Autoupdate, id uint64) {
+	timeout := time.After(time.Second)
+	for s.LastID() < id {
+		select {
+		case <-timeout:
+			t.Fatalf("Update %d was not received", id)
+		default:
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestConnectionNotifyOnEmpty(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.Data = map[string]json.RawMessage{
+		"user/1/name":  []byte(`"name"`),
+		"user/1/email": []byte(`"email"`),
+	}
+	datastore.OnlyData = true
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithNotifyOnEmpty(true))
+	defer s.Close()
+	kb := mockKeysBuilder{keys: test.Str("user/1/name", "user/1/email")}
+	c := s.Connect(1, kb, 0)
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+
+	// Delete the object.
+	datastore.Update(map[string]json.RawMessage{"user/1/name": nil, "user/1/email": nil})
+	datastore.Send(test.Str("user/1/name", "user/1/email"))
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("c.Next() after the first empty cycle returned an error: %v", err)
+	}
+
+	datastore.Send(test.Str("user/1/name"))
+	_, err := c.Next(context.Background())
+
+	var deleted autoupdate.DeletedError
+	if !errors.As(err, &deleted) {
+		t.Errorf("c.Next() after the second empty cycle returned error `%v`, expected a DeletedError", err)
+	}
+}
+
+func TestConnectionNotifyOnEmptyOneKeyLeft(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.Data = map[string]json.RawMessage{
+		"user/1/name":  []byte(`"name"`),
+		"user/1/email": []byte(`"email"`),
+	}
+	datastore.OnlyData = true
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithNotifyOnEmpty(true))
+	defer s.Close()
+	kb := mockKeysBuilder{keys: test.Str("user/1/name", "user/1/email")}
+	c := s.Connect(1, kb, 0)
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+
+	// Only delete one key.
+	datastore.Update(map[string]json.RawMessage{"user/1/name": nil})
+	for i := 0; i < 3; i++ {
+		datastore.Send(test.Str("user/1/name"))
+		if _, err := c.Next(context.Background()); err != nil {
+			t.Fatalf("c.Next() returned an error: %v", err)
+		}
+	}
+}
+
+func TestConnectionRecurringUpdate(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithRecurringUpdateInterval(20*time.Millisecond))
+	defer s.Close()
+	c := s.Connect(1, mockKeysBuilder{keys: test.Str("user/1/name")}, 0)
+
+	first, err := c.Next(context.Background())
+	if err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	second, err := c.Next(ctx)
+	if err != nil {
+		t.Fatalf("Second c.Next() returned an error: %v", err)
+	}
+
+	if len(second) != len(first) || string(second["user/1/name"]) != string(first["user/1/name"]) {
+		t.Errorf("Recurring update returned %v, expected %v", second, first)
+	}
+}
+
+func TestConnectionIdleTimeout(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	clock := &mockClock{now: time.Now()}
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithIdleTimeout(time.Minute), autoupdate.WithClock(clock))
+	defer s.Close()
+	c := s.Connect(1, mockKeysBuilder{keys: test.Str("user/1/name")}, 0)
+
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("c.Next() returned an error: %v", err)
+	}
+
+	// Reading in time does not close the connection.
+	clock.Advance(30 * time.Second)
+	datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(`"new value"`)})
+	datastore.Send(test.Str("user/1/name"))
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("c.Next() before the idle timeout returned an error: %v", err)
+	}
+
+	clock.Advance(2 * time.Minute)
+	_, err := c.Next(context.Background())
+
+	var idle autoupdate.IdleTimeoutError
+	if !errors.As(err, &idle) {
+		t.Errorf("c.Next() after the idle timeout returned error %v, expected an IdleTimeoutError", err)
+	}
+}
@@ -0,0 +1,19 @@
+package autoupdate
+
+import (
+	"context"
+	"fmt"
+)
+
+// DryRun simulates a connection for the given keys without sending any data.
+// It validates the keys, fetches the values and runs the restricter like for
+// the first data of a connection.
+//
+// Returns the number of keys that would have been sent to the client.
+func (a *Autoupdate) DryRun(ctx context.Context, uid int, keys []string) (int, error) {
+	data, err := a.SubscribeOnce(ctx, uid, keys)
+	if err != nil {
+		return 0, fmt.Errorf("simulate first data: %w", err)
+	}
+	return len(data), nil
+}
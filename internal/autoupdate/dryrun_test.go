@@ -0,0 +1,70 @@
+package autoupdate_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestDryRun(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	got, err := s.DryRun(context.Background(), 1, test.Str("user/1/name", "user/2/name"))
+	if err != nil {
+		t.Fatalf("DryRun() returned an unexpected error: %v", err)
+	}
+
+	if got != 2 {
+		t.Errorf("DryRun() returned %d, expected 2", got)
+	}
+}
+
+func TestDryRunInvalidKey(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	_, err := s.DryRun(context.Background(), 1, test.Str("user/1/name", "user/2"))
+
+	var invalid key.InvalidKeyError
+	if !errors.As(err, &invalid) {
+		t.Errorf("DryRun() returned error `%v`, expected an InvalidKeyError", err)
+	}
+}
+
+func BenchmarkDryRun1000Connections(b *testing.B) {
+	const connections = 1000
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	keys := make([]string, 0, 10)
+	for i := 0; i < 10; i++ {
+		keys = append(keys, fmt.Sprintf("user/%d/name", i))
+	}
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		var wg sync.WaitGroup
+		wg.Add(connections)
+		for i := 0; i < connections; i++ {
+			go func(uid int) {
+				defer wg.Done()
+				s.DryRun(context.Background(), uid, keys)
+			}(i)
+		}
+		wg.Wait()
+	}
+}
@@ -1,6 +1,7 @@
 package autoupdate
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -43,3 +44,72 @@ func (e NotExistError) Type() string {
 func (e NotExistError) KeyDoesNotExist() bool {
 	return true
 }
+
+// DeletedError is returned by Connection.Next(), when all requested keys do
+// not exist anymore. It is only returned, when the service was created with
+// the option WithNotifyOnEmpty.
+type DeletedError struct{}
+
+func (e DeletedError) Error() string {
+	return "all requested keys were deleted"
+}
+
+// Closing tells, that the connection ends and no more data will be sent.
+func (e DeletedError) Closing() {}
+
+// CloseFrame is sent to the client as last message.
+func (e DeletedError) CloseFrame() map[string]string {
+	return map[string]string{"closed": "object_deleted"}
+}
+
+// IdleTimeoutError is returned by Connection.Next(), when the idle timer ran
+// out after the last call of Next returned. It is only returned, when
+// the service was created with the option WithIdleTimeout.
+type IdleTimeoutError struct{}
+
+func (e IdleTimeoutError) Error() string {
+	return "connection was idle for too long"
+}
+
+// Closing tells, that the connection ends and no more data will be sent.
+func (e IdleTimeoutError) Closing() {}
+
+// CloseFrame is sent to the client as last message.
+func (e IdleTimeoutError) CloseFrame() map[string]string {
+	return map[string]string{"close": "idle_timeout"}
+}
+
+// ErrKeyNotAllowed is returned by the FilteredService for keys that are not in
+// the allowlist. Use errors.Is() to check for it.
+var ErrKeyNotAllowed = errors.New("key not allowed")
+
+// KeyNotAllowedError is returned by the FilteredService for a key that is not
+// in the allowlist.
+type KeyNotAllowedError struct {
+	Key string
+}
+
+func (e KeyNotAllowedError) Error() string {
+	return fmt.Sprintf("the key %s is not allowed", e.Key)
+}
+
+// Type returns the name of the error.
+func (e KeyNotAllowedError) Type() string {
+	return "KeyNotAllowedError"
+}
+
+// Is makes the error comparable to ErrKeyNotAllowed.
+func (e KeyNotAllowedError) Is(target error) bool {
+	return target == ErrKeyNotAllowed
+}
+
+// closedError is returned, when the service is closed while a connection
+// waits.
+type closedError struct{}
+
+func (e closedError) Error() string {
+	return "service is closed"
+}
+
+// Closing tells, that the error is returned because the service is closed.
+func (e closedError) Closing() {}
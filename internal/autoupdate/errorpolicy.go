@@ -0,0 +1,49 @@
+package autoupdate
+
+import "encoding/json"
+
+// ErrorPolicy decides, how an error from the restricter affects the other keys
+// that are restricted at the same time.
+type ErrorPolicy int
+
+const (
+	// FailAll returns the error of the restricter. No key of the batch is
+	// delivered. This is the default.
+	FailAll ErrorPolicy = iota
+
+	// FailKey restricts each key on its own, when the restricter returns an
+	// error for the batch. The keys, that still return an error, get the value
+	// null. The other keys are delivered normally.
+	FailKey
+)
+
+// keyErrorRestricter implements the ErrorPolicy FailKey.
+type keyErrorRestricter struct {
+	inner Restricter
+}
+
+// Restrict calls the inner restricter with all keys. If it fails, each key is
+// restricted separately.
+func (r *keyErrorRestricter) Restrict(uid int, data map[string]json.RawMessage) error {
+	batch := make(map[string]json.RawMessage, len(data))
+	for k, v := range data {
+		batch[k] = v
+	}
+
+	if err := r.inner.Restrict(uid, batch); err == nil {
+		for k, v := range batch {
+			data[k] = v
+		}
+		return nil
+	}
+
+	for k, v := range data {
+		single := map[string]json.RawMessage{k: v}
+		if err := r.inner.Restrict(uid, single); err != nil {
+			data[k] = []byte("null")
+			continue
+		}
+		data[k] = single[k]
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+// keyFailingRestricter returns an error, when the data contains the key fail.
+type keyFailingRestricter struct {
+	fail string
+}
+
+func (r keyFailingRestricter) Restrict(uid int, data map[string]json.RawMessage) error {
+	if _, ok := data[r.fail]; ok {
+		return errors.New("can not restrict " + r.fail)
+	}
+	return nil
+}
+
+func TestErrorPolicy(t *testing.T) {
+	keys := test.Str("user/1/name", "user/1/email", "user/2/name")
+
+	t.Run("FailAll", func(t *testing.T) {
+		datastore := test.NewMockDatastore()
+		defer datastore.Close()
+		s := autoupdate.New(datastore, keyFailingRestricter{fail: "user/1/name"}, autoupdate.WithErrorPolicy(autoupdate.FailAll))
+		defer s.Close()
+
+		got, err := s.SubscribeOnce(context.Background(), 1, keys)
+		if err == nil {
+			t.Errorf("SubscribeOnce() returned %v, expected an error", got)
+		}
+	})
+
+	t.Run("FailKey", func(t *testing.T) {
+		datastore := test.NewMockDatastore()
+		defer datastore.Close()
+		s := autoupdate.New(datastore, keyFailingRestricter{fail: "user/1/name"}, autoupdate.WithErrorPolicy(autoupdate.FailKey))
+		defer s.Close()
+
+		got, err := s.SubscribeOnce(context.Background(), 1, keys)
+		if err != nil {
+			t.Fatalf("SubscribeOnce() returned an unexpected error: %v", err)
+		}
+
+		if len(got) != 3 {
+			t.Errorf("Got %d keys, expected 3: %v", len(got), got)
+		}
+
+		if v := string(got["user/1/name"]); v != "null" {
+			t.Errorf("Got %s for the failing key, expected null", v)
+		}
+
+		for _, k := range test.Str("user/1/email", "user/2/name") {
+			if v := string(got[k]); v == "" || v == "null" {
+				t.Errorf("Got %q for key %s, expected its value", v, k)
+			}
+		}
+	})
+}
+
+func TestErrorPolicyDefault(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, keyFailingRestricter{fail: "user/1/name"})
+	defer s.Close()
+
+	if _, err := s.SubscribeOnce(context.Background(), 1, test.Str("user/1/name", "user/1/email")); err == nil {
+		t.Errorf("SubscribeOnce() returned no error, expected the default policy FailAll")
+	}
+}
@@ -0,0 +1,68 @@
+package autoupdate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+// ETag returns a hash of the data. Empty values are ignored, so the ETag of
+// the first data of a subscription is the same as the ETag of the values, that
+// the client has.
+func ETag(data map[string]json.RawMessage) string {
+	keys := make([]string, 0, len(data))
+	for k, v := range data {
+		if len(v) == 0 {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(data[k])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SubscribeIfChanged is like SubscribeReader() but only subscribes, if the
+// current values of the keys do not match the etag. The etag is created with
+// ETag() from the values, that the client has.
+//
+// If the values match, the returned reader is nil and the second return value
+// is false. In this case, the client already has the current values.
+//
+// The values are only fetched once. If they do not match, the stream starts
+// with the same values, that were compared with the etag.
+func (a *Autoupdate) SubscribeIfChanged(ctx context.Context, uid int, keys []string, etag string) (io.ReadCloser, bool, error) {
+	keys, err := a.expandKeys(keys)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := key.Validate(keys...); err != nil {
+		return nil, false, err
+	}
+
+	c := a.Connect(uid, staticKeys(keys), a.LastID())
+	data, err := c.Next(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("get data: %w", err)
+	}
+
+	if ETag(data) == etag {
+		return nil, false, nil
+	}
+
+	return streamConnection(ctx, c, data), true, nil
+}
@@ -0,0 +1,80 @@
+package autoupdate_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestETag(t *testing.T) {
+	data := map[string]json.RawMessage{"user/1/name": []byte(`"hugo"`), "user/2/name": []byte(`"emanuel"`)}
+
+	if autoupdate.ETag(data) != autoupdate.ETag(map[string]json.RawMessage{"user/2/name": []byte(`"emanuel"`), "user/1/name": []byte(`"hugo"`), "user/3/name": nil}) {
+		t.Errorf("ETag() depends on the order or on empty values")
+	}
+
+	if autoupdate.ETag(data) == autoupdate.ETag(map[string]json.RawMessage{"user/1/name": []byte(`"hugo"`), "user/2/name": []byte(`"other"`)}) {
+		t.Errorf("ETag() returned the same value for different data")
+	}
+}
+
+func TestSubscribeIfChanged(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.SetBulk(map[string]json.RawMessage{"user/1/name": []byte(`"hugo"`)})
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	keys := test.Str("user/1/name", "user/2/name")
+
+	snapshot, err := s.SubscribeOnce(context.Background(), 1, keys)
+	if err != nil {
+		t.Fatalf("SubscribeOnce() returned an unexpected error: %v", err)
+	}
+	etag := autoupdate.ETag(snapshot)
+
+	t.Run("unchanged", func(t *testing.T) {
+		r, changed, err := s.SubscribeIfChanged(context.Background(), 1, keys, etag)
+		if err != nil {
+			t.Fatalf("SubscribeIfChanged() returned an unexpected error: %v", err)
+		}
+
+		if changed || r != nil {
+			t.Errorf("SubscribeIfChanged() returned changed=%t and reader %v, expected false and nil", changed, r)
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		datastore.SetKeyData("user/1/name", []byte(`"new name"`))
+		calls := datastore.Calls()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		r, changed, err := s.SubscribeIfChanged(ctx, 1, keys, etag)
+		if err != nil {
+			t.Fatalf("SubscribeIfChanged() returned an unexpected error: %v", err)
+		}
+
+		if !changed || r == nil {
+			t.Fatalf("SubscribeIfChanged() returned changed=%t, expected a reader", changed)
+		}
+		defer r.Close()
+
+		line, err := bufio.NewReader(r).ReadString('\n')
+		if err != nil {
+			t.Fatalf("Can not read from reader: %v", err)
+		}
+
+		if expect := `{"user/1/name":"new name","user/2/name":"Hello World"}` + "\n"; line != expect {
+			t.Errorf("Got first data `%s`, expected `%s`", line, expect)
+		}
+
+		if got := datastore.Calls() - calls; got != 1 {
+			t.Errorf("SubscribeIfChanged() fetched the data %d times, expected once", got)
+		}
+	})
+}
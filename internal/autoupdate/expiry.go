@@ -0,0 +1,171 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// KeyExpirer is a KeysBuilder, where some keys are only subscribed for a
+// limited time. The keysbuilder of a key request body implements it for the
+// attribute expire.
+//
+// After the duration, the key is removed from the connection and sent one
+// last time with the value null.
+type KeyExpirer interface {
+	KeyExpiry() map[string]time.Duration
+}
+
+// KeyExpiry is the duration of one key for ExpiringKeys().
+type KeyExpiry struct {
+	Key string
+	TTL time.Duration
+}
+
+// WithKeyExpiry lets the key expire after the duration ttl.
+func WithKeyExpiry(key string, ttl time.Duration) KeyExpiry {
+	return KeyExpiry{Key: key, TTL: ttl}
+}
+
+// ExpiringKeys returns a KeysBuilder, where the given keys expire.
+func ExpiringKeys(kb KeysBuilder, expiry ...KeyExpiry) KeysBuilder {
+	ttls := make(map[string]time.Duration, len(expiry))
+	for _, e := range expiry {
+		ttls[e.Key] = e.TTL
+	}
+	return expirerKeys{KeysBuilder: kb, ttls: ttls}
+}
+
+// expirerKeys is the KeysBuilder returned by ExpiringKeys.
+type expirerKeys struct {
+	KeysBuilder
+	ttls map[string]time.Duration
+}
+
+func (e expirerKeys) KeyExpiry() map[string]time.Duration {
+	return e.ttls
+}
+
+// expiringKeys is the KeysBuilder of a connection with expiring keys. It
+// removes the expired keys.
+type expiringKeys struct {
+	KeysBuilder
+	deadlines map[string]time.Time
+	expired   map[string]bool
+}
+
+// newExpiringKeys returns expiringKeys, if kb is a KeyExpirer with at least one
+// key. In other cases, it returns nil.
+func newExpiringKeys(kb KeysBuilder, start time.Time) *expiringKeys {
+	expirer, ok := kb.(KeyExpirer)
+	if !ok {
+		return nil
+	}
+
+	ttls := expirer.KeyExpiry()
+	if len(ttls) == 0 {
+		return nil
+	}
+
+	deadlines := make(map[string]time.Time, len(ttls))
+	for key, ttl := range ttls {
+		deadlines[key] = start.Add(ttl)
+	}
+	return &expiringKeys{
+		KeysBuilder: kb,
+		deadlines:   deadlines,
+		expired:     make(map[string]bool),
+	}
+}
+
+// Keys returns the keys of the inner KeysBuilder without the expired keys.
+func (e *expiringKeys) Keys() []string {
+	keys := e.KeysBuilder.Keys()
+	if len(e.expired) == 0 {
+		return keys
+	}
+
+	filtered := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !e.expired[key] {
+			filtered = append(filtered, key)
+		}
+	}
+	return filtered
+}
+
+// next returns the next time, when a key expires. The second value is false,
+// if there is no key left to expire.
+func (e *expiringKeys) next() (time.Time, bool) {
+	var next time.Time
+	for key, deadline := range e.deadlines {
+		if e.expired[key] {
+			continue
+		}
+		if next.IsZero() || deadline.Before(next) {
+			next = deadline
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// expire removes all keys, that expired at the time now. It returns the
+// removed keys with the value null.
+func (e *expiringKeys) expire(now time.Time) map[string]json.RawMessage {
+	var data map[string]json.RawMessage
+	for key, deadline := range e.deadlines {
+		if e.expired[key] || deadline.After(now) {
+			continue
+		}
+
+		e.expired[key] = true
+		if data == nil {
+			data = make(map[string]json.RawMessage)
+		}
+		data[key] = []byte("null")
+	}
+	return data
+}
+
+// receiveOrExpire calls receiveOrRefresh. If a key of the connection expires
+// before, the expired keys are returned with the value null.
+func (c *Connection) receiveOrExpire(ctx context.Context) (map[string]json.RawMessage, error) {
+	if c.expiry == nil {
+		return c.receiveOrRefresh(ctx)
+	}
+
+	deadline, ok := c.expiry.next()
+	if !ok {
+		return c.receiveOrRefresh(ctx)
+	}
+
+	expireCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	tid := c.tid
+	data, err := c.receiveOrRefresh(expireCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		expired := c.expiry.expire(time.Now())
+		if c.tid == tid {
+			return expired, nil
+		}
+
+		// The deadline was reached after an update was received but before
+		// its data was fetched. Fetch it again, so it is not lost.
+		data, err := c.changedData(ctx, c.kb.Keys()...)
+		if err != nil {
+			return nil, fmt.Errorf("get data of update before expiry: %w", err)
+		}
+
+		if data == nil {
+			data = make(map[string]json.RawMessage, len(expired))
+		}
+		for k, v := range expired {
+			data[k] = v
+		}
+		return data, nil
+	}
+	return data, err
+}
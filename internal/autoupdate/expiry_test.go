@@ -0,0 +1,121 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestKeyExpiry(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.OnlyData = true
+	datastore.Data = map[string]json.RawMessage{
+		"user/1/name":  []byte(`"hugo"`),
+		"user/1/token": []byte(`"secret"`),
+	}
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	kb := autoupdate.ExpiringKeys(
+		mockKeysBuilder{keys: test.Str("user/1/name", "user/1/token")},
+		autoupdate.WithKeyExpiry("user/1/token", 50*time.Millisecond),
+	)
+	c := s.Connect(1, kb, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	data, err := c.Next(ctx)
+	if err != nil {
+		t.Fatalf("Next() returned an unexpected error: %v", err)
+	}
+	if len(data) != 2 {
+		t.Errorf("Got first data %v, expected both keys", data)
+	}
+
+	t.Run("key expires", func(t *testing.T) {
+		start := time.Now()
+		data, err := c.Next(ctx)
+		if err != nil {
+			t.Fatalf("Next() returned an unexpected error: %v", err)
+		}
+
+		if value, ok := data["user/1/token"]; !ok || string(value) != "null" || len(data) != 1 {
+			t.Errorf("Got data %v, expected only user/1/token with null", data)
+		}
+
+		if d := time.Since(start); d < 40*time.Millisecond {
+			t.Errorf("Key expired after %v, expected 50ms", d)
+		}
+	})
+
+	t.Run("other keys are delivered", func(t *testing.T) {
+		datastore.Update(map[string]json.RawMessage{
+			"user/1/name":  []byte(`"emma"`),
+			"user/1/token": []byte(`"new secret"`),
+		})
+		datastore.Send(test.Str("user/1/name", "user/1/token"))
+
+		data, err := c.Next(ctx)
+		if err != nil {
+			t.Fatalf("Next() returned an unexpected error: %v", err)
+		}
+
+		if string(data["user/1/name"]) != `"emma"` {
+			t.Errorf("Got user/1/name %s, expected \"emma\"", data["user/1/name"])
+		}
+
+		if _, ok := data["user/1/token"]; ok {
+			t.Errorf("Got the expired key user/1/token")
+		}
+	})
+}
+
+func TestKeyExpiryDuringUpdate(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.OnlyData = true
+	datastore.Data = map[string]json.RawMessage{
+		"user/1/name":  []byte(`"hugo"`),
+		"user/1/token": []byte(`"secret"`),
+	}
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	kb := autoupdate.ExpiringKeys(
+		mockKeysBuilder{keys: test.Str("user/1/name", "user/1/token")},
+		autoupdate.WithKeyExpiry("user/1/token", 100*time.Millisecond),
+	)
+	c := s.Connect(1, kb, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if _, err := c.Next(ctx); err != nil {
+		t.Fatalf("Next() returned an unexpected error: %v", err)
+	}
+
+	// The update is received before the key expires, but its data is fetched
+	// after the key expired.
+	datastore.SimulateLatency("user/1/name", 200*time.Millisecond)
+	datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(`"emma"`)})
+	datastore.Send(test.Str("user/1/name"))
+
+	data, err := c.Next(ctx)
+	if err != nil {
+		t.Fatalf("Next() returned an unexpected error: %v", err)
+	}
+
+	if string(data["user/1/name"]) != `"emma"` {
+		t.Errorf("Got user/1/name %s, expected \"emma\"", data["user/1/name"])
+	}
+
+	if string(data["user/1/token"]) != "null" {
+		t.Errorf("Got user/1/token %s, expected null", data["user/1/token"])
+	}
+}
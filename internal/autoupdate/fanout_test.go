@@ -0,0 +1,99 @@
+package autoupdate_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+// TestServiceFanOut10000Subscribers makes sure, that an update is sent to
+// many subscribers of the same key in a short time.
+func TestServiceFanOut10000Subscribers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip fan out test in short mode")
+	}
+
+	const subscribers = 10000
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	readers := make([]io.ReadCloser, subscribers)
+	for i := range readers {
+		r, err := s.SubscribeReader(ctx, 1, test.Str("user/1/name"))
+		if err != nil {
+			t.Fatalf("SubscribeReader() %d returned an unexpected error: %v", i, err)
+		}
+		defer r.Close()
+		readers[i] = r
+	}
+
+	var received int64
+	var wg sync.WaitGroup
+	wg.Add(subscribers)
+	start := make(chan struct{})
+	for _, r := range readers {
+		go func(r io.Reader) {
+			defer wg.Done()
+			scanner := bufio.NewScanner(r)
+
+			// The first line is the initial data.
+			if !scanner.Scan() {
+				return
+			}
+			<-start
+
+			if !scanner.Scan() {
+				return
+			}
+			if string(scanner.Bytes()) == `{"user/1/name":"new value"}` {
+				atomic.AddInt64(&received, 1)
+			}
+		}(r)
+	}
+
+	close(start)
+	begin := time.Now()
+	datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(`"new value"`)})
+	datastore.Send(test.Str("user/1/name"))
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Only %d of %d subscribers got the update after 10 seconds", atomic.LoadInt64(&received), subscribers)
+	}
+
+	if got := atomic.LoadInt64(&received); got != subscribers {
+		t.Errorf("%d subscribers got the update, expected %d", got, subscribers)
+	}
+
+	// With less then four CPUs, scheduling the goroutines of all subscribers
+	// takes most of the time. Only a generous bound is checked there.
+	deadline := 100 * time.Millisecond
+	if runtime.NumCPU() < 4 {
+		deadline = time.Second
+	}
+
+	if d := time.Since(begin); d > deadline {
+		t.Errorf("Sending the update to all subscribers took %v, expected less then %v", d, deadline)
+	}
+}
@@ -1,9 +1,9 @@
 package autoupdate_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"strings"
 	"testing"
 
 	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
@@ -63,6 +63,9 @@ var dataSet = map[string]json.RawMessage{
 	"G2/1/content_object_id":  []byte(`"B/1"`),
 }
 
+// fields is a shortcut to describe the fields of a relation.
+type fields = map[string]*autoupdate.FieldDescription
+
 func TestFeatures(t *testing.T) {
 	datastore := test.NewMockDatastore()
 	datastore.Data = dataSet
@@ -73,9 +76,8 @@ func TestFeatures(t *testing.T) {
 	for _, tt := range []struct {
 		name string
 
-		// request is an example request to the autoupdate service. The
-		// http-server expects not one request, but a list of requests.
-		request string
+		// request is an example request to the autoupdate service.
+		request *autoupdate.KeyRequestBuilder
 
 		// result is the data returned for the request. The http-server returns
 		// the same json encoded data but its format differs. It only returns
@@ -84,42 +86,15 @@ func TestFeatures(t *testing.T) {
 	}{
 		{
 			"Basic",
-			`{
-				"collection": "A",
-				"ids": [
-					1,
-					2
-				],
-				"fields": {
-					"a": null,
-					"C_ids": {
-						"type": "relation-list",
-						"collection": "C",
-						"fields": {
-							"c": null,
-							"G1_ids": {
-								"type": "relation-list",
-								"collection": "G1",
-								"fields": {
-									"g1": null
-								}
-							}
-						}
-					},
-					"B_id": {
-						"type": "relation",
-						"collection": "B",
-						"fields": {}
-					},
-					"G1_ids": {
-						"type": "relation-list",
-						"collection": "G1",
-						"fields": {
-							"g1": null
-						}
-					}
-				}
-			}`,
+			new(autoupdate.KeyRequestBuilder).
+				AddCollection("A", []int{1, 2}).
+				AddField("a").
+				AddRelation("C_ids", autoupdate.RelationList("C", fields{
+					"c":      nil,
+					"G1_ids": autoupdate.RelationList("G1", fields{"g1": nil}),
+				})).
+				AddRelation("B_id", autoupdate.Relation("B", fields{})).
+				AddRelation("G1_ids", autoupdate.RelationList("G1", fields{"g1": nil})),
 			`{
 				"A/1/a":      "a1",
 				"A/1/C_ids":  [],
@@ -139,40 +114,16 @@ func TestFeatures(t *testing.T) {
 		},
 		{
 			"Partial merged fields, generic lookup",
-			`{
-				"collection": "G2",
-				"ids": [1],
-				"fields": {
-					"content_object_id": {
-						"type": "generic-relation",
-						"fields": {
-							"B_children_ids": {
-								"type": "relation-list",
-								"collection": "B",
-								"fields": {
-									"C_ids": {
-										"type": "relation-list",
-										"collection": "C",
-										"fields": {
-											"c": null
-										}
-									},
-									"B_parent_id": null
-								}
-							},
-							"C_ids": {
-								"type": "relation-list",
-								"collection": "C",
-								"fields": {
-									"c": null,
-									"title": null
-								}
-							},
-							"G2_id": null
-						}
-					}
-				}
-			}`,
+			new(autoupdate.KeyRequestBuilder).
+				AddCollection("G2", []int{1}).
+				AddRelation("content_object_id", autoupdate.GenericRelation(fields{
+					"B_children_ids": autoupdate.RelationList("B", fields{
+						"C_ids":       autoupdate.RelationList("C", fields{"c": nil}),
+						"B_parent_id": nil,
+					}),
+					"C_ids": autoupdate.RelationList("C", fields{"c": nil, "title": nil}),
+					"G2_id": nil,
+				})),
 			`{
 				"B/1/B_children_ids":     [2],
 				"B/1/C_ids":              [1],
@@ -187,26 +138,16 @@ func TestFeatures(t *testing.T) {
 		},
 		{
 			"non-existent ids, fields, fqids, references, generic relations and fields without a relation",
-			`{
-				"collection": "G1",
-				"ids": [2,4],
-				"fields": {
-					"content_object_ids": {
-						"type": "generic-relation-list",
-						"fields": {
-							"a": null,
-							"b": null,
-							"not_existent": {
-								"type": "generic-relation",
-								"fields": {"key": null}
-							},
-							"title": null,
-							"G1_ids": null,
-							"A_id": null
-						}
-					}
-				}
-			}`,
+			new(autoupdate.KeyRequestBuilder).
+				AddCollection("G1", []int{2, 4}).
+				AddRelation("content_object_ids", autoupdate.GenericRelationList(fields{
+					"a":            nil,
+					"b":            nil,
+					"not_existent": autoupdate.GenericRelation(fields{"key": nil}),
+					"title":        nil,
+					"G1_ids":       nil,
+					"A_id":         nil,
+				})),
 			`{
 				"G1/2/content_object_ids": ["A/1","C/1","C/2"],
 				"A/1/a":                   "a1",
@@ -222,14 +163,10 @@ func TestFeatures(t *testing.T) {
 		},
 		{
 			"template fields",
-			`{
-				"collection": "D",
-				"ids": [1,2],
-				"fields": {
-					"d": null,
-					"B_$_ids": null
-				}
-			}`,
+			new(autoupdate.KeyRequestBuilder).
+				AddCollection("D", []int{1, 2}).
+				AddField("d").
+				AddField("B_$_ids"),
 			`{
 				"D/1/d":       "d1",
 				"D/1/B_$_ids": ["1","2","3"],
@@ -239,16 +176,10 @@ func TestFeatures(t *testing.T) {
 		},
 		{
 			"structured fields without references",
-			`{
-				"collection": "D",
-				"ids": [1,2],
-				"fields": {
-					"d": null,
-					"B_$_ids": {
-						"type": "template"
-					}
-				}
-			}`,
+			new(autoupdate.KeyRequestBuilder).
+				AddCollection("D", []int{1, 2}).
+				AddField("d").
+				AddRelation("B_$_ids", autoupdate.Template(nil)),
 			`{
 				"D/1/d":       "d1",
 				"D/1/B_$_ids": ["1","2","3"],
@@ -263,29 +194,10 @@ func TestFeatures(t *testing.T) {
 		},
 		{
 			"structed references",
-			`{
-				"collection": "D",
-				"ids": [1,2],
-				"fields": {
-					"B_$_ids": {
-						"type": "template",
-						"values": {
-							"type": "relation-list",
-							"collection": "B",
-							"fields": {
-								"b": null
-							}
-						}
-					},
-					"B_4_ids": {
-						"type": "relation-list",
-						"collection": "B",
-						"fields": {
-							"title": null
-						}
-					}
-				}
-			}`,
+			new(autoupdate.KeyRequestBuilder).
+				AddCollection("D", []int{1, 2}).
+				AddRelation("B_$_ids", autoupdate.Template(autoupdate.RelationList("B", fields{"b": nil}))).
+				AddRelation("B_4_ids", autoupdate.RelationList("B", fields{"title": nil})),
 			`{
 				"D/1/B_$_ids": ["1","2","3"],
 				"D/1/B_1_ids": [1,2],
@@ -301,9 +213,14 @@ func TestFeatures(t *testing.T) {
 		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
-			b, err := keysbuilder.FromJSON(context.Background(), strings.NewReader(tt.request), s, 1)
+			request, err := tt.request.Build()
+			if err != nil {
+				t.Fatalf("Build() returned an unexpected error: %v", err)
+			}
+
+			b, err := keysbuilder.ManyFromJSON(context.Background(), bytes.NewReader(request), s, 1)
 			if err != nil {
-				t.Fatalf("FromJSON() returned an unexpected error: %v", err)
+				t.Fatalf("ManyFromJSON() returned an unexpected error: %v", err)
 			}
 			c := s.Connect(1, b, 0)
 			data, err := c.Next(context.Background())
@@ -5,9 +5,20 @@ import (
 	"hash/maphash"
 )
 
+// SubscriptionFilter decides, if a changed value of a key is sent to the
+// client. old is the value, that was sent to the client before and new is the
+// changed value. new is empty, if the key was deleted. If the function returns
+// false, the key is not sent.
+type SubscriptionFilter func(key string, old, new json.RawMessage) bool
+
 type filter struct {
 	hash    maphash.Hash
 	history map[string]uint64
+
+	// update is an optional SubscriptionFilter. It is not called for keys,
+	// that were not sent before. values holds the sent values for it.
+	update SubscriptionFilter
+	values map[string]json.RawMessage
 }
 
 // filter has to be called on a reader that contains a decoded json object.
@@ -19,20 +30,41 @@ func (f *filter) filter(data map[string]json.RawMessage) error {
 	}
 
 	for key, value := range data {
-		if len(value) == 0 {
-			// Delete empty data
-			f.history[key] = 0
-			continue
+		var new uint64
+		if len(value) > 0 {
+			f.hash.Reset()
+			f.hash.Write(value)
+			new = f.hash.Sum64()
+			if old, ok := f.history[key]; ok && new == old {
+				delete(data, key)
+				continue
+			}
 		}
 
-		f.hash.Reset()
-		f.hash.Write(value)
-		new := f.hash.Sum64()
-		if old, ok := f.history[key]; ok && new == old {
-			delete(data, key)
-			continue
+		if f.update != nil {
+			if old, ok := f.values[key]; ok && !f.update(key, old, value) {
+				delete(data, key)
+				continue
+			}
+			f.remember(key, value)
 		}
+
+		// Empty data has the hash 0.
 		f.history[key] = new
 	}
 	return nil
 }
+
+// remember saves the value, that is sent to the client, for the update
+// filter.
+func (f *filter) remember(key string, value json.RawMessage) {
+	if f.values == nil {
+		f.values = make(map[string]json.RawMessage)
+	}
+
+	if len(value) == 0 {
+		delete(f.values, key)
+		return
+	}
+	f.values[key] = value
+}
@@ -0,0 +1,95 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestUpdateFilter(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		filter autoupdate.SubscriptionFilter
+		update map[string]json.RawMessage
+		expect []string
+	}{
+		{
+			"drop unchanged values",
+			func(key string, old, new json.RawMessage) bool {
+				var o, n interface{}
+				if json.Unmarshal(old, &o) != nil || json.Unmarshal(new, &n) != nil {
+					return true
+				}
+				return !reflect.DeepEqual(o, n)
+			},
+			map[string]json.RawMessage{
+				"user/1/name":       []byte(`"new name"`),
+				"user/1/settings":   []byte(`{"a": 1}`),
+				"user/1/updated_at": []byte(`2`),
+			},
+			test.Str("user/1/name", "user/1/updated_at"),
+		},
+		{
+			"drop updated_at",
+			func(key string, old, new json.RawMessage) bool {
+				return !strings.HasSuffix(key, "/updated_at")
+			},
+			map[string]json.RawMessage{
+				"user/1/name":       []byte(`"new name"`),
+				"user/1/updated_at": []byte(`2`),
+			},
+			test.Str("user/1/name"),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			datastore := test.NewMockDatastore()
+			defer datastore.Close()
+			datastore.SetBulk(map[string]json.RawMessage{
+				"user/1/name":       []byte(`"name"`),
+				"user/1/settings":   []byte(`{"a":1}`),
+				"user/1/updated_at": []byte(`1`),
+			})
+
+			s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithUpdateFilter(tt.filter))
+			defer s.Close()
+
+			kb := mockKeysBuilder{keys: test.Str("user/1/name", "user/1/settings", "user/1/updated_at")}
+			c := s.Connect(1, kb, 0)
+
+			data, err := c.Next(context.Background())
+			if err != nil {
+				t.Fatalf("Next() returned an unexpected error: %v", err)
+			}
+			if len(data) != 3 {
+				t.Errorf("First Next() returned %d keys, expected all 3", len(data))
+			}
+
+			datastore.SetBulk(tt.update)
+			var changed []string
+			for k := range tt.update {
+				changed = append(changed, k)
+			}
+			datastore.Send(changed)
+
+			data, err = c.Next(context.Background())
+			if err != nil {
+				t.Fatalf("Next() returned an unexpected error: %v", err)
+			}
+
+			var got []string
+			for k := range data {
+				got = append(got, k)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("Got keys %v, expected %v", got, tt.expect)
+			}
+		})
+	}
+}
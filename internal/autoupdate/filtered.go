@@ -0,0 +1,106 @@
+package autoupdate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// FilteredService is a Service that only allows keys from an allowlist. It can
+// be used for a public endpoint, where only specific keys can be requested.
+//
+// Has to be created with autoupdate.NewFilteredService().
+type FilteredService struct {
+	inner   Service
+	allowed map[string]bool
+}
+
+// NewFilteredService creates a FilteredService that wrapps the inner service.
+func NewFilteredService(inner Service, allowedKeys []string) *FilteredService {
+	allowed := make(map[string]bool, len(allowedKeys))
+	for _, key := range allowedKeys {
+		allowed[key] = true
+	}
+
+	return &FilteredService{
+		inner:   inner,
+		allowed: allowed,
+	}
+}
+
+// Connect is like Autoupdate.Connect(). If the keysbuilder contains a key that
+// is not allowed, the returned connection returns an KeyNotAllowedError.
+func (f *FilteredService) Connect(userID int, kb KeysBuilder, tid uint64) *Connection {
+	c := f.inner.Connect(userID, filteredKeysBuilder{KeysBuilder: kb, f: f}, tid)
+	if err := f.check(kb.Keys()); err != nil {
+		c.err = err
+	}
+	return c
+}
+
+// Value is like Autoupdate.Value() but returns an KeyNotAllowedError, if the
+// key is not allowed.
+func (f *FilteredService) Value(ctx context.Context, uid int, key string, value interface{}) error {
+	if err := f.check([]string{key}); err != nil {
+		return err
+	}
+	return f.inner.Value(ctx, uid, key, value)
+}
+
+// LastID returns the last id of the inner service.
+func (f *FilteredService) LastID() uint64 {
+	return f.inner.LastID()
+}
+
+// Close closes the inner service.
+func (f *FilteredService) Close() error {
+	return f.inner.Close()
+}
+
+// SubscribeJSON is like Autoupdate.SubscribeJSON(). If the key request
+// contains a key that is not allowed, a KeyNotAllowedError is returned.
+func (f *FilteredService) SubscribeJSON(ctx context.Context, uid int, body []byte) (io.ReadCloser, error) {
+	return subscribeJSON(ctx, f, uid, body)
+}
+
+// Subscribers returns the registry of the inner service.
+func (f *FilteredService) Subscribers() *SubscriberRegistry {
+	return f.inner.Subscribers()
+}
+
+// check returns an KeyNotAllowedError for the first key that is not in the
+// allowlist.
+func (f *FilteredService) check(keys []string) error {
+	for _, key := range keys {
+		if !f.allowed[key] {
+			return KeyNotAllowedError{Key: key}
+		}
+	}
+	return nil
+}
+
+// filteredKeysBuilder checks the keys after each update.
+type filteredKeysBuilder struct {
+	KeysBuilder
+	f *FilteredService
+}
+
+// KeyExpiry returns the expiring keys of the inner KeysBuilder.
+func (kb filteredKeysBuilder) KeyExpiry() map[string]time.Duration {
+	if expirer, ok := kb.KeysBuilder.(KeyExpirer); ok {
+		return expirer.KeyExpiry()
+	}
+	return nil
+}
+
+func (kb filteredKeysBuilder) Update() error {
+	if err := kb.KeysBuilder.Update(); err != nil {
+		return err
+	}
+
+	if err := kb.f.check(kb.Keys()); err != nil {
+		return fmt.Errorf("check updated keys: %w", err)
+	}
+	return nil
+}
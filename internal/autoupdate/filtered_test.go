@@ -0,0 +1,98 @@
+package autoupdate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestFilteredService(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	var service autoupdate.Service = autoupdate.NewFilteredService(s, test.Str("user/1/name"))
+
+	t.Run("Connect allowed", func(t *testing.T) {
+		c := service.Connect(1, mockKeysBuilder{keys: test.Str("user/1/name")}, 0)
+		data, err := c.Next(context.Background())
+		if err != nil {
+			t.Fatalf("c.Next() returned an error: %v", err)
+		}
+		if got := string(data["user/1/name"]); got != `"Hello World"` {
+			t.Errorf("Got value %s, expected \"Hello World\"", got)
+		}
+	})
+
+	t.Run("Connect not allowed", func(t *testing.T) {
+		c := service.Connect(1, mockKeysBuilder{keys: test.Str("user/1/name", "user/1/password")}, 0)
+		if _, err := c.Next(context.Background()); !errors.Is(err, autoupdate.ErrKeyNotAllowed) {
+			t.Errorf("c.Next() returned error `%v`, expected ErrKeyNotAllowed", err)
+		}
+	})
+
+	t.Run("Value not allowed", func(t *testing.T) {
+		var value string
+		if err := service.Value(context.Background(), 1, "user/1/password", &value); !errors.Is(err, autoupdate.ErrKeyNotAllowed) {
+			t.Errorf("Value() returned error `%v`, expected ErrKeyNotAllowed", err)
+		}
+	})
+
+	t.Run("Connection not allowed has stats", func(t *testing.T) {
+		c := service.Connect(1, mockKeysBuilder{keys: test.Str("user/1/password")}, 0)
+		c.AddBytesWritten(10)
+		if got := c.Stats(); got.UserID != 1 || got.BytesWritten != 10 {
+			t.Errorf("Got stats %v, expected user 1 with 10 bytes written", got)
+		}
+	})
+
+	t.Run("SubscribeJSON allowed", func(t *testing.T) {
+		r, err := service.SubscribeJSON(context.Background(), 1, []byte(`[{"ids":[1],"collection":"user","fields":{"name":null}}]`))
+		if err != nil {
+			t.Fatalf("SubscribeJSON() returned an unexpected error: %v", err)
+		}
+		r.Close()
+	})
+
+	t.Run("SubscribeJSON not allowed", func(t *testing.T) {
+		_, err := service.SubscribeJSON(context.Background(), 1, []byte(`[{"ids":[1],"collection":"user","fields":{"password":null}}]`))
+		if !errors.Is(err, autoupdate.ErrKeyNotAllowed) {
+			t.Errorf("SubscribeJSON() returned error `%v`, expected ErrKeyNotAllowed", err)
+		}
+	})
+}
+
+func TestFilteredServiceClose(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+
+	service := autoupdate.NewFilteredService(s, test.Str("user/1/name"))
+	if err := service.Close(); err != nil {
+		t.Fatalf("Close() returned an unexpected error: %v", err)
+	}
+
+	// The inner service has to be closed, so the second call to Next() does
+	// not block.
+	c := s.Connect(1, mockKeysBuilder{keys: test.Str("user/1/name")}, 0)
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("First Next() returned an unexpected error: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.Next(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Next() blocks after the service was closed")
+	}
+}
@@ -0,0 +1,188 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+// defaultGroupQueueSize is the number of updates that are queued for a member
+// of a SubscriptionGroup.
+const defaultGroupQueueSize = 100
+
+// SubscriptionGroup holds a connection for many users with the same keys.
+// Additional to the data from the datastore, an update can be sent to all
+// users at once with BroadcastUpdate().
+//
+// Each member has a queue of updates. If the queue is full, new updates are
+// merged into the last queued update, like the queue of a paused service.
+//
+// Has to be created with NewSubscriptionGroup(). The background jobs of the
+// group have to be stopped with Close().
+type SubscriptionGroup struct {
+	cancel     context.CancelFunc
+	restricter Restricter
+	queueSize  int
+
+	// mu protects the queues of all members, so a broadcast is added to all
+	// of them at the same time.
+	mu      sync.Mutex
+	members map[int]*groupMember
+}
+
+// groupMember is the connection of one user in a group.
+type groupMember struct {
+	queue  []map[string]json.RawMessage
+	err    error
+	signal chan struct{}
+}
+
+// NewSubscriptionGroup creates a connection for each user to the given keys.
+func NewSubscriptionGroup(service *Autoupdate, userIDs []int, keys []string) (*SubscriptionGroup, error) {
+	if err := key.Validate(keys...); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &SubscriptionGroup{
+		cancel:     cancel,
+		restricter: service.restricter,
+		queueSize:  defaultGroupQueueSize,
+		members:    make(map[int]*groupMember, len(userIDs)),
+	}
+
+	for _, uid := range userIDs {
+		if _, ok := g.members[uid]; ok {
+			continue
+		}
+
+		m := &groupMember{signal: make(chan struct{}, 1)}
+		g.members[uid] = m
+		go g.pump(ctx, m, service.Connect(uid, staticKeys(keys), 0))
+	}
+	return g, nil
+}
+
+// Close stops the background jobs of the group.
+func (g *SubscriptionGroup) Close() {
+	g.cancel()
+}
+
+// pump reads the data of a connection and adds it to the queue of the member.
+func (g *SubscriptionGroup) pump(ctx context.Context, m *groupMember, c *Connection) {
+	for {
+		data, err := c.Next(ctx)
+
+		g.mu.Lock()
+		if err != nil {
+			m.err = err
+		} else {
+			g.enqueue(m, data)
+		}
+		g.mu.Unlock()
+		m.notify()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// BroadcastUpdate sends the data to all members of the group. The data is
+// restricted for each member and then added to the queues of all members at
+// the same time.
+//
+// If the data can not be restricted for a member, the member gets the error
+// on the next call to Next().
+func (g *SubscriptionGroup) BroadcastUpdate(data map[string]json.RawMessage) {
+	restricted := make(map[*groupMember]map[string]json.RawMessage, len(g.members))
+	errs := make(map[*groupMember]error)
+	for uid, m := range g.members {
+		memberData := make(map[string]json.RawMessage, len(data))
+		for k, v := range data {
+			memberData[k] = v
+		}
+
+		if err := g.restricter.Restrict(uid, memberData); err != nil {
+			errs[m] = fmt.Errorf("restrict broadcast for user %d: %w", uid, err)
+			continue
+		}
+		restricted[m] = memberData
+	}
+
+	g.mu.Lock()
+	for m, memberData := range restricted {
+		g.enqueue(m, memberData)
+	}
+	for m, err := range errs {
+		m.err = err
+	}
+	g.mu.Unlock()
+
+	for _, m := range g.members {
+		m.notify()
+	}
+}
+
+// enqueue adds the data to the queue of the member. If the queue is full, the
+// data is merged into the last queued update.
+//
+// Has to be called with the lock.
+func (g *SubscriptionGroup) enqueue(m *groupMember, data map[string]json.RawMessage) {
+	if len(m.queue) < g.queueSize || len(m.queue) == 0 {
+		m.queue = append(m.queue, data)
+		return
+	}
+
+	last := m.queue[len(m.queue)-1]
+	merged := make(map[string]json.RawMessage, len(last)+len(data))
+	for k, v := range last {
+		merged[k] = v
+	}
+	for k, v := range data {
+		merged[k] = v
+	}
+	m.queue[len(m.queue)-1] = merged
+}
+
+// Next returns the next data for the user. It blocks until there is data or
+// the context is done.
+func (g *SubscriptionGroup) Next(ctx context.Context, uid int) (map[string]json.RawMessage, error) {
+	m, ok := g.members[uid]
+	if !ok {
+		return nil, fmt.Errorf("user %d is not in the group", uid)
+	}
+
+	for {
+		g.mu.Lock()
+		if len(m.queue) > 0 {
+			data := m.queue[0]
+			m.queue = m.queue[1:]
+			g.mu.Unlock()
+			return data, nil
+		}
+		err := m.err
+		g.mu.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case <-m.signal:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// notify wakes up a waiting call to Next.
+func (m *groupMember) notify() {
+	select {
+	case m.signal <- struct{}{}:
+	default:
+	}
+}
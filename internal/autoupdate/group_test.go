@@ -0,0 +1,166 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestSubscriptionGroup(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	uids := []int{1, 2, 3, 4, 5}
+	g, err := autoupdate.NewSubscriptionGroup(s, uids, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("NewSubscriptionGroup returned an unexpected error: %v", err)
+	}
+	defer g.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, uid := range uids {
+		if _, err := g.Next(ctx, uid); err != nil {
+			t.Fatalf("First Next for user %d returned an unexpected error: %v", uid, err)
+		}
+	}
+
+	g.BroadcastUpdate(map[string]json.RawMessage{"vote/1/state": []byte(`"started"`)})
+
+	// The broadcast was added to the queues of all members, before
+	// BroadcastUpdate returned. Next does not have to wait, so a done context
+	// does not matter.
+	done, cancelDone := context.WithCancel(context.Background())
+	cancelDone()
+	for _, uid := range uids {
+		data, err := g.Next(done, uid)
+		if err != nil {
+			t.Fatalf("Next for user %d returned an unexpected error: %v", uid, err)
+		}
+
+		if string(data["vote/1/state"]) != `"started"` {
+			t.Errorf("User %d got %v, expected the broadcast", uid, data)
+		}
+	}
+}
+
+// hidingRestricter removes the key vote/1/secret for all users except user 1.
+type hidingRestricter struct{}
+
+func (hidingRestricter) Restrict(uid int, data map[string]json.RawMessage) error {
+	if uid != 1 {
+		delete(data, "vote/1/secret")
+	}
+	return nil
+}
+
+func TestSubscriptionGroupRestricted(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, hidingRestricter{})
+	defer s.Close()
+
+	g, err := autoupdate.NewSubscriptionGroup(s, []int{1, 2}, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("NewSubscriptionGroup returned an unexpected error: %v", err)
+	}
+	defer g.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, uid := range []int{1, 2} {
+		if _, err := g.Next(ctx, uid); err != nil {
+			t.Fatalf("First Next for user %d returned an unexpected error: %v", uid, err)
+		}
+	}
+
+	g.BroadcastUpdate(map[string]json.RawMessage{"vote/1/secret": []byte(`"yes"`)})
+
+	data, err := g.Next(ctx, 1)
+	if err != nil {
+		t.Fatalf("Next for user 1 returned an unexpected error: %v", err)
+	}
+	if _, ok := data["vote/1/secret"]; !ok {
+		t.Errorf("User 1 did not get the secret key")
+	}
+
+	data, err = g.Next(ctx, 2)
+	if err != nil {
+		t.Fatalf("Next for user 2 returned an unexpected error: %v", err)
+	}
+	if _, ok := data["vote/1/secret"]; ok {
+		t.Errorf("User 2 got the restricted key")
+	}
+}
+
+func TestSubscriptionGroupQueueMerged(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	g, err := autoupdate.NewSubscriptionGroup(s, []int{1}, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("NewSubscriptionGroup returned an unexpected error: %v", err)
+	}
+	defer g.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := g.Next(ctx, 1); err != nil {
+		t.Fatalf("First Next returned an unexpected error: %v", err)
+	}
+
+	// The member does not read while many updates are sent.
+	const updates = 1000
+	for i := 0; i < updates; i++ {
+		g.BroadcastUpdate(map[string]json.RawMessage{fmt.Sprintf("vote/%d/state", i): []byte(`"started"`)})
+	}
+
+	done, cancelDone := context.WithCancel(context.Background())
+	cancelDone()
+	var frames int
+	keys := make(map[string]bool)
+	for {
+		data, err := g.Next(done, 1)
+		if err != nil {
+			break
+		}
+		frames++
+		for k := range data {
+			keys[k] = true
+		}
+	}
+
+	if frames >= updates {
+		t.Errorf("Got %d queued frames, expected the queue to be limited", frames)
+	}
+	if len(keys) != updates {
+		t.Errorf("Got %d keys, expected %d. Merged updates are lost", len(keys), updates)
+	}
+}
+
+func TestSubscriptionGroupUnknownUser(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	g, err := autoupdate.NewSubscriptionGroup(s, []int{1}, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("NewSubscriptionGroup returned an unexpected error: %v", err)
+	}
+	defer g.Close()
+
+	if _, err := g.Next(context.Background(), 2); err == nil {
+		t.Errorf("Next for an unknown user returned no error")
+	}
+}
@@ -0,0 +1,75 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestHooks(t *testing.T) {
+	var events []string
+	var subscribedKeys []string
+	var updateKeys []int
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(
+		datastore,
+		new(test.MockRestricter),
+		autoupdate.WithOnSubscribe(func(userID int, keys []string) {
+			events = append(events, "subscribe")
+			subscribedKeys = keys
+			if userID != 5 {
+				t.Errorf("OnSubscribe got user %d, expected 5", userID)
+			}
+		}),
+		autoupdate.WithOnUpdate(func(userID int, numKeys int) {
+			events = append(events, "update")
+			updateKeys = append(updateKeys, numKeys)
+			if userID != 5 {
+				t.Errorf("OnUpdate got user %d, expected 5", userID)
+			}
+		}),
+		autoupdate.WithOnUnsubscribe(func(userID int) {
+			events = append(events, "unsubscribe")
+			if userID != 5 {
+				t.Errorf("OnUnsubscribe got user %d, expected 5", userID)
+			}
+		}),
+	)
+	defer s.Close()
+
+	kb := mockKeysBuilder{keys: test.Str("user/1/name", "user/2/name")}
+	c := s.Connect(5, kb, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if _, err := c.Next(ctx); err != nil {
+		t.Fatalf("Next() returned an unexpected error: %v", err)
+	}
+
+	datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(`"new value"`)})
+	datastore.Send(test.Str("user/1/name"))
+	if _, err := c.Next(ctx); err != nil {
+		t.Fatalf("Second Next() returned an unexpected error: %v", err)
+	}
+
+	cancel()
+	for i := 0; i < 2; i++ {
+		if _, err := c.Next(ctx); err == nil {
+			t.Fatalf("Next() after cancel did not return an error")
+		}
+	}
+
+	if expect := []string{"subscribe", "update", "unsubscribe"}; !reflect.DeepEqual(events, expect) {
+		t.Errorf("Got hooks %v, expected %v", events, expect)
+	}
+	if expect := test.Str("user/1/name", "user/2/name"); !reflect.DeepEqual(subscribedKeys, expect) {
+		t.Errorf("OnSubscribe got keys %v, expected %v", subscribedKeys, expect)
+	}
+	if expect := []int{1}; !reflect.DeepEqual(updateKeys, expect) {
+		t.Errorf("OnUpdate got numKeys %v, expected %v", updateKeys, expect)
+	}
+}
@@ -3,6 +3,8 @@ package autoupdate
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"time"
 )
 
 // Datastore gets values for keys and informs, if they change.
@@ -11,10 +13,26 @@ type Datastore interface {
 	KeysChanged() ([]string, error)
 }
 
+// CollectionLister returns the ids of all objects of a collection. If the
+// Datastore implements it, WatchCollection uses it to find the existing
+// objects. It is implemented by datastore.Datastore.
+type CollectionLister interface {
+	CollectionIDs(ctx context.Context, collection string) ([]int, error)
+}
+
 // Restricter restricts keys.
 type Restricter interface {
 	// Restrict manipulates the values for the user with the given id.
-	Restrict(uid int, data map[string]json.RawMessage)
+	Restrict(uid int, data map[string]json.RawMessage) error
+}
+
+// Clock tells the time and calls functions after a duration.
+type Clock interface {
+	Now() time.Time
+
+	// AfterFunc calls f after the duration d. The returned function stops the
+	// timer. It returns false, if f was already called.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
 }
 
 // KeysBuilder holds the keys that are requested by a user.
@@ -22,3 +40,17 @@ type KeysBuilder interface {
 	Update() error
 	Keys() []string
 }
+
+// Service is the interface of the autoupdate service, that is used by the
+// http handler. It is implemented by Autoupdate and FilteredService.
+//
+// The other methods of Autoupdate, like SubscribeReader(), are not part of
+// the interface. They connect to the Autoupdate directly.
+type Service interface {
+	Connect(userID int, kb KeysBuilder, tid uint64) *Connection
+	Value(ctx context.Context, uid int, key string, value interface{}) error
+	LastID() uint64
+	SubscribeJSON(ctx context.Context, uid int, body []byte) (io.ReadCloser, error)
+	Subscribers() *SubscriberRegistry
+	io.Closer
+}
@@ -0,0 +1,153 @@
+package autoupdate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// KeyRequestBuilder creates the json body of a key request, as it is expected
+// by the keysbuilder package. Fields without a relation are added with
+// AddField(), related fields with AddRelation().
+//
+// The zero value is ready to use. Errors are returned by Build().
+type KeyRequestBuilder struct {
+	requests []keyRequest
+	err      error
+}
+
+type keyRequest struct {
+	IDs        []int                        `json:"ids"`
+	Collection string                       `json:"collection"`
+	Fields     map[string]*FieldDescription `json:"fields"`
+}
+
+// FieldDescription describes a field that points to other objects. Use one of
+// the constructors Relation(), RelationList(), GenericRelation(),
+// GenericRelationList() or Template() to create it.
+//
+// A nil FieldDescription is a field without a relation.
+type FieldDescription struct {
+	typ        string
+	collection string
+	fields     map[string]*FieldDescription
+	values     *FieldDescription
+}
+
+// Relation describes a field that points to one object of the collection.
+func Relation(collection string, fields map[string]*FieldDescription) *FieldDescription {
+	return &FieldDescription{typ: "relation", collection: collection, fields: fields}
+}
+
+// RelationList describes a field that points to many objects of the
+// collection.
+func RelationList(collection string, fields map[string]*FieldDescription) *FieldDescription {
+	return &FieldDescription{typ: "relation-list", collection: collection, fields: fields}
+}
+
+// GenericRelation describes a field that points to one object given by its
+// fqid.
+func GenericRelation(fields map[string]*FieldDescription) *FieldDescription {
+	return &FieldDescription{typ: "generic-relation", fields: fields}
+}
+
+// GenericRelationList describes a field that points to many objects given by
+// their fqids.
+func GenericRelationList(fields map[string]*FieldDescription) *FieldDescription {
+	return &FieldDescription{typ: "generic-relation-list", fields: fields}
+}
+
+// Template describes a template field. values describes the fields created
+// from the template. It can be nil.
+func Template(values *FieldDescription) *FieldDescription {
+	return &FieldDescription{typ: "template", values: values}
+}
+
+// MarshalJSON encodes the field description as expected by the keysbuilder.
+func (d *FieldDescription) MarshalJSON() ([]byte, error) {
+	if d == nil {
+		return []byte("null"), nil
+	}
+
+	v := map[string]interface{}{"type": d.typ}
+	if d.collection != "" {
+		v["collection"] = d.collection
+	}
+
+	if d.typ == "template" {
+		if d.values != nil {
+			v["values"] = d.values
+		}
+		return json.Marshal(v)
+	}
+
+	fields := d.fields
+	if fields == nil {
+		fields = make(map[string]*FieldDescription)
+	}
+	v["fields"] = fields
+	return json.Marshal(v)
+}
+
+// AddCollection starts a new key request for the given collection and ids.
+func (b *KeyRequestBuilder) AddCollection(collection string, ids []int) *KeyRequestBuilder {
+	if collection == "" && b.err == nil {
+		b.err = errors.New("collection can not be empty")
+	}
+
+	b.requests = append(b.requests, keyRequest{
+		IDs:        ids,
+		Collection: collection,
+		Fields:     make(map[string]*FieldDescription),
+	})
+	return b
+}
+
+// AddField adds a field to the key request that was started with the last
+// call of AddCollection().
+func (b *KeyRequestBuilder) AddField(field string) *KeyRequestBuilder {
+	return b.AddRelation(field, nil)
+}
+
+// AddRelation adds a field with a relation to the key request that was
+// started with the last call of AddCollection().
+func (b *KeyRequestBuilder) AddRelation(field string, d *FieldDescription) *KeyRequestBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	if len(b.requests) == 0 {
+		b.err = fmt.Errorf("field %s was added before a collection", field)
+		return b
+	}
+
+	if field == "" {
+		b.err = errors.New("field can not be empty")
+		return b
+	}
+
+	b.requests[len(b.requests)-1].Fields[field] = d
+	return b
+}
+
+// Build validates the key request and returns it as json.
+func (b *KeyRequestBuilder) Build() ([]byte, error) {
+	if b.err != nil {
+		return nil, fmt.Errorf("invalid key request: %w", b.err)
+	}
+
+	if len(b.requests) == 0 {
+		return nil, errors.New("invalid key request: no collection")
+	}
+
+	for _, r := range b.requests {
+		if len(r.IDs) == 0 {
+			return nil, fmt.Errorf("invalid key request: no ids for collection %s", r.Collection)
+		}
+		if len(r.Fields) == 0 {
+			return nil, fmt.Errorf("invalid key request: no fields for collection %s", r.Collection)
+		}
+	}
+
+	return json.Marshal(b.requests)
+}
@@ -0,0 +1,82 @@
+package autoupdate_test
+
+import (
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+)
+
+func TestKeyRequestBuilder(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		builder *autoupdate.KeyRequestBuilder
+		expect  string
+	}{
+		{
+			"One field",
+			new(autoupdate.KeyRequestBuilder).AddCollection("user", []int{1}).AddField("name"),
+			`[{"ids":[1],"collection":"user","fields":{"name":null}}]`,
+		},
+		{
+			"Many fields",
+			new(autoupdate.KeyRequestBuilder).AddCollection("user", []int{1, 2}).AddField("first").AddField("last"),
+			`[{"ids":[1,2],"collection":"user","fields":{"first":null,"last":null}}]`,
+		},
+		{
+			"Many collections",
+			new(autoupdate.KeyRequestBuilder).AddCollection("user", []int{1}).AddField("name").AddCollection("motion", []int{5}).AddField("title"),
+			`[{"ids":[1],"collection":"user","fields":{"name":null}},{"ids":[5],"collection":"motion","fields":{"title":null}}]`,
+		},
+		{
+			"Relation",
+			new(autoupdate.KeyRequestBuilder).AddCollection("user", []int{1}).AddRelation("note_id", autoupdate.Relation("note", fields{"text": nil})),
+			`[{"ids":[1],"collection":"user","fields":{"note_id":{"collection":"note","fields":{"text":null},"type":"relation"}}}]`,
+		},
+		{
+			"Relation without fields",
+			new(autoupdate.KeyRequestBuilder).AddCollection("user", []int{1}).AddRelation("note_id", autoupdate.Relation("note", nil)),
+			`[{"ids":[1],"collection":"user","fields":{"note_id":{"collection":"note","fields":{},"type":"relation"}}}]`,
+		},
+		{
+			"Generic relation list",
+			new(autoupdate.KeyRequestBuilder).AddCollection("tag", []int{1}).AddRelation("tagged_ids", autoupdate.GenericRelationList(fields{"title": nil})),
+			`[{"ids":[1],"collection":"tag","fields":{"tagged_ids":{"fields":{"title":null},"type":"generic-relation-list"}}}]`,
+		},
+		{
+			"Template",
+			new(autoupdate.KeyRequestBuilder).AddCollection("user", []int{1}).AddRelation("group_$_ids", autoupdate.Template(autoupdate.RelationList("group", fields{"name": nil}))),
+			`[{"ids":[1],"collection":"user","fields":{"group_$_ids":{"type":"template","values":{"collection":"group","fields":{"name":null},"type":"relation-list"}}}}]`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.Build()
+			if err != nil {
+				t.Fatalf("Build() returned an unexpected error: %v", err)
+			}
+
+			if string(got) != tt.expect {
+				t.Errorf("Build() returned %s, expected %s", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestKeyRequestBuilderInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		builder *autoupdate.KeyRequestBuilder
+	}{
+		{"Empty", new(autoupdate.KeyRequestBuilder)},
+		{"Field without collection", new(autoupdate.KeyRequestBuilder).AddField("name")},
+		{"Empty collection", new(autoupdate.KeyRequestBuilder).AddCollection("", []int{1}).AddField("name")},
+		{"No ids", new(autoupdate.KeyRequestBuilder).AddCollection("user", nil).AddField("name")},
+		{"No fields", new(autoupdate.KeyRequestBuilder).AddCollection("user", []int{1})},
+		{"Empty field", new(autoupdate.KeyRequestBuilder).AddCollection("user", []int{1}).AddField("")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.builder.Build(); err == nil {
+				t.Errorf("Build() did not return an error")
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package autoupdate_test
 
 import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
 	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
 	"github.com/openslides/openslides-autoupdate-service/internal/test"
 )
@@ -28,3 +33,101 @@ func getConnection() (connection *autoupdate.Connection, datastore *test.MockDat
 		datastore.Close()
 	}
 }
+
+// blockingDatastore is a MockDatastore where one call to Get can be blocked.
+type blockingDatastore struct {
+	*test.MockDatastore
+
+	mu    sync.Mutex
+	block *blocker
+}
+
+type blocker struct {
+	waiting chan struct{}
+	release chan struct{}
+}
+
+// blockNext blocks the next call to Get. The returned channel is closed, when
+// Get was called. Get returns after release was called.
+func (d *blockingDatastore) blockNext() (waiting <-chan struct{}, release func()) {
+	b := &blocker{
+		waiting: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	d.mu.Lock()
+	d.block = b
+	d.mu.Unlock()
+	return b.waiting, func() { close(b.release) }
+}
+
+func (d *blockingDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	d.mu.Lock()
+	b := d.block
+	d.block = nil
+	d.mu.Unlock()
+
+	if b != nil {
+		close(b.waiting)
+		<-b.release
+	}
+	return d.MockDatastore.Get(ctx, keys...)
+}
+
+// mockClock is a clock that only changes, when it is advanced. The functions
+// of AfterFunc are called by Advance.
+type mockClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*mockTimer
+}
+
+// mockTimer is a function that is called at a specific time.
+type mockTimer struct {
+	at   time.Time
+	f    func()
+	done bool
+}
+
+func (c *mockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *mockClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &mockTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		stopped := !t.done
+		t.done = true
+		return stopped
+	}
+}
+
+// Advance moves the clock forward and calls the functions of the timers, that
+// run out.
+func (c *mockClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+
+	var due []func()
+	for _, t := range c.timers {
+		if !t.done && !t.at.After(c.now) {
+			t.done = true
+			due = append(due, t.f)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, f := range due {
+		f()
+	}
+}
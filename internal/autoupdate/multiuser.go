@@ -0,0 +1,182 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+// MultiUserUpdate is the data for one user of SubscribeMultiUser.
+type MultiUserUpdate struct {
+	UserID int
+	Data   map[string]json.RawMessage
+}
+
+// MultiUserSubscription holds a connection for many users to the same keys.
+// The data of all connections is sent to one channel. Users can be added and
+// removed at any time.
+//
+// Has to be created with Autoupdate.NewMultiUserSubscription().
+type MultiUserSubscription struct {
+	service *Autoupdate
+	keys    []string
+	ctx     context.Context
+	updates chan MultiUserUpdate
+
+	// wg counts the running connections. The channel is closed, when the
+	// context is done and all connections have stopped.
+	wg sync.WaitGroup
+
+	// mu protects the fields below.
+	mu      sync.Mutex
+	members map[int]*multiUserMember
+	stopped bool
+}
+
+// multiUserMember is the connection of one user.
+type multiUserMember struct {
+	cancel context.CancelFunc
+}
+
+// SubscribeMultiUser creates a connection for each user to the given keys and
+// sends the data of all of them to the returned channel. The first update of
+// each user contains all values.
+//
+// The channel is closed, when the context is done. A user, whose connection
+// returns an error, for example when the service is closed, gets no further
+// updates.
+//
+// Use NewMultiUserSubscription() to add or remove users later.
+func (a *Autoupdate) SubscribeMultiUser(ctx context.Context, userIDs []int, keys []string) (<-chan MultiUserUpdate, error) {
+	s, err := a.NewMultiUserSubscription(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, uid := range userIDs {
+		s.Add(uid)
+	}
+	return s.Updates(), nil
+}
+
+// NewMultiUserSubscription creates a MultiUserSubscription without users. It
+// stops, when the context is done.
+func (a *Autoupdate) NewMultiUserSubscription(ctx context.Context, keys []string) (*MultiUserSubscription, error) {
+	keys, err := a.expandKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := key.Validate(keys...); err != nil {
+		return nil, err
+	}
+
+	m := &MultiUserSubscription{
+		service: a,
+		keys:    keys,
+		ctx:     ctx,
+		updates: make(chan MultiUserUpdate),
+		members: make(map[int]*multiUserMember),
+	}
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		m.stopped = true
+		m.mu.Unlock()
+
+		m.wg.Wait()
+		close(m.updates)
+	}()
+
+	return m, nil
+}
+
+// Updates returns the channel with the data of all users.
+func (m *MultiUserSubscription) Updates() <-chan MultiUserUpdate {
+	return m.updates
+}
+
+// Add creates a connection for the user. It does nothing, if the user is
+// already a member or the subscription is stopped.
+func (m *MultiUserSubscription) Add(uid int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.members[uid]; ok || m.stopped {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	member := &multiUserMember{cancel: cancel}
+	m.members[uid] = member
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.pump(ctx, uid, member)
+	}()
+}
+
+// Remove stops the connection of the user. An update of the user, that was
+// already waiting for the channel, can still be sent after Remove returns.
+func (m *MultiUserSubscription) Remove(uid int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if member, ok := m.members[uid]; ok {
+		member.cancel()
+		delete(m.members, uid)
+	}
+}
+
+// Users returns the ids of all users, that are members.
+func (m *MultiUserSubscription) Users() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	uids := make([]int, 0, len(m.members))
+	for uid := range m.members {
+		uids = append(uids, uid)
+	}
+	return uids
+}
+
+// pump sends the data of the connection of one user to the shared channel.
+func (m *MultiUserSubscription) pump(ctx context.Context, uid int, member *multiUserMember) {
+	c := m.service.Connect(uid, staticKeys(m.keys), m.service.LastID())
+	defer c.register()()
+
+	defer func() {
+		m.mu.Lock()
+		if m.members[uid] == member {
+			delete(m.members, uid)
+		}
+		m.mu.Unlock()
+		member.cancel()
+	}()
+
+	for {
+		data, err := c.Next(ctx)
+		if err != nil {
+			return
+		}
+
+		if len(data) == 0 {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			// The user was removed while Next() was running.
+			return
+		}
+
+		select {
+		case m.updates <- MultiUserUpdate{UserID: uid, Data: data}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+// readUsers reads n updates from the channel and returns the sorted user ids.
+func readUsers(t *testing.T, ch <-chan autoupdate.MultiUserUpdate, n int) []int {
+	t.Helper()
+
+	var uids []int
+	for i := 0; i < n; i++ {
+		select {
+		case u, ok := <-ch:
+			if !ok {
+				t.Fatalf("Channel was closed after %d updates, expected %d", i, n)
+			}
+			uids = append(uids, u.UserID)
+		case <-time.After(time.Second):
+			t.Fatalf("Got %d updates, expected %d", i, n)
+		}
+	}
+	sort.Ints(uids)
+	return uids
+}
+
+func TestSubscribeMultiUser(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := s.SubscribeMultiUser(ctx, []int{1, 2}, test.Str("agenda/1/title"))
+	if err != nil {
+		t.Fatalf("SubscribeMultiUser() returned an unexpected error: %v", err)
+	}
+
+	if got := readUsers(t, ch, 2); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("Got first data for users %v, expected [1 2]", got)
+	}
+
+	datastore.Update(map[string]json.RawMessage{"agenda/1/title": []byte(`"new"`)})
+	datastore.Send(test.Str("agenda/1/title"))
+
+	if got := readUsers(t, ch, 2); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("Got update for users %v, expected [1 2]", got)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("Got an update after the context was canceled")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Channel was not closed after the context was canceled")
+	}
+}
+
+func TestMultiUserSubscriptionAddRemove(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m, err := s.NewMultiUserSubscription(ctx, test.Str("agenda/1/title"))
+	if err != nil {
+		t.Fatalf("NewMultiUserSubscription() returned an unexpected error: %v", err)
+	}
+	ch := m.Updates()
+
+	m.Add(1)
+	m.Add(2)
+	readUsers(t, ch, 2)
+
+	m.Add(3)
+	if got := readUsers(t, ch, 1); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("Got first data for users %v after Add(3), expected [3]", got)
+	}
+
+	m.Remove(1)
+	users := m.Users()
+	sort.Ints(users)
+	if !reflect.DeepEqual(users, []int{2, 3}) {
+		t.Errorf("Users() returned %v, expected [2 3]", users)
+	}
+
+	datastore.Update(map[string]json.RawMessage{"agenda/1/title": []byte(`"new"`)})
+	datastore.Send(test.Str("agenda/1/title"))
+
+	if got := readUsers(t, ch, 2); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("Got update for users %v after Remove(1), expected [2 3]", got)
+	}
+
+	select {
+	case u := <-ch:
+		t.Errorf("Got unexpected update for user %d", u.UserID)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestSubscribeMultiUserInvalidKey(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	if _, err := s.SubscribeMultiUser(context.Background(), []int{1}, test.Str("invalid")); err == nil {
+		t.Errorf("SubscribeMultiUser() returned no error for an invalid key")
+	}
+}
@@ -0,0 +1,70 @@
+package autoupdate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+// SubscribeOnce returns the restricted values for the given keys like the
+// first data of a connection. It does not wait for updates.
+//
+// Keys that do not exist are not in the returned map.
+func (a *Autoupdate) SubscribeOnce(ctx context.Context, uid int, keys []string) (map[string]json.RawMessage, error) {
+	keys, err := a.expandKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := key.Validate(keys...); err != nil {
+		return nil, err
+	}
+
+	data, err := a.Connect(uid, staticKeys(keys), 0).Next(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get data: %w", err)
+	}
+	return data, nil
+}
+
+// SubscribeDelta is like SubscribeOnce() but only returns the keys, that have
+// a different value then in since. since are the values, that the client got
+// from an earlier call. The values are compared byte by byte.
+//
+// Keys that were deleted since the earlier call are returned with the value
+// null.
+func (a *Autoupdate) SubscribeDelta(ctx context.Context, uid int, keys []string, since map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	keys, err := a.expandKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := key.Validate(keys...); err != nil {
+		return nil, err
+	}
+
+	data, err := a.Connect(uid, staticKeys(keys), 0).Next(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get data: %w", err)
+	}
+
+	delta := make(map[string]json.RawMessage)
+	for _, k := range keys {
+		value := data[k]
+		old, existed := since[k]
+		if len(value) == 0 {
+			if existed && len(old) > 0 && string(old) != "null" {
+				delta[k] = []byte("null")
+			}
+			continue
+		}
+
+		if !bytes.Equal(value, old) {
+			delta[k] = value
+		}
+	}
+	return delta, nil
+}
@@ -0,0 +1,134 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestSubscribeOnce(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.Data = map[string]json.RawMessage{
+		"user/1/name": []byte(`"emma"`),
+		"user/2/name": nil,
+	}
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	keys := test.Str("user/1/name", "user/2/name", "user/3/name")
+
+	got, err := s.SubscribeOnce(context.Background(), 1, keys)
+	if err != nil {
+		t.Fatalf("SubscribeOnce() returned an unexpected error: %v", err)
+	}
+
+	expect, err := s.Connect(1, mockKeysBuilder{keys: keys}, 0).Next(context.Background())
+	if err != nil {
+		t.Fatalf("Next() returned an unexpected error: %v", err)
+	}
+
+	if len(got) != len(expect) {
+		t.Fatalf("SubscribeOnce() returned %d keys, expected %d", len(got), len(expect))
+	}
+	for key, value := range expect {
+		if string(got[key]) != string(value) {
+			t.Errorf("SubscribeOnce() returned `%s` for key %s, expected `%s`", got[key], key, value)
+		}
+	}
+}
+
+func TestSubscribeOnceInvalidKey(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	_, err := s.SubscribeOnce(context.Background(), 1, test.Str("user/1"))
+
+	var invalid key.InvalidKeyError
+	if !errors.As(err, &invalid) {
+		t.Errorf("SubscribeOnce() returned error `%v`, expected an InvalidKeyError", err)
+	}
+}
+
+func TestSubscribeDelta(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.OnlyData = true
+	datastore.Data = map[string]json.RawMessage{
+		"user/1/name": []byte(`"emma"`),
+		"user/2/name": []byte(`"hugo"`),
+	}
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	keys := test.Str("user/1/name", "user/2/name", "user/3/name")
+
+	t.Run("unchanged", func(t *testing.T) {
+		since := map[string]json.RawMessage{
+			"user/1/name": []byte(`"emma"`),
+			"user/2/name": []byte(`"hugo"`),
+		}
+
+		got, err := s.SubscribeDelta(context.Background(), 1, keys, since)
+		if err != nil {
+			t.Fatalf("SubscribeDelta() returned an unexpected error: %v", err)
+		}
+
+		if len(got) != 0 {
+			t.Errorf("SubscribeDelta() returned %v, expected an empty map", got)
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		since := map[string]json.RawMessage{
+			"user/1/name": []byte(`"emma"`),
+			"user/2/name": []byte(`"old"`),
+			"user/3/name": []byte(`"deleted"`),
+		}
+
+		got, err := s.SubscribeDelta(context.Background(), 1, keys, since)
+		if err != nil {
+			t.Fatalf("SubscribeDelta() returned an unexpected error: %v", err)
+		}
+
+		expect := map[string]string{
+			"user/2/name": `"hugo"`,
+			"user/3/name": `null`,
+		}
+		if len(got) != len(expect) {
+			t.Fatalf("SubscribeDelta() returned %v, expected %v", got, expect)
+		}
+		for key, value := range expect {
+			if string(got[key]) != value {
+				t.Errorf("SubscribeDelta() returned `%s` for key %s, expected `%s`", got[key], key, value)
+			}
+		}
+	})
+}
+
+func TestSubscribeOnceKeyRange(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithMaxKeyRange(5))
+	defer s.Close()
+
+	got, err := s.SubscribeOnce(context.Background(), 1, test.Str("user/[1-3]/name"))
+	if err != nil {
+		t.Fatalf("SubscribeOnce() returned an unexpected error: %v", err)
+	}
+
+	if len(got) != 3 || got["user/1/name"] == nil || got["user/3/name"] == nil {
+		t.Errorf("SubscribeOnce() returned %v, expected user/1/name to user/3/name", got)
+	}
+
+	_, err = s.SubscribeOnce(context.Background(), 1, test.Str("user/[1-6]/name"))
+	var rangeErr key.InvalidRangeError
+	if !errors.As(err, &rangeErr) {
+		t.Errorf("SubscribeOnce() returned error `%v` for a too big range, expected an InvalidRangeError", err)
+	}
+}
@@ -0,0 +1,181 @@
+package autoupdate
+
+import "time"
+
+// Option is an optional argument for autoupdate.New().
+type Option func(*Autoupdate)
+
+// WithBatchUpdates merges all updates, that are available when a connection
+// sends data, into one frame. Without this option, each update from the
+// datastore is returned separately.
+func WithBatchUpdates(batch bool) Option {
+	return func(a *Autoupdate) {
+		a.batchUpdates = batch
+	}
+}
+
+// WithNotifyOnEmpty closes a connection, when all requested keys are empty for
+// two update cycles in a row. This happens, when the requested objects were
+// deleted. In this case, Connection.Next() returns a DeletedError.
+func WithNotifyOnEmpty(notify bool) Option {
+	return func(a *Autoupdate) {
+		a.notifyOnEmpty = notify
+	}
+}
+
+// WithRecurringUpdateInterval sends the values of all requested keys again,
+// when a connection did not get any data for the duration d. This helps
+// clients that missed an update.
+func WithRecurringUpdateInterval(d time.Duration) Option {
+	return func(a *Autoupdate) {
+		a.recurringInterval = d
+	}
+}
+
+// WithIdleTimeout closes a connection, when the client does not ask for new
+// data for the duration d. This happens, when a client stops reading but does
+// not close the connection. A timer is started each time Connection.Next()
+// returns. If it runs out, the next call to Next() returns an
+// IdleTimeoutError.
+//
+// The readers of SubscribeReader() are closed with an IdleTimeoutError by the
+// timer, even when a write to them blocks. The http handler needs a write
+// timeout for the same effect.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(a *Autoupdate) {
+		a.idleTimeout = d
+	}
+}
+
+// WithRestricterRetries calls the restricter again, when it returns a
+// TransientRestricterError. It works like RetryableRestricter() but stops
+// waiting for the next try, when the service is closed.
+func WithRestricterRetries(maxRetries int, backoff time.Duration) Option {
+	return func(a *Autoupdate) {
+		a.restricterRetries = maxRetries
+		a.restricterBackoff = backoff
+	}
+}
+
+// WithErrorPolicy sets, how an error from the restricter affects the other
+// keys, that are restricted at the same time. The default is FailAll.
+//
+// With FailKey, the keys that can not be restricted get the value null.
+func WithErrorPolicy(p ErrorPolicy) Option {
+	return func(a *Autoupdate) {
+		a.errorPolicy = p
+	}
+}
+
+// WithUpdateFilter sets a function, that decides for each changed key, if it
+// is sent to the client. It gets the value, that was sent before, and the new
+// value. When it returns false, the key is not included in the update. It is
+// not called for the first data of a connection.
+//
+// It is called in the goroutine that calls Connection.Next(), so it has to be
+// fast.
+func WithUpdateFilter(fn SubscriptionFilter) Option {
+	return func(a *Autoupdate) {
+		a.updateFilter = fn
+	}
+}
+
+// WithReadinessWindow sets, how long a failed call to the datastore makes the
+// service not ready. See ReadinessProbe(). The default is 30 seconds.
+func WithReadinessWindow(d time.Duration) Option {
+	return func(a *Autoupdate) {
+		a.readinessWindow = d
+	}
+}
+
+// WithClock sets the clock that is used for the idle timeout and the
+// readiness window. The default is the system time. It can be used in tests.
+func WithClock(c Clock) Option {
+	return func(a *Autoupdate) {
+		a.clock = c
+	}
+}
+
+// systemClock is the Clock that uses the system time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// WithPauseQueueSize sets the number of updates that are queued while the
+// service is paused. The default is 1000.
+func WithPauseQueueSize(size int) Option {
+	return func(a *Autoupdate) {
+		a.pauseQueueSize = size
+	}
+}
+
+// WithOnSubscribe sets a hook that is called, when a connection returns its
+// first data. It gets the user id and the requested keys.
+//
+// The hooks are called in the goroutine that calls Connection.Next(), so they
+// have to be fast.
+func WithOnSubscribe(fn func(userID int, keys []string)) Option {
+	return func(a *Autoupdate) {
+		a.onSubscribe = fn
+	}
+}
+
+// WithOnUpdate sets a hook that is called, when a connection returns data
+// after the first time. It gets the user id and the number of returned keys.
+func WithOnUpdate(fn func(userID int, numKeys int)) Option {
+	return func(a *Autoupdate) {
+		a.onUpdate = fn
+	}
+}
+
+// WithOnUnsubscribe sets a hook that is called, when a connection that
+// returned data ends with an error. This happens for example, when the
+// context of the client is done or the service is closed.
+func WithOnUnsubscribe(fn func(userID int)) Option {
+	return func(a *Autoupdate) {
+		a.onUnsubscribe = fn
+	}
+}
+
+// WithComputedFields registers fields, that are computed from other fields.
+// When a source key of a computed field changes, the computed field is
+// updated for all connections, that requested it.
+func WithComputedFields(fields ...ComputedField) Option {
+	return func(a *Autoupdate) {
+		if a.computed == nil {
+			a.computed = make(map[string]ComputedField, len(fields))
+		}
+		for _, cf := range fields {
+			a.computed[cf.Key] = cf
+		}
+	}
+}
+
+// WithReadYourWritesConsistency lets connections wait with their first data,
+// until the datastore has the write version of the context. See
+// WithWriteVersion(). This makes sure, that a user who creates an object and
+// subscribes to it afterwards gets the new object.
+//
+// The datastore has to implement VersionedDatastore.
+func WithReadYourWritesConsistency(enabled bool) Option {
+	return func(a *Autoupdate) {
+		a.readYourWrites = enabled
+	}
+}
+
+// WithMaxKeyRange sets the maximum number of ids in a key with an id range
+// like user/[1-100]/name. The default is 1000.
+//
+// The ranges are expanded by SubscribeReader(), SubscribeOnce(),
+// SubscribeDelta(), SubscribeRaw() and ServeStream().
+func WithMaxKeyRange(max int) Option {
+	return func(a *Autoupdate) {
+		a.maxKeyRange = max
+	}
+}
@@ -0,0 +1,82 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestPauseResume(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	updater := test.NewUpdaterMock()
+	defer updater.Close()
+	s := autoupdate.New(datastore.New(ts.TS.URL, updater), new(test.MockRestricter))
+	defer s.Close()
+
+	var keys []string
+	for i := 1; i <= 5; i++ {
+		keys = append(keys, fmt.Sprintf("user/%d/name", i))
+	}
+	c := s.Connect(1, mockKeysBuilder{keys: keys}, 0)
+	if _, err := c.Next(context.Background()); err != nil {
+		t.Fatalf("First Next() returned an unexpected error: %v", err)
+	}
+
+	s.Pause()
+	for i, key := range keys {
+		s.UpdateKeys(map[string]json.RawMessage{key: []byte(fmt.Sprintf(`"update %d"`, i+1))})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if data, err := c.Next(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Next() on paused service returned %v (data: %v), expected context.DeadlineExceeded", err, data)
+	}
+
+	s.Resume()
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got := make(map[string]string)
+	for len(got) < len(keys) {
+		data, err := c.Next(ctx)
+		if err != nil {
+			t.Fatalf("Next() returned an unexpected error after %d updates: %v", len(got), err)
+		}
+		for key, value := range data {
+			got[key] = string(value)
+		}
+	}
+
+	for i, key := range keys {
+		if expect := fmt.Sprintf(`"update %d"`, i+1); got[key] != expect {
+			t.Errorf("Got %s for key %s, expected %s", got[key], key, expect)
+		}
+	}
+}
+
+func TestPauseQueueSize(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithPauseQueueSize(2))
+	defer s.Close()
+	lastID := s.LastID()
+
+	s.Pause()
+	for i := 1; i <= 5; i++ {
+		s.UpdateKeys(map[string]json.RawMessage{fmt.Sprintf("user/%d/name", i): nil})
+	}
+	s.Resume()
+
+	if got := s.LastID() - lastID; got != 2 {
+		t.Errorf("Resume() published %d updates, expected 2", got)
+	}
+}
@@ -0,0 +1,77 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// backgroundJobs is the number of goroutines, that are started by New().
+const backgroundJobs = 2
+
+// defaultReadinessWindow is the default for WithReadinessWindow().
+const defaultReadinessWindow = 30 * time.Second
+
+// LivenessProbe returns nil, if the background jobs of the service are
+// running. It can be used for a kubernetes liveness probe.
+func (a *Autoupdate) LivenessProbe() error {
+	select {
+	case <-a.closed:
+		return closedError{}
+	default:
+	}
+
+	if running := atomic.LoadInt32(&a.runningJobs); running != backgroundJobs {
+		return fmt.Errorf("%d of %d background jobs are running", running, backgroundJobs)
+	}
+	return nil
+}
+
+// ReadinessProbe returns nil, if the service can deliver data. It can be used
+// for a kubernetes readiness probe.
+//
+// The service is not ready before the first successful response from the
+// datastore, so the cache is warm. Afterwards, it is not ready, when the last
+// call to the datastore failed less then the readiness window ago.
+func (a *Autoupdate) ReadinessProbe() error {
+	select {
+	case <-a.closed:
+		return closedError{}
+	default:
+	}
+
+	lastSuccess := atomic.LoadInt64(&a.lastDatastoreSuccess)
+	if lastSuccess == 0 {
+		return errors.New("cache is not warm: the datastore did not respond yet")
+	}
+
+	lastFailure := atomic.LoadInt64(&a.lastDatastoreFailure)
+	if lastFailure > lastSuccess && a.clock.Now().Sub(time.Unix(0, lastFailure)) < a.readinessWindow {
+		return fmt.Errorf("datastore did not respond since %s", time.Unix(0, lastSuccess).Format(time.RFC3339))
+	}
+	return nil
+}
+
+// observeDatastore remembers, if a call to the datastore was successful.
+// Errors from a canceled context are ignored.
+func (a *Autoupdate) observeDatastore(ctx context.Context, err error) {
+	if err == nil {
+		atomic.StoreInt64(&a.lastDatastoreSuccess, a.clock.Now().UnixNano())
+		return
+	}
+
+	if ctx.Err() == nil {
+		atomic.StoreInt64(&a.lastDatastoreFailure, a.clock.Now().UnixNano())
+	}
+}
+
+// getValues calls datastore.Get() and remembers the result for the
+// ReadinessProbe.
+func (a *Autoupdate) getValues(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	values, err := a.datastore.Get(ctx, keys...)
+	a.observeDatastore(ctx, err)
+	return values, err
+}
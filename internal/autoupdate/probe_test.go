@@ -0,0 +1,104 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+// unavailableDatastore is a MockDatastore, that returns an error from Get(),
+// while it is unavailable.
+type unavailableDatastore struct {
+	*test.MockDatastore
+
+	mu          sync.Mutex
+	unavailable bool
+}
+
+func (d *unavailableDatastore) setUnavailable(unavailable bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.unavailable = unavailable
+}
+
+func (d *unavailableDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	d.mu.Lock()
+	unavailable := d.unavailable
+	d.mu.Unlock()
+
+	if unavailable {
+		return nil, errors.New("datastore is not available")
+	}
+	return d.MockDatastore.Get(ctx, keys...)
+}
+
+func TestReadinessProbe(t *testing.T) {
+	datastore := &unavailableDatastore{MockDatastore: test.NewMockDatastore()}
+	defer datastore.Close()
+	clock := &mockClock{now: time.Now()}
+	s := autoupdate.New(
+		datastore,
+		new(test.MockRestricter),
+		autoupdate.WithClock(clock),
+		autoupdate.WithReadinessWindow(time.Minute),
+	)
+	defer s.Close()
+
+	if err := s.ReadinessProbe(); err == nil {
+		t.Errorf("ReadinessProbe() returned no error before the first data was fetched")
+	}
+
+	if _, err := s.SubscribeOnce(context.Background(), 1, test.Str("user/1/name")); err != nil {
+		t.Fatalf("SubscribeOnce() returned an unexpected error: %v", err)
+	}
+
+	if err := s.ReadinessProbe(); err != nil {
+		t.Errorf("ReadinessProbe() returned an unexpected error: %v", err)
+	}
+
+	datastore.setUnavailable(true)
+	clock.Advance(time.Second)
+	if _, err := s.SubscribeOnce(context.Background(), 1, test.Str("user/1/name")); err == nil {
+		t.Fatalf("SubscribeOnce() returned no error while the datastore is not available")
+	}
+
+	if err := s.ReadinessProbe(); err == nil {
+		t.Errorf("ReadinessProbe() returned no error while the datastore is not available")
+	}
+
+	clock.Advance(2 * time.Minute)
+	if err := s.ReadinessProbe(); err != nil {
+		t.Errorf("ReadinessProbe() returned an error after the readiness window: %v", err)
+	}
+
+	datastore.setUnavailable(false)
+	if _, err := s.SubscribeOnce(context.Background(), 1, test.Str("user/1/name")); err != nil {
+		t.Fatalf("SubscribeOnce() returned an unexpected error: %v", err)
+	}
+
+	if err := s.ReadinessProbe(); err != nil {
+		t.Errorf("ReadinessProbe() returned an unexpected error after the datastore is available again: %v", err)
+	}
+}
+
+func TestLivenessProbe(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+
+	if err := s.LivenessProbe(); err != nil {
+		t.Errorf("LivenessProbe() returned an unexpected error: %v", err)
+	}
+
+	s.Close()
+
+	if err := s.LivenessProbe(); err == nil {
+		t.Errorf("LivenessProbe() returned no error after the service was closed")
+	}
+}
@@ -0,0 +1,270 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// rawQueueSize is the number of frames that are buffered for each subscriber
+// of SubscribeRaw.
+const rawQueueSize = 16
+
+// SubscribeRaw is like SubscribeReader() but returns each update as an encoded
+// json object. All subscriptions of the same user to the same keys share one
+// connection. Each update is encoded only once for all of them.
+//
+// The first frame contains all values. It is fetched before the method
+// returns, errors on this first fetch are returned directly. The first fetch
+// uses the context of the first subscriber. Other subscribers of the same keys
+// wait for it.
+//
+// The channel is closed, when the context is done, the service is closed or
+// the connection returns an error. A subscriber that does not read fast enough
+// so that rawQueueSize frames are waiting is removed and its channel is
+// closed.
+func (a *Autoupdate) SubscribeRaw(ctx context.Context, uid int, keys []string) (<-chan []byte, error) {
+	keys, err := a.expandKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	feedKey := rawFeedKey(uid, keys)
+
+	for {
+		a.rawMu.Lock()
+		if a.rawFeeds == nil {
+			a.rawFeeds = make(map[string]*rawFeed)
+		}
+
+		feed, exists := a.rawFeeds[feedKey]
+		if !exists {
+			feed = newRawFeed()
+			a.rawFeeds[feedKey] = feed
+		}
+		a.rawMu.Unlock()
+
+		if !exists {
+			// The first fetch is done without the lock, so other feeds are
+			// not blocked by the datastore.
+			a.startRawFeed(ctx, feed, feedKey, uid, keys)
+		}
+
+		select {
+		case <-feed.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if err := feed.err; err != nil {
+			if exists && isContextErr(err) {
+				// The context of the first subscriber was canceled. Try
+				// again with this one.
+				continue
+			}
+			return nil, err
+		}
+
+		ch, ok, err := feed.subscribe(ctx)
+		if ok || err != nil {
+			return ch, err
+		}
+		// The feed is stopping. Start a new one.
+	}
+}
+
+// isContextErr returns true, if the error was created by a canceled context.
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// rawFeedKey returns an identifier for the user and the keys. The order of
+// the keys does not matter.
+func rawFeedKey(uid int, keys []string) string {
+	sorted := make([]string, len(keys))
+	copy(sorted, keys)
+	sort.Strings(sorted)
+	return fmt.Sprintf("%d:%s", uid, strings.Join(sorted, ","))
+}
+
+// rawFeed is one connection that sends its encoded data to many subscribers.
+type rawFeed struct {
+	cancel context.CancelFunc
+
+	// ready is closed, after the first fetch is done. If it failed, err is
+	// set.
+	ready chan struct{}
+	err   error
+
+	// closed is closed, when the background job of the feed has stopped.
+	closed chan struct{}
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// state holds the values of all keys, that were sent until now. It is
+	// sent as first frame to new subscribers.
+	state       map[string]json.RawMessage
+	subscribers map[chan []byte]struct{}
+
+	// stopped is true, when the feed does not accept new subscribers.
+	stopped bool
+}
+
+func newRawFeed() *rawFeed {
+	return &rawFeed{
+		ready:       make(chan struct{}),
+		closed:      make(chan struct{}),
+		subscribers: make(map[chan []byte]struct{}),
+	}
+}
+
+// startRawFeed creates a connection, fetches the first data with the given
+// context and starts the background job of the feed. It closes feed.ready.
+//
+// If the first fetch fails, the feed is removed from a.rawFeeds.
+func (a *Autoupdate) startRawFeed(ctx context.Context, feed *rawFeed, feedKey string, uid int, keys []string) {
+	defer close(feed.ready)
+
+	c := a.Connect(uid, staticKeys(keys), a.LastID())
+
+	data, err := c.Next(ctx)
+	if err != nil {
+		feed.err = fmt.Errorf("get first data: %w", err)
+		a.rawMu.Lock()
+		if a.rawFeeds[feedKey] == feed {
+			delete(a.rawFeeds, feedKey)
+		}
+		a.rawMu.Unlock()
+		return
+	}
+
+	feedCtx, cancel := context.WithCancel(context.Background())
+	feed.cancel = cancel
+	feed.state = data
+
+	go func() {
+		defer func() {
+			a.rawMu.Lock()
+			if a.rawFeeds[feedKey] == feed {
+				delete(a.rawFeeds, feedKey)
+			}
+			a.rawMu.Unlock()
+			feed.close()
+		}()
+
+		for {
+			data, err := c.Next(feedCtx)
+			if err != nil {
+				return
+			}
+
+			if err := feed.broadcast(data); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// subscribe adds a new subscriber to the feed. The first frame is the current
+// state of the feed.
+//
+// Returns false, if the feed is stopped and does not accept new subscribers.
+func (f *rawFeed) subscribe(ctx context.Context) (<-chan []byte, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stopped {
+		return nil, false, nil
+	}
+
+	first, err := json.Marshal(f.state)
+	if err != nil {
+		return nil, false, fmt.Errorf("encode first data: %w", err)
+	}
+
+	ch := make(chan []byte, rawQueueSize)
+	ch <- first
+	f.subscribers[ch] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.unsubscribe(ch)
+		case <-f.closed:
+		}
+	}()
+
+	return ch, true, nil
+}
+
+// stopIfEmpty stops the feed, when there are no subscribers.
+//
+// Has to be called with the lock.
+func (f *rawFeed) stopIfEmpty() {
+	if len(f.subscribers) == 0 {
+		f.stopped = true
+		f.cancel()
+	}
+}
+
+// unsubscribe removes the subscriber. The feed is stopped, when it was the
+// last subscriber.
+func (f *rawFeed) unsubscribe(ch chan []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subscribers[ch]; !ok {
+		return
+	}
+
+	delete(f.subscribers, ch)
+	close(ch)
+	f.stopIfEmpty()
+}
+
+// broadcast encodes the data once and sends it to all subscribers.
+func (f *rawFeed) broadcast(data map[string]json.RawMessage) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode data: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for k, v := range data {
+		f.state[k] = v
+	}
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- encoded:
+		default:
+			// The subscriber is too slow.
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	f.stopIfEmpty()
+	return nil
+}
+
+// close closes the channels of all subscribers.
+func (f *rawFeed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = true
+	f.cancel()
+	close(f.closed)
+	for ch := range f.subscribers {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
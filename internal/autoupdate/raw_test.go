@@ -0,0 +1,205 @@
+package autoupdate_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+// receiveRaw returns the next frame from the channel or fails the test after
+// one second.
+func receiveRaw(t testing.TB, ch <-chan []byte) string {
+	t.Helper()
+	select {
+	case frame, ok := <-ch:
+		if !ok {
+			t.Fatalf("Channel was closed")
+		}
+		return string(frame)
+	case <-time.After(time.Second):
+		t.Fatalf("Did not receive a frame")
+	}
+	return ""
+}
+
+func TestSubscribeRaw(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, err := s.SubscribeRaw(ctx, 1, test.Str("user/1/name", "user/2/name"))
+	if err != nil {
+		t.Fatalf("SubscribeRaw() returned an unexpected error: %v", err)
+	}
+	if got, expect := receiveRaw(t, first), `{"user/1/name":"Hello World","user/2/name":"Hello World"}`; got != expect {
+		t.Errorf("Got first frame %s, expected %s", got, expect)
+	}
+
+	datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(`"new value"`)})
+	datastore.Send(test.Str("user/1/name"))
+	if got, expect := receiveRaw(t, first), `{"user/1/name":"new value"}`; got != expect {
+		t.Errorf("Got update %s, expected %s", got, expect)
+	}
+
+	// A second subscriber with the same keys in an other order gets the
+	// current state of the shared connection.
+	second, err := s.SubscribeRaw(ctx, 1, test.Str("user/2/name", "user/1/name"))
+	if err != nil {
+		t.Fatalf("Second SubscribeRaw() returned an unexpected error: %v", err)
+	}
+	if got, expect := receiveRaw(t, second), `{"user/1/name":"new value","user/2/name":"Hello World"}`; got != expect {
+		t.Errorf("Got first frame %s for second subscriber, expected %s", got, expect)
+	}
+
+	datastore.Update(map[string]json.RawMessage{"user/2/name": []byte(`"other value"`)})
+	datastore.Send(test.Str("user/2/name"))
+	expect := `{"user/2/name":"other value"}`
+	for i, ch := range []<-chan []byte{first, second} {
+		if got := receiveRaw(t, ch); got != expect {
+			t.Errorf("Subscriber %d got update %s, expected %s", i, got, expect)
+		}
+	}
+}
+
+func TestSubscribeRawContextDone(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := s.SubscribeRaw(ctx, 1, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("SubscribeRaw() returned an unexpected error: %v", err)
+	}
+	receiveRaw(t, ch)
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("Got a frame after the context was canceled")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Channel was not closed after the context was canceled")
+	}
+
+	// A new subscription after the last one was closed works.
+	ch, err = s.SubscribeRaw(context.Background(), 1, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("SubscribeRaw() returned an unexpected error: %v", err)
+	}
+	if got, expect := receiveRaw(t, ch), `{"user/1/name":"Hello World"}`; got != expect {
+		t.Errorf("Got first frame %s, expected %s", got, expect)
+	}
+}
+
+func BenchmarkSubscribe100(b *testing.B) {
+	const subscribers = 100
+	keys := test.Str("user/1/name", "user/2/name", "user/3/name", "user/4/name", "user/5/name")
+
+	b.Run("SubscribeRaw", func(b *testing.B) {
+		datastore := test.NewMockDatastore()
+		defer datastore.Close()
+		s := autoupdate.New(datastore, new(test.MockRestricter))
+		defer s.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		channels := make([]<-chan []byte, subscribers)
+		for i := range channels {
+			ch, err := s.SubscribeRaw(ctx, 1, keys)
+			if err != nil {
+				b.Fatalf("SubscribeRaw() returned an unexpected error: %v", err)
+			}
+			receiveRaw(b, ch)
+			channels[i] = ch
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			datastore.Send(keys)
+			for _, ch := range channels {
+				receiveRaw(b, ch)
+			}
+		}
+	})
+
+	b.Run("SubscribeReader", func(b *testing.B) {
+		datastore := test.NewMockDatastore()
+		defer datastore.Close()
+		s := autoupdate.New(datastore, new(test.MockRestricter))
+		defer s.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		scanners := make([]*bufio.Scanner, subscribers)
+		for i := range scanners {
+			r, err := s.SubscribeReader(ctx, 1, keys)
+			if err != nil {
+				b.Fatalf("SubscribeReader() returned an unexpected error: %v", err)
+			}
+			defer r.Close()
+			scanners[i] = bufio.NewScanner(r)
+			scanners[i].Scan()
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			datastore.Send(keys)
+			for _, scanner := range scanners {
+				if !scanner.Scan() {
+					b.Fatalf("Can not read from stream: %v", scanner.Err())
+				}
+			}
+		}
+	})
+}
+
+func TestSubscribeRawSlowFirstFetch(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.SimulateLatency("user/1/name", time.Second)
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	slowCtx, cancelSlow := context.WithCancel(context.Background())
+	slowErr := make(chan error)
+	go func() {
+		_, err := s.SubscribeRaw(slowCtx, 1, test.Str("user/1/name"))
+		slowErr <- err
+	}()
+
+	// Give the slow subscription time to start its first fetch.
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	ch, err := s.SubscribeRaw(ctx, 1, test.Str("user/2/name"))
+	if err != nil {
+		t.Fatalf("SubscribeRaw for other keys returned an error: %v", err)
+	}
+	if got := receiveRaw(t, ch); got != `{"user/2/name":"Hello World"}` {
+		t.Errorf("Got first frame %s", got)
+	}
+
+	cancelSlow()
+	select {
+	case err := <-slowErr:
+		if err == nil {
+			t.Errorf("SubscribeRaw with a canceled context returned no error")
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Errorf("SubscribeRaw did not return after its context was canceled")
+	}
+}
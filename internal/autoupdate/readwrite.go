@@ -0,0 +1,80 @@
+package autoupdate
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// versionPollInterval is the time between two calls to
+// VersionedDatastore.Version(), while a connection waits for the datastore.
+const versionPollInterval = 10 * time.Millisecond
+
+// VersionedDatastore is a Datastore, that knows the version of its data. The
+// version increases with each write.
+type VersionedDatastore interface {
+	Datastore
+	Version(ctx context.Context) (uint64, error)
+}
+
+// writeVersionKey is the context key for the write version.
+type writeVersionKey struct{}
+
+// WithWriteVersion returns a context that holds the version of the last write
+// of a user. The http handler gets it from the auth token.
+//
+// With the option WithReadYourWritesConsistency, a connection waits with its
+// first data until the datastore has at least this version.
+func WithWriteVersion(ctx context.Context, version uint64) context.Context {
+	return context.WithValue(ctx, writeVersionKey{}, version)
+}
+
+// writeVersion returns the write version of the context or 0.
+func writeVersion(ctx context.Context) uint64 {
+	v, _ := ctx.Value(writeVersionKey{}).(uint64)
+	return v
+}
+
+// waitForWriteVersion blocks until the datastore has at least the write
+// version of the context.
+//
+// It returns immediately, if the option WithReadYourWritesConsistency is not
+// set, the context has no write version or the datastore does not implement
+// VersionedDatastore.
+func (a *Autoupdate) waitForWriteVersion(ctx context.Context) error {
+	if !a.readYourWrites {
+		return nil
+	}
+
+	want := writeVersion(ctx)
+	if want == 0 {
+		return nil
+	}
+
+	ds, ok := a.datastore.(VersionedDatastore)
+	if !ok {
+		return nil
+	}
+
+	ticker := time.NewTicker(versionPollInterval)
+	defer ticker.Stop()
+
+	for {
+		got, err := ds.Version(ctx)
+		if err != nil {
+			return fmt.Errorf("get datastore version: %w", err)
+		}
+
+		if got >= want {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for datastore version %d, got %d: %w", want, got, ctx.Err())
+		case <-a.closed:
+			return closedError{}
+		case <-ticker.C:
+		}
+	}
+}
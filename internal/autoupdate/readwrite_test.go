@@ -0,0 +1,115 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+// versionedDatastore is a MockDatastore with a version.
+type versionedDatastore struct {
+	*test.MockDatastore
+
+	mu      sync.Mutex
+	version uint64
+}
+
+func (d *versionedDatastore) Version(ctx context.Context) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.version, nil
+}
+
+// write sets the data and the version at the same time.
+func (d *versionedDatastore) write(data map[string]json.RawMessage, version uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Update(data)
+	d.version = version
+}
+
+func newVersionedDatastore() *versionedDatastore {
+	d := &versionedDatastore{MockDatastore: test.NewMockDatastore(), version: 1}
+	d.OnlyData = true
+	d.Data = map[string]json.RawMessage{"motion/1/title": []byte(`"old"`)}
+	return d
+}
+
+func TestReadYourWritesDatastoreBehind(t *testing.T) {
+	datastore := newVersionedDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithReadYourWritesConsistency(true))
+	defer s.Close()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		datastore.write(map[string]json.RawMessage{"motion/1/title": []byte(`"new"`)}, 2)
+	}()
+
+	ctx := autoupdate.WithWriteVersion(context.Background(), 2)
+	data, err := s.Connect(1, mockKeysBuilder{keys: test.Str("motion/1/title")}, 0).Next(ctx)
+	if err != nil {
+		t.Fatalf("Next() returned an unexpected error: %v", err)
+	}
+
+	if got := string(data["motion/1/title"]); got != `"new"` {
+		t.Errorf("Got %s, expected the value after the write", got)
+	}
+}
+
+func TestReadYourWritesDatastoreUpToDate(t *testing.T) {
+	datastore := newVersionedDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithReadYourWritesConsistency(true))
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(autoupdate.WithWriteVersion(context.Background(), 1), time.Second)
+	defer cancel()
+
+	data, err := s.Connect(1, mockKeysBuilder{keys: test.Str("motion/1/title")}, 0).Next(ctx)
+	if err != nil {
+		t.Fatalf("Next() returned an unexpected error: %v", err)
+	}
+
+	if got := string(data["motion/1/title"]); got != `"old"` {
+		t.Errorf("Got %s, expected \"old\"", got)
+	}
+}
+
+func TestReadYourWritesNeverCatchesUp(t *testing.T) {
+	datastore := newVersionedDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithReadYourWritesConsistency(true))
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(autoupdate.WithWriteVersion(context.Background(), 2), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := s.Connect(1, mockKeysBuilder{keys: test.Str("motion/1/title")}, 0).Next(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Next() returned error `%v`, expected a deadline exceeded error", err)
+	}
+}
+
+func TestReadYourWritesDisabled(t *testing.T) {
+	datastore := newVersionedDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	ctx := autoupdate.WithWriteVersion(context.Background(), 2)
+	data, err := s.Connect(1, mockKeysBuilder{keys: test.Str("motion/1/title")}, 0).Next(ctx)
+	if err != nil {
+		t.Fatalf("Next() returned an unexpected error: %v", err)
+	}
+
+	if got := string(data["motion/1/title"]); got != `"old"` {
+		t.Errorf("Got %s, expected \"old\" without waiting", got)
+	}
+}
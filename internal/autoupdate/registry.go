@@ -0,0 +1,118 @@
+package autoupdate
+
+import "sync"
+
+// maxEndedSubscriptions is the number of ended subscriptions, whose version is
+// kept for a reconnect.
+const maxEndedSubscriptions = 1024
+
+// Token identifies a subscription in a SubscriberRegistry.
+type Token string
+
+// SubscriberRegistry holds all active subscriptions of a service. It can be
+// used by admin tools to list the subscriptions.
+//
+// The service registers the streams of SubscribeReader() and the other
+// Subscribe methods by itself. Other users of Connect(), like the http
+// handler, have to register their subscriptions.
+//
+// After a subscription is deregistered, its last version is kept, so a client
+// can reconnect with the token. See LastVersion().
+//
+// The zero value is an empty registry. It is save for concurrent use.
+type SubscriberRegistry struct {
+	set SubscriptionSet
+
+	mu         sync.Mutex
+	ended      map[Token]endedSubscription
+	endedOrder []Token
+}
+
+// endedSubscription is the state of a deregistered subscription.
+type endedSubscription struct {
+	userID  int
+	version uint64
+}
+
+// Register adds the subscription to the registry and returns its token.
+func (r *SubscriberRegistry) Register(sub *Subscription) Token {
+	return Token(r.set.Add(sub))
+}
+
+// Deregister removes the subscription with the token. Nothing happens, if the
+// token is unknown.
+func (r *SubscriberRegistry) Deregister(token Token) {
+	sub, ok := r.set.Get(string(token))
+	if !ok {
+		return
+	}
+	r.set.Remove(string(token))
+
+	if sub.Version() == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ended == nil {
+		r.ended = make(map[Token]endedSubscription)
+	}
+
+	if len(r.endedOrder) >= maxEndedSubscriptions {
+		delete(r.ended, r.endedOrder[0])
+		r.endedOrder = r.endedOrder[1:]
+	}
+	r.ended[token] = endedSubscription{userID: sub.UserID, version: sub.Version()}
+	r.endedOrder = append(r.endedOrder, token)
+}
+
+// LastVersion returns the last version of the subscription with the token. It
+// works for active and for ended subscriptions. The second value is false, if
+// the token is unknown or belongs to another user.
+func (r *SubscriberRegistry) LastVersion(token Token, uid int) (uint64, bool) {
+	if sub, ok := r.set.Get(string(token)); ok {
+		if sub.UserID != uid {
+			return 0, false
+		}
+		return sub.Version(), true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ended, ok := r.ended[token]
+	if !ok || ended.userID != uid {
+		return 0, false
+	}
+	return ended.version, true
+}
+
+// Get returns the subscription with the token.
+func (r *SubscriberRegistry) Get(token Token) (*Subscription, bool) {
+	return r.set.Get(string(token))
+}
+
+// List returns all registered subscriptions in random order.
+func (r *SubscriberRegistry) List() []*Subscription {
+	subs := make([]*Subscription, 0, r.set.Count())
+	r.set.Range(func(sub *Subscription) bool {
+		subs = append(subs, sub)
+		return true
+	})
+	return subs
+}
+
+// Subscribers returns the registry of the active subscriptions of the
+// service.
+func (a *Autoupdate) Subscribers() *SubscriberRegistry {
+	return &a.subscribers
+}
+
+// register adds the connection to the registry of its service. The returned
+// function removes it.
+func (c *Connection) register() func() {
+	registry := &c.autoupdate.subscribers
+	token := registry.Register(&Subscription{UserID: c.uid, Connection: c})
+	return func() { registry.Deregister(token) }
+}
@@ -0,0 +1,106 @@
+package autoupdate_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestSubscriberRegistryConcurrent(t *testing.T) {
+	var registry autoupdate.SubscriberRegistry
+	deregistered := make(map[*autoupdate.Subscription]bool)
+	var mu sync.Mutex
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(uid int) {
+			defer wg.Done()
+
+			sub := &autoupdate.Subscription{UserID: uid}
+			token := registry.Register(sub)
+			registry.List()
+			registry.Deregister(token)
+
+			mu.Lock()
+			deregistered[sub] = true
+			mu.Unlock()
+
+			// A subscription that was deregistered must never be listed
+			// again.
+			for _, listed := range registry.List() {
+				mu.Lock()
+				gone := deregistered[listed]
+				mu.Unlock()
+				if gone {
+					t.Errorf("List() returned the deregistered subscription of user %d", listed.UserID)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(registry.List()); got != 0 {
+		t.Errorf("List() returned %d subscriptions after all were deregistered, expected 0", got)
+	}
+}
+
+func TestSubscriberRegistryService(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	r, err := s.SubscribeReader(context.Background(), 5, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("SubscribeReader() returned an unexpected error: %v", err)
+	}
+
+	// The subscription is registered in the background.
+	waitFor := func(n int) []*autoupdate.Subscription {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			subs := s.Subscribers().List()
+			if len(subs) == n || time.Now().After(deadline) {
+				return subs
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	subs := waitFor(1)
+	if len(subs) != 1 || subs[0].UserID != 5 {
+		t.Fatalf("Got subscriptions %v, expected one of user 5", subs)
+	}
+
+	r.Close()
+	if subs := waitFor(0); len(subs) != 0 {
+		t.Errorf("Got %d subscriptions after the reader was closed, expected 0", len(subs))
+	}
+}
+
+func TestSubscriberRegistryLastVersion(t *testing.T) {
+	var r autoupdate.SubscriberRegistry
+	sub := &autoupdate.Subscription{UserID: 1}
+	token := r.Register(sub)
+	sub.SetVersion(5)
+
+	if v, ok := r.LastVersion(token, 1); !ok || v != 5 {
+		t.Errorf("LastVersion() of active subscription returned %d, %t, expected 5, true", v, ok)
+	}
+
+	r.Deregister(token)
+
+	if v, ok := r.LastVersion(token, 1); !ok || v != 5 {
+		t.Errorf("LastVersion() of ended subscription returned %d, %t, expected 5, true", v, ok)
+	}
+
+	if _, ok := r.LastVersion(token, 2); ok {
+		t.Errorf("LastVersion() returned the version for another user")
+	}
+}
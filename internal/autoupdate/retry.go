@@ -0,0 +1,82 @@
+package autoupdate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TransientRestricterError is an error from a restricter, that can go away
+// when the call is repeated. For example, when the permission service is
+// temporary not available.
+type TransientRestricterError interface {
+	error
+	Transient()
+}
+
+// retryableRestricter is returned by RetryableRestricter.
+type retryableRestricter struct {
+	inner      Restricter
+	maxRetries int
+	backoff    time.Duration
+
+	// closed stops the waiting between the tries. It is nil, when the
+	// restricter is not used with the option WithRestricterRetries.
+	closed <-chan struct{}
+}
+
+// RetryableRestricter returns a Restricter that calls the inner restricter
+// again, when it returns a TransientRestricterError. It retries up to
+// maxRetries times. The wait time before the first retry is backoff and gets
+// doubled for each further retry.
+//
+// Other errors are returned immediately.
+//
+// Use the option WithRestricterRetries to stop waiting for the next try, when
+// the service gets closed.
+func RetryableRestricter(inner Restricter, maxRetries int, backoff time.Duration) Restricter {
+	return &retryableRestricter{
+		inner:      inner,
+		maxRetries: maxRetries,
+		backoff:    backoff,
+	}
+}
+
+// Restrict calls the inner restricter. Each try gets a copy of the data, so a
+// failed try does not change the values.
+func (r *retryableRestricter) Restrict(uid int, data map[string]json.RawMessage) error {
+	wait := r.backoff
+	for try := 0; ; try++ {
+		tryData := make(map[string]json.RawMessage, len(data))
+		for k, v := range data {
+			tryData[k] = v
+		}
+
+		err := r.inner.Restrict(uid, tryData)
+		if err == nil {
+			for k, v := range tryData {
+				data[k] = v
+			}
+			return nil
+		}
+
+		var transient TransientRestricterError
+		if !errors.As(err, &transient) {
+			return err
+		}
+
+		if try >= r.maxRetries {
+			return fmt.Errorf("giving up after %d retries: %w", try, err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-r.closed:
+			timer.Stop()
+			return fmt.Errorf("service closed before retry %d: %w", try+1, err)
+		}
+		wait *= 2
+	}
+}
@@ -0,0 +1,124 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+type transientError struct{}
+
+func (transientError) Error() string { return "service unavailable" }
+func (transientError) Transient()    {}
+
+// failingRestricter returns the errors in order. After all errors are used, it
+// sets all values to "restricted".
+type failingRestricter struct {
+	mu    sync.Mutex
+	errs  []error
+	calls int
+}
+
+func (r *failingRestricter) Restrict(uid int, data map[string]json.RawMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+	for k := range data {
+		data[k] = []byte(`"restricted"`)
+	}
+
+	if len(r.errs) == 0 {
+		return nil
+	}
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+	return err
+}
+
+func (r *failingRestricter) calledOnce() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls > 0
+}
+
+func TestRetryableRestricter(t *testing.T) {
+	errPermanent := errors.New("permanent error")
+
+	for _, tt := range []struct {
+		name      string
+		errs      []error
+		expectErr error
+		calls     int
+	}{
+		{"one transient error", []error{transientError{}}, nil, 2},
+		{"permanent error", []error{errPermanent}, errPermanent, 1},
+		{"max retries", []error{transientError{}, transientError{}, transientError{}, transientError{}}, transientError{}, 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &failingRestricter{errs: tt.errs}
+			r := autoupdate.RetryableRestricter(inner, 3, 0)
+			data := map[string]json.RawMessage{"user/1/name": []byte(`"value"`)}
+
+			err := r.Restrict(1, data)
+
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("Restrict() returned error %v, expected %v", err, tt.expectErr)
+			}
+
+			if inner.calls != tt.calls {
+				t.Errorf("Inner restricter was called %d times, expected %d", inner.calls, tt.calls)
+			}
+
+			expect := `"restricted"`
+			if tt.expectErr != nil {
+				expect = `"value"`
+			}
+			if got := string(data["user/1/name"]); got != expect {
+				t.Errorf("Got value %s, expected %s", got, expect)
+			}
+		})
+	}
+}
+
+func TestRestricterRetriesStopOnClose(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	restricter := &failingRestricter{errs: []error{transientError{}, transientError{}}}
+	s := autoupdate.New(datastore, restricter, autoupdate.WithRestricterRetries(3, time.Hour))
+
+	done := make(chan error, 1)
+	go func() {
+		var value string
+		done <- s.Value(context.Background(), 1, "user/1/name", &value)
+	}()
+
+	// Wait until the first try failed.
+	for i := 0; ; i++ {
+		if i > 100 {
+			t.Fatalf("Restricter was not called")
+		}
+		if restricter.calledOnce() {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	s.Close()
+
+	select {
+	case err := <-done:
+		var transient autoupdate.TransientRestricterError
+		if !errors.As(err, &transient) {
+			t.Errorf("Value() returned error `%v`, expected the transient error", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Value() did not return after the service was closed")
+	}
+}
@@ -0,0 +1,74 @@
+package autoupdate
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// SubscriptionStats holds statistics of one connection.
+type SubscriptionStats struct {
+	UserID       int       `json:"user_id"`
+	UpdatesSent  uint64    `json:"updates_sent"`
+	BytesWritten uint64    `json:"bytes_written"`
+	StartedAt    time.Time `json:"started_at"`
+	LastUpdateAt time.Time `json:"last_update_at"`
+}
+
+// Stats returns the statistics of the connection.
+//
+// UpdatesSent counts the calls to Next() that returned data. BytesWritten has
+// to be counted by the caller of Next() with AddBytesWritten().
+func (c *Connection) Stats() SubscriptionStats {
+	var lastUpdateAt time.Time
+	if nano := atomic.LoadInt64(&c.lastUpdateAt); nano != 0 {
+		lastUpdateAt = time.Unix(0, nano)
+	}
+
+	return SubscriptionStats{
+		UserID:       c.uid,
+		UpdatesSent:  atomic.LoadUint64(&c.updatesSent),
+		BytesWritten: atomic.LoadUint64(&c.bytesWritten),
+		StartedAt:    c.startedAt,
+		LastUpdateAt: lastUpdateAt,
+	}
+}
+
+// AddBytesWritten adds n to the written bytes of the connection.
+func (c *Connection) AddBytesWritten(n int) {
+	atomic.AddUint64(&c.bytesWritten, uint64(n))
+}
+
+// updateSent counts an update that was returned by Next.
+func (c *Connection) updateSent(now time.Time) {
+	atomic.AddUint64(&c.updatesSent, 1)
+	atomic.StoreInt64(&c.lastUpdateAt, now.UnixNano())
+}
+
+// StatsWriter returns a writer that counts the bytes written to w as bytes
+// written by the connection.
+//
+// The returned writer has a Flush method that flushes w, if w can be flushed.
+// So it can be used in place of a http.ResponseWriter.
+func (c *Connection) StatsWriter(w io.Writer) io.Writer {
+	return statsWriter{Writer: w, c: c}
+}
+
+// statsWriter is an io.Writer that counts the written bytes of a connection.
+type statsWriter struct {
+	io.Writer
+	c *Connection
+}
+
+func (w statsWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.c.AddBytesWritten(n)
+	return n, err
+}
+
+// Flush sends buffered data, if the inner writer supports it.
+func (w statsWriter) Flush() {
+	if f, ok := w.Writer.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,39 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestConnectionStats(t *testing.T) {
+	c, datastore, close := getConnection()
+	defer close()
+
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(fmt.Sprintf(`"value %d"`, i))})
+			datastore.Send(test.Str("user/1/name"))
+		}
+
+		if _, err := c.Next(context.Background()); err != nil {
+			t.Fatalf("Next() returned an unexpected error: %v", err)
+		}
+	}
+
+	stats := c.Stats()
+	if stats.UpdatesSent != 3 {
+		t.Errorf("UpdatesSent is %d, expected 3", stats.UpdatesSent)
+	}
+
+	if stats.UserID != 1 {
+		t.Errorf("UserID is %d, expected 1", stats.UserID)
+	}
+
+	if stats.LastUpdateAt.Before(stats.StartedAt) {
+		t.Errorf("LastUpdateAt %s is before StartedAt %s", stats.LastUpdateAt, stats.StartedAt)
+	}
+}
@@ -0,0 +1,243 @@
+package autoupdate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+	"github.com/openslides/openslides-autoupdate-service/internal/keysbuilder"
+)
+
+// SubscribeReader connects to the service like Connect() but returns the data
+// as a stream. Each update is a json object in one line.
+//
+// The first data is fetched before the method returns. Errors on this first
+// fetch are returned directly.
+//
+// The stream returns io.EOF, when the context is done or the service is
+// closed. The returned reader has to be closed to stop the background job.
+//
+// Keys with an id range like user/[1-100]/name are expanded. See
+// WithMaxKeyRange().
+func (a *Autoupdate) SubscribeReader(ctx context.Context, uid int, keys []string) (io.ReadCloser, error) {
+	keys, err := a.expandKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	c := a.Connect(uid, staticKeys(keys), a.LastID())
+	return connectionReader(ctx, c)
+}
+
+// SubscribeWithTimeout is like SubscribeReader() but the stream is closed
+// after the duration d. This makes sure, that the background job stops even
+// when the reader is never closed.
+func (a *Autoupdate) SubscribeWithTimeout(ctx context.Context, d time.Duration, uid int, keys []string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(d))
+	r, err := a.SubscribeReader(ctx, uid, keys)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &cancelReader{ReadCloser: r, cancel: cancel}, nil
+}
+
+// SubscribeJSON is like SubscribeReader() but the keys are given as a json key
+// request body, like the body of a request to the autoupdate url.
+//
+// An invalid body returns an error that wraps the error from the keysbuilder
+// package. Invalid keys return a key.InvalidKeyError like in SubscribeOnce.
+func (a *Autoupdate) SubscribeJSON(ctx context.Context, uid int, body []byte) (io.ReadCloser, error) {
+	return subscribeJSON(ctx, a, uid, body)
+}
+
+// subscribeJSON parses the key request body and connects to the service.
+func subscribeJSON(ctx context.Context, s Service, uid int, body []byte) (io.ReadCloser, error) {
+	kb, err := keysbuilder.ManyFromJSON(ctx, bytes.NewReader(body), s, uid)
+	if err != nil {
+		return nil, fmt.Errorf("parse key request: %w", err)
+	}
+
+	if err := key.Validate(kb.Keys()...); err != nil {
+		return nil, err
+	}
+
+	return connectionReader(ctx, s.Connect(uid, kb, s.LastID()))
+}
+
+// SubscribeFunc is like SubscribeReader() but the keys are returned by fn. fn
+// is called at the beginning and on each update to get the current keys.
+//
+// Errors from fn and invalid keys on the first call are returned directly.
+// Later, they close the stream with the error.
+func (a *Autoupdate) SubscribeFunc(ctx context.Context, uid int, fn func() ([]string, error)) (io.ReadCloser, error) {
+	kb := &funcKeys{fn: fn}
+	if err := kb.Update(); err != nil {
+		return nil, err
+	}
+
+	return connectionReader(ctx, a.Connect(uid, kb, a.LastID()))
+}
+
+// ServeStream connects to the service and calls send with the first data and
+// each update. It can be used to stream the data with other protocols then
+// http, for example with a grpc stream.
+//
+// ServeStream blocks until the context is done or the service is closed. In
+// this case, nil is returned. Errors from send or while fetching the data are
+// returned directly.
+func (a *Autoupdate) ServeStream(ctx context.Context, uid int, keys []string, send func(map[string]json.RawMessage) error) error {
+	keys, err := a.expandKeys(keys)
+	if err != nil {
+		return err
+	}
+
+	c := a.Connect(uid, staticKeys(keys), a.LastID())
+	defer c.register()()
+
+	for {
+		data, err := c.Next(ctx)
+		if err != nil {
+			if isClosing(err) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil
+			}
+			return err
+		}
+
+		if err := send(data); err != nil {
+			return fmt.Errorf("send data: %w", err)
+		}
+	}
+}
+
+// connectionReader reads the first data from a connection and starts a
+// background job that writes the connection data into a pipe.
+func connectionReader(ctx context.Context, c *Connection) (io.ReadCloser, error) {
+	data, err := c.Next(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get first data: %w", err)
+	}
+
+	return streamConnection(ctx, c, data), nil
+}
+
+// streamConnection starts a background job that writes data and all further
+// data of the connection into a pipe. data has to be the first data of the
+// connection.
+func streamConnection(ctx context.Context, c *Connection, data map[string]json.RawMessage) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+	r, w := io.Pipe()
+
+	go func() {
+		defer c.register()()
+
+		encode := json.NewEncoder(c.StatsWriter(w)).Encode
+
+		// If the client does not read for the idle timeout, the pipe is
+		// closed, so a blocking write returns.
+		c.setOnIdle(func() {
+			w.CloseWithError(IdleTimeoutError{})
+		})
+
+		for {
+			if err := encode(data); err != nil {
+				// The reader was closed.
+				w.CloseWithError(err)
+				return
+			}
+
+			var err error
+			data, err = c.Next(ctx)
+			if err != nil {
+				var closer interface {
+					CloseFrame() map[string]string
+				}
+				if errors.As(err, &closer) {
+					encode(closer.CloseFrame())
+				}
+
+				if isClosing(err) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+					w.Close()
+					return
+				}
+				w.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	return &streamReader{PipeReader: r, cancel: cancel}
+}
+
+// streamReader is the io.ReadCloser returned by SubscribeReader.
+type streamReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+// Close stops the background job and closes the reader.
+func (r *streamReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
+
+// cancelReader is a io.ReadCloser that cancels a context when it is closed.
+type cancelReader struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close cancels the context and closes the inner reader.
+func (r *cancelReader) Close() error {
+	r.cancel()
+	return r.ReadCloser.Close()
+}
+
+// isClosing returns true, if the error was returned because the service was
+// closed.
+func isClosing(err error) bool {
+	var closing interface {
+		Closing()
+	}
+	return errors.As(err, &closing)
+}
+
+// staticKeys is a KeysBuilder for a fixed list of keys.
+type staticKeys []string
+
+func (s staticKeys) Update() error {
+	return nil
+}
+
+func (s staticKeys) Keys() []string {
+	return s
+}
+
+// funcKeys is a KeysBuilder that gets the keys from a function.
+type funcKeys struct {
+	fn   func() ([]string, error)
+	keys []string
+}
+
+func (f *funcKeys) Update() error {
+	keys, err := f.fn()
+	if err != nil {
+		return fmt.Errorf("get keys: %w", err)
+	}
+
+	if err := key.Validate(keys...); err != nil {
+		return err
+	}
+
+	f.keys = keys
+	return nil
+}
+
+func (f *funcKeys) Keys() []string {
+	return f.keys
+}
@@ -0,0 +1,426 @@
+package autoupdate_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestSubscribeReader(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	r, err := s.SubscribeReader(context.Background(), 1, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("SubscribeReader() returned an unexpected error: %v", err)
+	}
+	defer r.Close()
+	scanner := bufio.NewScanner(r)
+
+	for _, expect := range []string{`"Hello World"`, `"new value"`} {
+		if !scanner.Scan() {
+			t.Fatalf("Can not read from stream: %v", scanner.Err())
+		}
+
+		var data map[string]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			t.Fatalf("Stream returned invalid json `%s`: %v", scanner.Bytes(), err)
+		}
+
+		if got := string(data["user/1/name"]); got != expect {
+			t.Errorf("Got value %s, expected %s", got, expect)
+		}
+
+		datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(`"new value"`)})
+		datastore.Send(test.Str("user/1/name"))
+	}
+}
+
+func TestSubscribeReaderContextDone(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, err := s.SubscribeReader(ctx, 1, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("SubscribeReader() returned an unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	cancel()
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Errorf("Reading the stream returned an unexpected error: %v", err)
+	}
+
+	if expect := "{\"user/1/name\":\"Hello World\"}\n"; string(got) != expect {
+		t.Errorf("Got %q, expected %q", got, expect)
+	}
+}
+
+// TestSubscribeReaderSharedContext makes sure, that all subscriptions stop,
+// when a context, that they share, is canceled.
+func TestSubscribeReaderSharedContext(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	goroutines := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var readers []io.ReadCloser
+	for i := 0; i < 2; i++ {
+		r, err := s.SubscribeReader(ctx, 1, test.Str("user/1/name"))
+		if err != nil {
+			t.Fatalf("SubscribeReader() returned an unexpected error: %v", err)
+		}
+		defer r.Close()
+		readers = append(readers, r)
+	}
+
+	for i, r := range readers {
+		// Read the first frame, so the background job waits for updates.
+		if _, err := bufio.NewReader(r).ReadBytes('\n'); err != nil {
+			t.Fatalf("Can not read first frame of reader %d: %v", i, err)
+		}
+	}
+
+	// On a machine with only a few cpus, the scheduler needs more time.
+	limit := 10 * time.Millisecond
+	if runtime.NumCPU() < 4 {
+		limit = 100 * time.Millisecond
+	}
+
+	begin := time.Now()
+	cancel()
+	for i, r := range readers {
+		if _, err := ioutil.ReadAll(r); err != nil {
+			t.Errorf("Reader %d returned an unexpected error: %v", i, err)
+		}
+	}
+
+	if d := time.Since(begin); d > limit {
+		t.Errorf("Closing both streams took %v, expected less then %v", d, limit)
+	}
+
+	// The background jobs stop after the stream is closed. Give them some
+	// time to return.
+	for i := 0; i < 100 && runtime.NumGoroutine() > goroutines; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if got := runtime.NumGoroutine(); got > goroutines {
+		t.Errorf("Got %d goroutines after the context was canceled, expected %d", got, goroutines)
+	}
+}
+
+func TestSubscribeWithTimeout(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	start := time.Now()
+	r, err := s.SubscribeWithTimeout(context.Background(), 10*time.Millisecond, 1, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("SubscribeWithTimeout() returned an unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Errorf("Reading the stream returned an unexpected error: %v", err)
+	}
+
+	if expect := "{\"user/1/name\":\"Hello World\"}\n"; string(got) != expect {
+		t.Errorf("Got %q, expected %q", got, expect)
+	}
+
+	if d := time.Since(start); d < 10*time.Millisecond || d > time.Second {
+		t.Errorf("Stream was closed after %v, expected 10ms", d)
+	}
+}
+
+func TestServeStream(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan map[string]json.RawMessage)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ServeStream(ctx, 1, test.Str("user/1/name"), func(data map[string]json.RawMessage) error {
+			received <- data
+			return nil
+		})
+	}()
+
+	for i, expect := range []string{`"Hello World"`, `"new value"`} {
+		select {
+		case data := <-received:
+			if got := string(data["user/1/name"]); got != expect {
+				t.Errorf("Got value %s, expected %s", got, expect)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive data %d", i)
+		}
+
+		if i == 0 {
+			datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(`"new value"`)})
+			datastore.Send(test.Str("user/1/name"))
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeStream() returned an unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("ServeStream() did not return after the context was canceled")
+	}
+}
+
+func TestServeStreamSendError(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	myErr := errors.New("my error")
+	err := s.ServeStream(context.Background(), 1, test.Str("user/1/name"), func(map[string]json.RawMessage) error {
+		return myErr
+	})
+
+	if !errors.Is(err, myErr) {
+		t.Errorf("ServeStream() returned %v, expected %v", err, myErr)
+	}
+}
+
+func TestSubscribeReaderDeleted(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.Data = map[string]json.RawMessage{"user/1/name": []byte(`"name"`)}
+	datastore.OnlyData = true
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithNotifyOnEmpty(true))
+	defer s.Close()
+
+	r, err := s.SubscribeReader(context.Background(), 1, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("SubscribeReader() returned an unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	br := bufio.NewReader(r)
+	if line, err := br.ReadString('\n'); err != nil || line != "{\"user/1/name\":\"name\"}\n" {
+		t.Fatalf("Got first frame %q (err: %v), expected the value", line, err)
+	}
+
+	datastore.Update(map[string]json.RawMessage{"user/1/name": nil})
+	datastore.Send(test.Str("user/1/name"))
+
+	// Wait for the first empty cycle. Otherwise both updates could be
+	// received together.
+	if line, err := br.ReadString('\n'); err != nil || line != "{\"user/1/name\":null}\n" {
+		t.Fatalf("Got second frame %q (err: %v), expected null", line, err)
+	}
+	datastore.Send(test.Str("user/1/name"))
+
+	got, err := ioutil.ReadAll(br)
+	if err != nil {
+		t.Errorf("Reading the stream returned an unexpected error: %v", err)
+	}
+
+	expect := "{\"closed\":\"object_deleted\"}\n"
+	if string(got) != expect {
+		t.Errorf("Got %q, expected %q", got, expect)
+	}
+}
+
+func TestSubscribeReaderIdleTimeout(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	clock := &mockClock{now: time.Now()}
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithIdleTimeout(time.Minute), autoupdate.WithClock(clock))
+	defer s.Close()
+
+	r, err := s.SubscribeReader(context.Background(), 1, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("SubscribeReader returned an unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	// The client does not read the first frame for longer then the timeout.
+	// The timer closes the stream, even when the write of the first frame
+	// blocks.
+	clock.Advance(2 * time.Minute)
+
+	_, err = ioutil.ReadAll(r)
+	var idle autoupdate.IdleTimeoutError
+	if !errors.As(err, &idle) {
+		t.Errorf("Reading the stream returned error %v, expected an IdleTimeoutError", err)
+	}
+}
+
+func TestSubscribeReaderIdleTimeoutNotReading(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithIdleTimeout(10*time.Millisecond))
+	defer s.Close()
+
+	r, err := s.SubscribeReader(context.Background(), 1, test.Str("user/1/name"))
+	if err != nil {
+		t.Fatalf("SubscribeReader returned an unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	// The client never reads, so Next() is never called again. The pipe has
+	// to be closed by the timer.
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = ioutil.ReadAll(r)
+	var idle autoupdate.IdleTimeoutError
+	if !errors.As(err, &idle) {
+		t.Errorf("Reading the stream returned error %v, expected an IdleTimeoutError", err)
+	}
+}
+
+func TestSubscribeJSON(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	body := []byte(`[{"ids":[1],"collection":"user","fields":{"name":null}}]`)
+	r, err := s.SubscribeJSON(context.Background(), 1, body)
+	if err != nil {
+		t.Fatalf("SubscribeJSON() returned an unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Can not read from stream: %v", err)
+	}
+
+	if expect := "{\"user/1/name\":\"Hello World\"}\n"; line != expect {
+		t.Errorf("Got first frame %q, expected %q", line, expect)
+	}
+}
+
+func TestSubscribeJSONInvalid(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	for _, tt := range []struct {
+		name string
+		body string
+	}{
+		{"empty", ``},
+		{"no json", `not json`},
+		{"no list", `{"ids":[1],"collection":"user","fields":{"name":null}}`},
+		{"no ids", `[{"collection":"user","fields":{"name":null}}]`},
+		{"no fields", `[{"ids":[1],"collection":"user"}]`},
+		{"invalid key", `[{"ids":[1],"collection":"user","fields":{"first/name":null}}]`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := s.SubscribeJSON(context.Background(), 1, []byte(tt.body))
+			if err == nil {
+				r.Close()
+				t.Errorf("SubscribeJSON() did not return an error")
+			}
+		})
+	}
+}
+
+func TestSubscribeFunc(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	var calls int
+	fn := func() ([]string, error) {
+		calls++
+		if calls == 1 {
+			return test.Str("user/1/name"), nil
+		}
+		return test.Str("user/2/name"), nil
+	}
+
+	r, err := s.SubscribeFunc(context.Background(), 1, fn)
+	if err != nil {
+		t.Fatalf("SubscribeFunc() returned an unexpected error: %v", err)
+	}
+	defer r.Close()
+	stream := bufio.NewReader(r)
+
+	received := make(map[string]bool)
+	readFrame := func() {
+		line, err := stream.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("Can not read from stream: %v", err)
+		}
+
+		var data map[string]json.RawMessage
+		if err := json.Unmarshal(line, &data); err != nil {
+			t.Fatalf("Got invalid json: %v", err)
+		}
+		for k := range data {
+			received[k] = true
+		}
+	}
+
+	readFrame()
+	datastore.Send(test.Str("user/1/name"))
+	readFrame()
+
+	if !received["user/1/name"] || !received["user/2/name"] {
+		t.Errorf("Got keys %v, expected user/1/name and user/2/name", received)
+	}
+}
+
+func TestSubscribeFuncError(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	for _, tt := range []struct {
+		name string
+		fn   func() ([]string, error)
+	}{
+		{"error", func() ([]string, error) { return nil, errors.New("no keys") }},
+		{"invalid key", func() ([]string, error) { return test.Str("user/name"), nil }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := s.SubscribeFunc(context.Background(), 1, tt.fn)
+			if err == nil {
+				r.Close()
+				t.Errorf("SubscribeFunc() did not return an error")
+			}
+		})
+	}
+}
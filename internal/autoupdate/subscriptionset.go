@@ -0,0 +1,111 @@
+package autoupdate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Subscription is an active connection of a client.
+type Subscription struct {
+	UserID     int
+	Connection *Connection
+
+	version uint64
+}
+
+// SetVersion saves the version of the last data, that was sent to the client.
+// See Connection.Version().
+func (s *Subscription) SetVersion(version uint64) {
+	atomic.StoreUint64(&s.version, version)
+}
+
+// Version returns the version, that was saved with SetVersion.
+func (s *Subscription) Version() uint64 {
+	return atomic.LoadUint64(&s.version)
+}
+
+// SubscriptionSet holds active subscriptions. Each subscription is identified
+// by a random token.
+//
+// The zero value is an empty set. It is save for concurrent use.
+type SubscriptionSet struct {
+	mu   sync.RWMutex
+	subs map[string]*Subscription
+}
+
+// Add adds the subscription to the set and returns its token.
+func (s *SubscriptionSet) Add(sub *Subscription) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subs == nil {
+		s.subs = make(map[string]*Subscription)
+	}
+
+	for {
+		token := newToken()
+		if _, ok := s.subs[token]; ok {
+			continue
+		}
+		s.subs[token] = sub
+		return token
+	}
+}
+
+// Remove removes the subscription with the token. Nothing happens, if the
+// token is unknown.
+func (s *SubscriptionSet) Remove(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subs, token)
+}
+
+// Get returns the subscription with the token.
+func (s *SubscriptionSet) Get(token string) (*Subscription, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sub, ok := s.subs[token]
+	return sub, ok
+}
+
+// Count returns the number of subscriptions in the set.
+func (s *SubscriptionSet) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.subs)
+}
+
+// Range calls fn for each subscription in the set until fn returns false.
+//
+// fn is called with the subscriptions, that were in the set when Range was
+// called. It can add or remove subscriptions.
+func (s *SubscriptionSet) Range(fn func(*Subscription) bool) {
+	s.mu.RLock()
+	subs := make([]*Subscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	s.mu.RUnlock()
+
+	for _, sub := range subs {
+		if !fn(sub) {
+			return
+		}
+	}
+}
+
+// newToken returns a random token.
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand only fails, if the os does not provide randomness.
+		panic(fmt.Sprintf("can not read random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,68 @@
+package autoupdate_test
+
+import (
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+)
+
+func TestSubscriptionSet(t *testing.T) {
+	var set autoupdate.SubscriptionSet
+	sub1 := &autoupdate.Subscription{UserID: 1}
+	sub2 := &autoupdate.Subscription{UserID: 2}
+
+	token1 := set.Add(sub1)
+	token2 := set.Add(sub2)
+
+	if token1 == token2 {
+		t.Errorf("Add() returned the same token twice: %s", token1)
+	}
+
+	if got := set.Count(); got != 2 {
+		t.Errorf("Count() returned %d, expected 2", got)
+	}
+
+	got, ok := set.Get(token1)
+	if !ok || got != sub1 {
+		t.Errorf("Get(token1) returned %v, %t, expected sub1", got, ok)
+	}
+
+	set.Remove(token1)
+	if _, ok := set.Get(token1); ok {
+		t.Errorf("Get() found a removed subscription")
+	}
+	if got := set.Count(); got != 1 {
+		t.Errorf("Count() after Remove() returned %d, expected 1", got)
+	}
+
+	// Removing an unknown token does nothing.
+	set.Remove("unknown")
+	if got, ok := set.Get(token2); !ok || got != sub2 {
+		t.Errorf("Get(token2) returned %v, %t, expected sub2", got, ok)
+	}
+}
+
+func TestSubscriptionSetRange(t *testing.T) {
+	var set autoupdate.SubscriptionSet
+	for i := 0; i < 5; i++ {
+		set.Add(&autoupdate.Subscription{UserID: i})
+	}
+
+	seen := make(map[int]bool)
+	set.Range(func(sub *autoupdate.Subscription) bool {
+		seen[sub.UserID] = true
+		return true
+	})
+	if len(seen) != 5 {
+		t.Errorf("Range() called fn with %d subscriptions, expected 5", len(seen))
+	}
+
+	calls := 0
+	set.Range(func(sub *autoupdate.Subscription) bool {
+		calls++
+		return calls < 2
+	})
+	if calls != 2 {
+		t.Errorf("Range() called fn %d times, expected 2", calls)
+	}
+}
@@ -0,0 +1,48 @@
+package autoupdate_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestUpdateKeys(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	updater := test.NewUpdaterMock()
+	defer updater.Close()
+	s := autoupdate.New(datastore.New(ts.TS.URL, updater), new(test.MockRestricter))
+	defer s.Close()
+
+	kb := mockKeysBuilder{keys: test.Str("user/1/name")}
+	connections := []*autoupdate.Connection{s.Connect(1, kb, 0), s.Connect(2, kb, 0)}
+	for _, c := range connections {
+		if _, err := c.Next(context.Background()); err != nil {
+			t.Fatalf("First Next() returned an unexpected error: %v", err)
+		}
+	}
+
+	s.UpdateKeys(map[string]json.RawMessage{"user/1/name": []byte(`"announcement"`)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i, c := range connections {
+		data, err := c.Next(ctx)
+		if err != nil {
+			t.Fatalf("Next() of connection %d returned an unexpected error: %v", i, err)
+		}
+
+		if got := string(data["user/1/name"]); got != `"announcement"` {
+			t.Errorf("Connection %d got `%s`, expected `\"announcement\"`", i, got)
+		}
+	}
+
+	if ts.RequestCount != 1 {
+		t.Errorf("Got %d requests to the datastore, expected 1", ts.RequestCount)
+	}
+}
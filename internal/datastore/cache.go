@@ -4,7 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"sync"
+	"sync/atomic"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
 )
 
 const (
@@ -28,17 +32,47 @@ type cacheSetFunc func(keys []string) (map[string]json.RawMessage, error)
 //
 // A new cache instance has to be created with newCache().
 type cache struct {
+	// hits and misses are used with atomic and have to be at the beginning of
+	// the struct to be 64 bit aligned on 32 bit systems.
+	hits   uint64
+	misses uint64
+
 	mu      sync.RWMutex
 	data    map[string]json.RawMessage
 	pending map[string]chan struct{}
+	etags   map[string]string
+
+	// index holds the keys, that were updated with SetIfExist, in sorted
+	// order.
+	index key.KeyIndex
+
+	// maxValueBytes is the size of the biggest value that is stored. Zero
+	// means no limit.
+	maxValueBytes int
+}
+
+// cacheOption is an optional argument for newCache().
+type cacheOption func(*cache)
+
+// withMaxValueBytes sets the size of the biggest value that is saved in the
+// cache. Bigger values are not stored and fetched again on each request.
+func withMaxValueBytes(n int) cacheOption {
+	return func(c *cache) {
+		c.maxValueBytes = n
+	}
 }
 
 // newCache creates an initialized cache instance.
-func newCache() *cache {
-	return &cache{
+func newCache(options ...cacheOption) *cache {
+	c := &cache{
 		data:    make(map[string]json.RawMessage),
 		pending: make(map[string]chan struct{}),
+		etags:   make(map[string]string),
 	}
+	for _, o := range options {
+		o(c)
+	}
+	return c
 }
 
 // GetOrSet returns the values for a list of keys. If one or more keys do not
@@ -58,32 +92,59 @@ func newCache() *cache {
 //
 // If the context is done, GetOrSet returns. But the set() call is not stopped.
 // Other calls to GetOrSet may wait for its result.
+//
+// Values bigger then the max value size are returned but not stored in the
+// cache. They are fetched again on the next call.
 func (c *cache) GetOrSet(ctx context.Context, keys []string, set cacheSetFunc) ([]json.RawMessage, error) {
-	c.mu.Lock()
-	missingKeys := c.notExistToPending(keys)
-	c.mu.Unlock()
+	missingKeys := c.startFetching(keys)
 
 	// Fetch missing keys.
+	var oversized map[string]json.RawMessage
 	if len(missingKeys) > 0 {
 		// Fetch missing keys in the background. Do not stop the fetching. Even
 		// when the context is done. Other calls could also request it.
-		errChan := make(chan error)
+		type result struct {
+			oversized map[string]json.RawMessage
+			err       error
+		}
+		resultChan := make(chan result, 1)
 		go func() {
-			err := c.fetchMissing(missingKeys, set)
-			errChan <- err
+			oversized, err := c.fetchMissing(missingKeys, set)
+			resultChan <- result{oversized, err}
 		}()
 
 		select {
-		case err := <-errChan:
-			if err != nil {
-				return nil, fmt.Errorf("fetching key: %w", err)
+		case r := <-resultChan:
+			if r.err != nil {
+				return nil, fmt.Errorf("fetching key: %w", r.err)
 			}
+			oversized = r.oversized
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		}
 	}
 
-	// Build return values. Blocks until pending keys are fetched.
+	return c.values(ctx, keys, oversized, set)
+}
+
+// startFetching sets all keys, that do not exist in the cache, to pending and
+// returns them. The caller has to fetch them and call storeMissing().
+func (c *cache) startFetching(keys []string) []string {
+	c.mu.Lock()
+	missingKeys := c.notExistToPending(keys)
+	c.mu.Unlock()
+
+	atomic.AddUint64(&c.misses, uint64(len(missingKeys)))
+	atomic.AddUint64(&c.hits, uint64(len(keys)-len(missingKeys)))
+	return missingKeys
+}
+
+// values returns the values for the keys. Blocks until pending keys are
+// fetched.
+//
+// oversized are values, that were fetched for this call but are to big for
+// the cache.
+func (c *cache) values(ctx context.Context, keys []string, oversized map[string]json.RawMessage, set cacheSetFunc) ([]json.RawMessage, error) {
 	values := make([]json.RawMessage, len(keys))
 	c.mu.RLock()
 	for i, key := range keys {
@@ -92,8 +153,14 @@ func (c *cache) GetOrSet(ctx context.Context, keys []string, set cacheSetFunc) (
 			values[i] = c.data[key]
 			continue
 		case stInvalid:
+			c.mu.RUnlock()
 			return nil, fmt.Errorf("key `%s` is in invalid state", key)
 		case stNotExist:
+			if value, ok := oversized[key]; ok {
+				values[i] = value
+				continue
+			}
+			c.mu.RUnlock()
 			return nil, fmt.Errorf("key `%s` does not exist in cache", key)
 		}
 		p := c.pending[key]
@@ -109,13 +176,16 @@ func (c *cache) GetOrSet(ctx context.Context, keys []string, set cacheSetFunc) (
 		if c.keyState(key) != stExist {
 			// The value is not in the cache after pending was done. This
 			// happens when the request to the datastore of another
-			// GetOrSet-Call returned with an error. Try it once more.
+			// GetOrSet-Call returned with an error or the value was to big
+			// for the cache. Try it once more.
 			c.mu.RUnlock()
-			_, err := c.GetOrSet(ctx, []string{key}, set)
+			value, err := c.GetOrSet(ctx, []string{key}, set)
 			if err != nil {
 				return nil, fmt.Errorf("fetching keys for a second time: %w", err)
 			}
 			c.mu.RLock()
+			values[i] = value[0]
+			continue
 		}
 
 		values[i] = c.data[key]
@@ -124,13 +194,64 @@ func (c *cache) GetOrSet(ctx context.Context, keys []string, set cacheSetFunc) (
 	return values, nil
 }
 
+// GetOrSetMany is like GetOrSet but for many groups of keys. The keys of all
+// groups are fetched together, so the set function is called at most once.
+//
+// The returned values have the same order as the given groups.
+func (c *cache) GetOrSetMany(ctx context.Context, keyGroups [][]string, set cacheSetFunc) ([][]json.RawMessage, error) {
+	return getOrSetMany(ctx, c.GetOrSet, keyGroups, set)
+}
+
+// getOrSetMany implements GetOrSetMany with the given GetOrSet function.
+func getOrSetMany(ctx context.Context, getOrSet func(context.Context, []string, cacheSetFunc) ([]json.RawMessage, error), keyGroups [][]string, set cacheSetFunc) ([][]json.RawMessage, error) {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, group := range keyGroups {
+		for _, key := range group {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	values, err := getOrSet(ctx, keys, set)
+	if err != nil {
+		return nil, err
+	}
+
+	keyValue := make(map[string]json.RawMessage, len(keys))
+	for i, key := range keys {
+		keyValue[key] = values[i]
+	}
+
+	groupValues := make([][]json.RawMessage, len(keyGroups))
+	for i, group := range keyGroups {
+		groupValues[i] = make([]json.RawMessage, len(group))
+		for j, key := range group {
+			groupValues[i][j] = keyValue[key]
+		}
+	}
+	return groupValues, nil
+}
+
 // fetchMissing loads the given keys with the set method. Does not update keys
 // that are already in the cache.
 //
 // Deletes the keys from the pending map, even when an error happens.
-func (c *cache) fetchMissing(keys []string, set cacheSetFunc) error {
+//
+// Returns the values that are to big to be stored.
+func (c *cache) fetchMissing(keys []string, set cacheSetFunc) (map[string]json.RawMessage, error) {
 	data, err := set(keys)
+	return c.storeMissing(keys, data, err)
+}
 
+// storeMissing saves the result of the set function for the given keys. The
+// keys have to be set to pending with startFetching().
+//
+// The data can contain keys, that are not in keys. They are ignored.
+func (c *cache) storeMissing(keys []string, data map[string]json.RawMessage, err error) (map[string]json.RawMessage, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -147,35 +268,164 @@ func (c *cache) fetchMissing(keys []string, set cacheSetFunc) error {
 	}()
 
 	if err != nil {
-		return fmt.Errorf("fetching missing keys: %w", err)
+		return nil, fmt.Errorf("fetching missing keys: %w", err)
 	}
 
+	var oversized map[string]json.RawMessage
 	for k, v := range data {
-		if c.keyState(k) == stPending {
-			c.set(k, v)
+		if c.keyState(k) != stPending {
+			continue
+		}
+
+		if c.tooBig(k, v) {
+			if oversized == nil {
+				oversized = make(map[string]json.RawMessage)
+			}
+			oversized[k] = v
+			continue
 		}
+		c.set(k, v)
 	}
 
 	// Set all keys, that where not returned to not existing.
 	for _, k := range keys {
+		if _, ok := oversized[k]; ok {
+			continue
+		}
+
 		if c.keyState(k) == stPending {
 			c.set(k, nil)
 		}
 	}
-	return nil
+	return oversized, nil
 }
 
 // SetIfExist updates each the cache with the value in the given map. But keys
 // that exists or are pending get an update.
+//
+// Values bigger then the max value size are discarded. Existing keys with such
+// a value are removed from the cache.
 func (c *cache) SetIfExist(data map[string]json.RawMessage) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	for key, value := range data {
-		if c.keyState(key) == stNotExist {
+		state := c.keyState(key)
+		if state == stNotExist {
+			continue
+		}
+
+		if c.tooBig(key, value) {
+			if state == stExist {
+				c.delete(key)
+				c.index.Remove(key)
+			}
 			continue
 		}
 		c.set(key, value)
+		c.index.Add(key)
+	}
+}
+
+// tooBig returns true, if the value is bigger then the max value size. Logs a
+// warning in this case.
+func (c *cache) tooBig(key string, value json.RawMessage) bool {
+	if c.maxValueBytes <= 0 || len(value) <= c.maxValueBytes {
+		return false
+	}
+	log.Printf("Warning: Value of key %s has %d bytes and is not stored in the cache", key, len(value))
+	return true
+}
+
+// DeleteKeys removes the given keys from the cache. The next call to GetOrSet
+// fetches them again. Pending keys are not changed.
+func (c *cache) DeleteKeys(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if c.keyState(key) == stExist {
+			c.delete(key)
+		}
+	}
+}
+
+// Keys returns all keys that exist in the cache.
+func (c *cache) Keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make([]string, 0, len(c.data))
+	for key := range c.data {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// ForEach calls fn for each key that exists in the cache. The cache is locked
+// while fn is called, so fn must not use the cache.
+func (c *cache) ForEach(fn func(key string, value json.RawMessage)) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for key, value := range c.data {
+		fn(key, value)
+	}
+}
+
+// ETags returns the etags for the given keys. Keys without an etag are not in
+// the returned map.
+func (c *cache) ETags(keys []string) map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	etags := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if etag, ok := c.etags[key]; ok {
+			etags[key] = etag
+		}
+	}
+	return etags
+}
+
+// SetETags saves the etags for keys that exist in the cache.
+func (c *cache) SetETags(etags map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, etag := range etags {
+		if c.keyState(key) != stExist {
+			continue
+		}
+		c.etags[key] = etag
+	}
+}
+
+// CacheStats holds counters of the cache.
+type CacheStats struct {
+	// Hits is the number of requested keys, that did not have to be fetched.
+	Hits uint64
+
+	// Misses is the number of requested keys, that had to be fetched.
+	Misses uint64
+
+	// Pending is the number of keys, that are currently fetched.
+	Pending int
+
+	// Entries is the number of keys in the cache.
+	Entries int
+}
+
+// Stats returns the current counters of the cache.
+func (c *cache) Stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return CacheStats{
+		Hits:    atomic.LoadUint64(&c.hits),
+		Misses:  atomic.LoadUint64(&c.misses),
+		Pending: len(c.pending),
+		Entries: len(c.data),
 	}
 }
 
@@ -205,14 +455,42 @@ func (c *cache) keyState(key string) int {
 }
 
 // set sets a key in the cache to a value. Closes the pending state.
+//
+// The etag of the key is removed, since it belongs to the old value.
 func (c *cache) set(key string, value json.RawMessage) {
 	c.data[key] = value
+	delete(c.etags, key)
 	if p, ok := c.pending[key]; ok {
 		close(p)
 		delete(c.pending, key)
 	}
 }
 
+// delete removes an existing key from the cache.
+//
+// The cache has to be in write lock to call this method.
+func (c *cache) delete(key string) {
+	delete(c.data, key)
+	delete(c.etags, key)
+}
+
+// KeysWithPrefix returns all keys that start with the prefix, were updated with
+// SetIfExist and still exist in the cache.
+func (c *cache) KeysWithPrefix(prefix string) []string {
+	keys := c.index.Range(prefix)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	existing := keys[:0]
+	for _, key := range keys {
+		if _, ok := c.data[key]; ok {
+			existing = append(existing, key)
+		}
+	}
+	return existing
+}
+
 // notExistToPending sets all given keys, that do not exist in the cache, to pending.
 // Returns the list of keys that where set to pending.
 //
@@ -4,6 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
 	"testing"
 	"time"
 
@@ -215,6 +220,43 @@ func TestCacheGetOrSetOldData(t *testing.T) {
 	}
 }
 
+func TestCacheGetOrSetReturnOldDataAfterRace(t *testing.T) {
+	// GetOrSet is called with key1 and returns version1 after a long time. In
+	// the meantime, there are two updates via setIfExist on version2 and
+	// version3. At the end, the cache has to hold version3.
+	c := newCache()
+
+	waitForGetOrSetStart := make(chan struct{})
+	waitForGetOrSetEnd := make(chan struct{})
+	waitForSetIfExist := make(chan struct{})
+
+	go func() {
+		c.GetOrSet(context.Background(), []string{"key1"}, func(keys []string) (map[string]json.RawMessage, error) {
+			close(waitForGetOrSetStart)
+			<-waitForSetIfExist
+			return map[string]json.RawMessage{"key1": []byte("v1")}, nil
+		})
+		close(waitForGetOrSetEnd)
+	}()
+
+	<-waitForGetOrSetStart
+	c.SetIfExist(map[string]json.RawMessage{"key1": []byte("v2")})
+	c.SetIfExist(map[string]json.RawMessage{"key1": []byte("v3")})
+	close(waitForSetIfExist)
+
+	<-waitForGetOrSetEnd
+	data, err := c.GetOrSet(context.Background(), []string{"key1"}, func(keys []string) (map[string]json.RawMessage, error) {
+		return map[string]json.RawMessage{"key1": []byte("key not in cache")}, nil
+	})
+	if err != nil {
+		t.Errorf("GetOrSet returned unexpected error: %v", err)
+	}
+
+	if string(data[0]) != "v3" {
+		t.Errorf("value for key1 is %s, expected `v3`", data[0])
+	}
+}
+
 func TestCacheErrorOnFetching(t *testing.T) {
 	// Make sure, that if a GetOrSet call fails the requested keys are not left
 	// in pending state.
@@ -281,3 +323,209 @@ func TestCacheFailInOthetGetOrSetCall(t *testing.T) {
 		t.Errorf("second GetOrSet returned `%v`, expected `value`", data[0])
 	}
 }
+
+func TestCacheGetOrSetMany(t *testing.T) {
+	c := newCache()
+	var calls int
+	got, err := c.GetOrSetMany(context.Background(), [][]string{{"key1", "key2"}, {"key2", "key3"}}, func(keys []string) (map[string]json.RawMessage, error) {
+		calls++
+		data := make(map[string]json.RawMessage, len(keys))
+		for _, key := range keys {
+			data[key] = json.RawMessage(key)
+		}
+		return data, nil
+	})
+
+	if err != nil {
+		t.Errorf("GetOrSetMany() returned the unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("set function was called %d times, expected 1", calls)
+	}
+
+	expect := [][]json.RawMessage{
+		{[]byte("key1"), []byte("key2")},
+		{[]byte("key2"), []byte("key3")},
+	}
+	if len(got) != len(expect) || !test.CmpSliceBytes(got[0], expect[0]) || !test.CmpSliceBytes(got[1], expect[1]) {
+		t.Errorf("GetOrSetMany() returned `%s`, expected `%s`", got, expect)
+	}
+}
+
+func TestCacheGetOrSetManyOnlyMissing(t *testing.T) {
+	c := newCache()
+	if _, err := c.GetOrSet(context.Background(), []string{"key1"}, benchmarkSetFunc); err != nil {
+		t.Fatalf("GetOrSet() returned the unexpected error: %v", err)
+	}
+
+	var requested []string
+	_, err := c.GetOrSetMany(context.Background(), [][]string{{"key1", "key2"}, {"key3"}}, func(keys []string) (map[string]json.RawMessage, error) {
+		requested = append(requested, keys...)
+		return benchmarkSetFunc(keys)
+	})
+	if err != nil {
+		t.Fatalf("GetOrSetMany() returned the unexpected error: %v", err)
+	}
+
+	sort.Strings(requested)
+	if !test.CmpSlice(requested, []string{"key2", "key3"}) {
+		t.Errorf("set function was called with %v, expected [key2 key3]", requested)
+	}
+}
+
+// overlappingGroups returns 10 groups of keys where each group shares half of
+// its keys with the next group.
+func overlappingGroups() [][]string {
+	groups := make([][]string, 10)
+	for i := range groups {
+		for j := 0; j < 10; j++ {
+			groups[i] = append(groups[i], fmt.Sprintf("user/%d/name", i*5+j))
+		}
+	}
+	return groups
+}
+
+func benchmarkSetFunc(keys []string) (map[string]json.RawMessage, error) {
+	data := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		data[key] = json.RawMessage(`"value"`)
+	}
+	return data, nil
+}
+
+func BenchmarkCacheGetOrSetSequential(b *testing.B) {
+	groups := overlappingGroups()
+	for n := 0; n < b.N; n++ {
+		c := newCache()
+		for _, group := range groups {
+			c.GetOrSet(context.Background(), group, benchmarkSetFunc)
+		}
+	}
+}
+
+func BenchmarkCacheGetOrSetMany(b *testing.B) {
+	groups := overlappingGroups()
+	for n := 0; n < b.N; n++ {
+		c := newCache()
+		c.GetOrSetMany(context.Background(), groups, benchmarkSetFunc)
+	}
+}
+
+// disjointGroups returns 50 groups with one key each.
+func disjointGroups() [][]string {
+	groups := make([][]string, 50)
+	for i := range groups {
+		groups[i] = []string{fmt.Sprintf("user/%d/name", i)}
+	}
+	return groups
+}
+
+func BenchmarkCacheGetOrSetSequentialDisjoint(b *testing.B) {
+	groups := disjointGroups()
+	for n := 0; n < b.N; n++ {
+		c := newCache()
+		for _, group := range groups {
+			c.GetOrSet(context.Background(), group, benchmarkSetFunc)
+		}
+	}
+}
+
+func BenchmarkCacheGetOrSetManyDisjoint(b *testing.B) {
+	groups := disjointGroups()
+	for n := 0; n < b.N; n++ {
+		c := newCache()
+		c.GetOrSetMany(context.Background(), groups, benchmarkSetFunc)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	c := newCache()
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetOrSet(context.Background(), []string{"key1"}, benchmarkSetFunc); err != nil {
+			t.Fatalf("GetOrSet() returned the unexpected error: %v", err)
+		}
+	}
+
+	got := c.Stats()
+	expect := CacheStats{Hits: 2, Misses: 1, Pending: 0, Entries: 1}
+	if got != expect {
+		t.Errorf("Stats() returned %+v, expected %+v", got, expect)
+	}
+}
+
+func TestCacheMaxValueBytes(t *testing.T) {
+	c := newCache(withMaxValueBytes(5))
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var calls int
+	set := func(keys []string) (map[string]json.RawMessage, error) {
+		calls++
+		return map[string]json.RawMessage{
+			"fits":     json.RawMessage("12345"),
+			"oversize": json.RawMessage("123456"),
+		}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		got, err := c.GetOrSet(context.Background(), []string{"fits", "oversize"}, set)
+		if err != nil {
+			t.Fatalf("GetOrSet() returned the unexpected error: %v", err)
+		}
+
+		if string(got[0]) != "12345" || string(got[1]) != "123456" {
+			t.Errorf("GetOrSet() returned %s, expected [12345 123456]", got)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("Set function was called %d times, expected 2", calls)
+	}
+
+	if keys := c.Keys(); len(keys) != 1 || keys[0] != "fits" {
+		t.Errorf("Cache has keys %v, expected [fits]", keys)
+	}
+}
+
+func TestCacheMaxValueBytesSetIfExist(t *testing.T) {
+	c := newCache(withMaxValueBytes(5))
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	c.GetOrSet(context.Background(), []string{"fits", "oversize"}, func([]string) (map[string]json.RawMessage, error) {
+		return map[string]json.RawMessage{"fits": json.RawMessage("1"), "oversize": json.RawMessage("1")}, nil
+	})
+
+	c.SetIfExist(map[string]json.RawMessage{
+		"fits":     json.RawMessage("12345"),
+		"oversize": json.RawMessage("123456"),
+	})
+
+	got, err := c.GetOrSet(context.Background(), []string{"fits", "oversize"}, func(keys []string) (map[string]json.RawMessage, error) {
+		if len(keys) != 1 || keys[0] != "oversize" {
+			t.Errorf("Set function was called with %v, expected [oversize]", keys)
+		}
+		return map[string]json.RawMessage{"oversize": json.RawMessage("fetched")}, nil
+	})
+	if err != nil {
+		t.Fatalf("GetOrSet() returned the unexpected error: %v", err)
+	}
+
+	if string(got[0]) != "12345" || string(got[1]) != "fetched" {
+		t.Errorf("GetOrSet() returned %s, expected [12345 fetched]", got)
+	}
+}
+
+func TestCacheKeysWithPrefix(t *testing.T) {
+	c := newCache()
+	c.GetOrSet(context.Background(), []string{"user/1/name", "user/2/name", "motion/1/title"}, func([]string) (map[string]json.RawMessage, error) {
+		return nil, nil
+	})
+	c.SetIfExist(map[string]json.RawMessage{"user/1/name": json.RawMessage(`"new"`), "user/3/name": json.RawMessage(`"new"`)})
+	c.DeleteKeys("user/2/name")
+
+	got := c.KeysWithPrefix("user/")
+	if len(got) != 1 || got[0] != "user/1/name" {
+		t.Errorf("KeysWithPrefix() returned %v, expected [user/1/name]", got)
+	}
+}
@@ -0,0 +1,32 @@
+package datastore
+
+// CacheBuster invalidates keys of a cache, when they are sent to a channel.
+// This can be used by other processes in the same binary to force a re-fetch
+// without a request to the service.
+//
+// Has to be created with datastore.NewCacheBuster().
+type CacheBuster struct {
+	done chan struct{}
+}
+
+// NewCacheBuster starts a background job that reads from the busts channel
+// and invalidates each batch of keys in the cache. The job stops, when the
+// channel is closed.
+func NewCacheBuster(cache Cache, busts <-chan []string) *CacheBuster {
+	b := &CacheBuster{done: make(chan struct{})}
+
+	go func() {
+		defer close(b.done)
+		for keys := range busts {
+			cache.Invalidate(keys...)
+		}
+	}()
+
+	return b
+}
+
+// Done returns a channel that is closed, when the busts channel was closed and
+// all batches are processed.
+func (b *CacheBuster) Done() <-chan struct{} {
+	return b.done
+}
@@ -0,0 +1,38 @@
+package datastore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestCacheBuster(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	d := datastore.New(ts.TS.URL, new(test.UpdaterMock))
+	busts := make(chan []string)
+	buster := datastore.NewCacheBuster(d, busts)
+
+	for i := 0; i < 2; i++ {
+		if _, err := d.Get(context.Background(), "user/1/name", "user/2/name"); err != nil {
+			t.Fatalf("Get() returned an unexpected error: %v", err)
+		}
+	}
+
+	if ts.RequestCount != 1 {
+		t.Fatalf("Got %d requests to the datastore before the bust, expected 1", ts.RequestCount)
+	}
+
+	busts <- []string{"user/1/name"}
+	close(busts)
+	<-buster.Done()
+
+	if _, err := d.Get(context.Background(), "user/1/name", "user/2/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	if ts.RequestCount != 2 {
+		t.Errorf("Got %d requests to the datastore after the bust, expected 2", ts.RequestCount)
+	}
+}
@@ -0,0 +1,60 @@
+package datastore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CompareAndSwap sets the value of the key to value, but only if the current
+// value in the cache is expected. The values are compared byte by byte. It
+// returns true, if the value was swapped, and false, if the value has changed.
+//
+// The key has to be in the cache, for example by an earlier call to Get().
+// The new value is saved in the cache and returned by the next call to
+// KeysChanged(), so all subscribers of the key get the new value.
+func (d *Datastore) CompareAndSwap(key string, expected, value json.RawMessage) (bool, error) {
+	if len(value) > 0 && !json.Valid(value) {
+		return false, fmt.Errorf("new value for key %s is not valid json", key)
+	}
+
+	swapped, err := d.cache.CompareAndSwap(key, expected, value)
+	if err != nil || !swapped {
+		return false, err
+	}
+
+	d.publishLocal(map[string]json.RawMessage{key: value})
+	return true, nil
+}
+
+// CompareAndSwap sets the value of an existing key to value, if its current
+// value is expected. Returns an error, if the key does not exist, is currently
+// fetched or if value is bigger then the max value size. In this cases, the
+// cache is not changed.
+func (c *cache) CompareAndSwap(key string, expected, value json.RawMessage) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.keyState(key) {
+	case stExist:
+	case stNotExist:
+		return false, fmt.Errorf("key %s is not in the cache", key)
+	default:
+		return false, fmt.Errorf("key %s is currently fetched", key)
+	}
+
+	if !bytes.Equal(c.data[key], expected) {
+		return false, nil
+	}
+
+	if c.tooBig(key, value) {
+		return false, fmt.Errorf("new value for key %s is too big for the cache", key)
+	}
+	c.set(key, value)
+	return true, nil
+}
+
+// CompareAndSwap is like cache.CompareAndSwap.
+func (c *shardedCache) CompareAndSwap(key string, expected, value json.RawMessage) (bool, error) {
+	return c.shards[c.shardIndex(key)].CompareAndSwap(key, expected, value)
+}
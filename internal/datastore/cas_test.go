@@ -0,0 +1,182 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	ts.Data = map[string]json.RawMessage{"motion/1/title": []byte(`"old"`)}
+	ts.OnlyData = true
+	d := New(ts.TS.URL, new(test.UpdaterMock))
+
+	if _, err := d.Get(context.Background(), "motion/1/title"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		expected string
+		value    string
+		swapped  bool
+		result   string
+	}{
+		{"successful", `"old"`, `"new"`, true, `"new"`},
+		{"value changed", `"old"`, `"other"`, false, `"new"`},
+		{"same value", `"new"`, `"new"`, true, `"new"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			swapped, err := d.CompareAndSwap("motion/1/title", json.RawMessage(tt.expected), json.RawMessage(tt.value))
+			if err != nil {
+				t.Fatalf("CompareAndSwap() returned an unexpected error: %v", err)
+			}
+
+			if swapped != tt.swapped {
+				t.Errorf("CompareAndSwap() returned %t, expected %t", swapped, tt.swapped)
+			}
+
+			got, err := d.Get(context.Background(), "motion/1/title")
+			if err != nil {
+				t.Fatalf("Get() returned an unexpected error: %v", err)
+			}
+
+			if string(got[0]) != tt.result {
+				t.Errorf("Got %s, expected %s", got[0], tt.result)
+			}
+		})
+	}
+
+	if ts.RequestCount != 1 {
+		t.Errorf("Got %d requests to the datastore, expected 1", ts.RequestCount)
+	}
+}
+
+func TestCompareAndSwapInvalid(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	d := New(ts.TS.URL, new(test.UpdaterMock))
+
+	if _, err := d.CompareAndSwap("motion/1/title", nil, json.RawMessage(`"new"`)); err == nil {
+		t.Errorf("CompareAndSwap() returned no error for a key, that is not in the cache")
+	}
+
+	if _, err := d.CompareAndSwap("motion/1/title", nil, json.RawMessage(`"new`)); err == nil {
+		t.Errorf("CompareAndSwap() returned no error for an invalid value")
+	}
+}
+
+func TestCompareAndSwapPublishes(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	ts.Data = map[string]json.RawMessage{"motion/1/title": []byte(`"old"`)}
+	ts.OnlyData = true
+	updater := test.NewUpdaterMock()
+	defer updater.Close()
+	d := New(ts.TS.URL, updater)
+	defer d.Close()
+
+	if _, err := d.Get(context.Background(), "motion/1/title"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	if _, err := d.CompareAndSwap("motion/1/title", json.RawMessage(`"old"`), json.RawMessage(`"new"`)); err != nil {
+		t.Fatalf("CompareAndSwap() returned an unexpected error: %v", err)
+	}
+
+	keys, err := d.KeysChanged()
+	if err != nil {
+		t.Fatalf("KeysChanged() returned an unexpected error: %v", err)
+	}
+
+	if len(keys) != 1 || keys[0] != "motion/1/title" {
+		t.Errorf("KeysChanged() returned %v, expected [motion/1/title]", keys)
+	}
+}
+
+func TestCompareAndSwapTooBig(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	ts.Data = map[string]json.RawMessage{"motion/1/title": []byte(`"old"`)}
+	ts.OnlyData = true
+	d := New(ts.TS.URL, new(test.UpdaterMock), WithMaxValueBytes(10))
+
+	if _, err := d.Get(context.Background(), "motion/1/title"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	swapped, err := d.CompareAndSwap("motion/1/title", json.RawMessage(`"old"`), json.RawMessage(`"a very long value"`))
+	if err == nil {
+		t.Errorf("CompareAndSwap() returned no error for a value, that is too big")
+	}
+	if swapped {
+		t.Errorf("CompareAndSwap() returned true for a value, that is too big")
+	}
+
+	got, err := d.Get(context.Background(), "motion/1/title")
+	if err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+	if string(got[0]) != `"old"` {
+		t.Errorf("Got %s, expected the old value", got[0])
+	}
+}
+
+func TestCompareAndSwapConcurrent(t *testing.T) {
+	c := newShardedCache(4)
+	c.GetOrSet(context.Background(), []string{"counter/1/value"}, func([]string) (map[string]json.RawMessage, error) {
+		return map[string]json.RawMessage{"counter/1/value": []byte(`0`)}, nil
+	})
+
+	const workers = 10
+	const increments = 100
+
+	var wg sync.WaitGroup
+	var failed int
+	var failedMu sync.Mutex
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < increments; {
+				values, err := c.GetOrSet(context.Background(), []string{"counter/1/value"}, nil)
+				if err != nil {
+					t.Errorf("GetOrSet() returned an unexpected error: %v", err)
+					return
+				}
+
+				n, _ := strconv.Atoi(string(values[0]))
+				swapped, err := c.CompareAndSwap("counter/1/value", values[0], []byte(strconv.Itoa(n+1)))
+				if err != nil {
+					t.Errorf("CompareAndSwap() returned an unexpected error: %v", err)
+					return
+				}
+
+				if !swapped {
+					failedMu.Lock()
+					failed++
+					failedMu.Unlock()
+					continue
+				}
+				j++
+			}
+		}()
+	}
+	wg.Wait()
+
+	values, err := c.GetOrSet(context.Background(), []string{"counter/1/value"}, nil)
+	if err != nil {
+		t.Fatalf("GetOrSet() returned an unexpected error: %v", err)
+	}
+
+	if expect := strconv.Itoa(workers * increments); string(values[0]) != expect {
+		t.Errorf("Got counter %s after %d failed swaps, expected %s", values[0], failed, expect)
+	}
+}
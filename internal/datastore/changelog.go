@@ -0,0 +1,128 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// ChangelogDatastore wrapps a datastore and writes every changed value to a
+// log. Each line of the log is a json object with the time, the key, the old
+// value and the new value.
+//
+// To know the old values, the ChangelogDatastore remembers the last value of
+// each key, that was requested or changed. Only changes of these keys are
+// written to the log. At most maxKeys values are remembered. If there are more,
+// an arbitrary key is forgotten.
+//
+// Has to be created with datastore.NewChangelogDatastore().
+type ChangelogDatastore struct {
+	inner   Source
+	maxKeys int
+
+	mu    sync.Mutex
+	log   io.Writer
+	known map[string]json.RawMessage
+}
+
+// NewChangelogDatastore creates a ChangelogDatastore. maxKeys is the number of
+// values that are remembered. Zero means no limit.
+func NewChangelogDatastore(inner Source, log io.Writer, maxKeys int) *ChangelogDatastore {
+	return &ChangelogDatastore{
+		inner:   inner,
+		maxKeys: maxKeys,
+		log:     log,
+		known:   make(map[string]json.RawMessage),
+	}
+}
+
+// Get returns the values from the inner datastore.
+func (d *ChangelogDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	values, err := d.inner.Get(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, key := range keys {
+		if _, ok := d.known[key]; !ok {
+			d.remember(key, values[i])
+		}
+	}
+	return values, nil
+}
+
+// KeysChanged returns the changed keys from the inner datastore. Before the
+// keys are returned, the values of known keys are written to the log.
+//
+// Errors while writing the log are only logged. The keys are returned anyway,
+// so no update gets lost.
+func (d *ChangelogDatastore) KeysChanged() ([]string, error) {
+	keys, err := d.inner.KeysChanged()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	var knownKeys []string
+	for _, key := range keys {
+		if _, ok := d.known[key]; ok {
+			knownKeys = append(knownKeys, key)
+		}
+	}
+	d.mu.Unlock()
+
+	if len(knownKeys) == 0 {
+		return keys, nil
+	}
+
+	values, err := d.inner.Get(context.Background(), knownKeys...)
+	if err != nil {
+		log.Printf("Can not get changed values for the changelog: %v", err)
+		return keys, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	encoder := json.NewEncoder(d.log)
+	for i, key := range knownKeys {
+		entry := changelogEntry{
+			Time: now,
+			Key:  key,
+			Old:  d.known[key],
+			New:  values[i],
+		}
+		if err := encoder.Encode(entry); err != nil {
+			log.Printf("Can not write changelog for key %s: %v", key, err)
+		}
+		d.remember(key, values[i])
+	}
+	return keys, nil
+}
+
+// remember saves the value of a key. If there are to many keys, an arbitrary
+// other key is removed.
+//
+// Has to be called with the lock.
+func (d *ChangelogDatastore) remember(key string, value json.RawMessage) {
+	if _, ok := d.known[key]; !ok && d.maxKeys > 0 && len(d.known) >= d.maxKeys {
+		for k := range d.known {
+			delete(d.known, k)
+			break
+		}
+	}
+	d.known[key] = value
+}
+
+type changelogEntry struct {
+	Time time.Time       `json:"time"`
+	Key  string          `json:"key"`
+	Old  json.RawMessage `json:"old"`
+	New  json.RawMessage `json:"new"`
+}
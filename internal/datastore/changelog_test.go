@@ -0,0 +1,156 @@
+package datastore_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestChangelogDatastore(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	ts.Data = map[string]json.RawMessage{"user/1/name": []byte(`"old"`)}
+	updater := test.NewUpdaterMock()
+	defer updater.Close()
+
+	buf := new(bytes.Buffer)
+	d := datastore.NewChangelogDatastore(datastore.New(ts.TS.URL, updater), buf, 0)
+
+	if _, err := d.Get(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	updater.Send(map[string]json.RawMessage{"user/1/name": []byte(`"new"`)})
+	if _, err := d.KeysChanged(); err != nil {
+		t.Fatalf("KeysChanged() returned an unexpected error: %v", err)
+	}
+
+	updater.Send(map[string]json.RawMessage{"user/1/name": []byte(`"newer"`)})
+	if _, err := d.KeysChanged(); err != nil {
+		t.Fatalf("KeysChanged() returned an unexpected error: %v", err)
+	}
+
+	expect := []struct {
+		old string
+		new string
+	}{
+		{`"old"`, `"new"`},
+		{`"new"`, `"newer"`},
+	}
+
+	scanner := bufio.NewScanner(buf)
+	for i, e := range expect {
+		if !scanner.Scan() {
+			t.Fatalf("Log has only %d lines, expected %d", i, len(expect))
+		}
+
+		var entry struct {
+			Time time.Time       `json:"time"`
+			Key  string          `json:"key"`
+			Old  json.RawMessage `json:"old"`
+			New  json.RawMessage `json:"new"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Log line %d is invalid json: %v", i, err)
+		}
+
+		if entry.Time.IsZero() {
+			t.Errorf("Log line %d has no time", i)
+		}
+		if entry.Key != "user/1/name" {
+			t.Errorf("Log line %d has key %s, expected user/1/name", i, entry.Key)
+		}
+		if string(entry.Old) != e.old || string(entry.New) != e.new {
+			t.Errorf("Log line %d has old value %s and new value %s, expected %s and %s", i, entry.Old, entry.New, e.old, e.new)
+		}
+	}
+}
+
+func TestChangelogDatastoreUnknownKey(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	updater := test.NewUpdaterMock()
+	defer updater.Close()
+
+	buf := new(bytes.Buffer)
+	d := datastore.NewChangelogDatastore(datastore.New(ts.TS.URL, updater), buf, 0)
+
+	updater.Send(map[string]json.RawMessage{"user/1/name": []byte(`"new"`)})
+	keys, err := d.KeysChanged()
+	if err != nil {
+		t.Fatalf("KeysChanged() returned an unexpected error: %v", err)
+	}
+
+	if len(keys) != 1 || keys[0] != "user/1/name" {
+		t.Errorf("KeysChanged() returned %v, expected [user/1/name]", keys)
+	}
+	if ts.RequestCount != 0 {
+		t.Errorf("Got %d requests to the datastore, expected 0", ts.RequestCount)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Got changelog `%s`, expected no entry for an unknown key", buf.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestChangelogDatastoreWriteError(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	ts.Data = map[string]json.RawMessage{"user/1/name": []byte(`"old"`)}
+	updater := test.NewUpdaterMock()
+	defer updater.Close()
+
+	d := datastore.NewChangelogDatastore(datastore.New(ts.TS.URL, updater), failingWriter{}, 0)
+	if _, err := d.Get(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	updater.Send(map[string]json.RawMessage{"user/1/name": []byte(`"new"`)})
+	keys, err := d.KeysChanged()
+	if err != nil {
+		t.Fatalf("KeysChanged() returned an unexpected error: %v", err)
+	}
+
+	if len(keys) != 1 || keys[0] != "user/1/name" {
+		t.Errorf("KeysChanged() returned %v, expected [user/1/name]", keys)
+	}
+}
+
+func TestChangelogDatastoreMaxKeys(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	ts.Data = map[string]json.RawMessage{
+		"user/1/name": []byte(`"a"`),
+		"user/2/name": []byte(`"b"`),
+		"user/3/name": []byte(`"c"`),
+	}
+	updater := test.NewUpdaterMock()
+	defer updater.Close()
+
+	buf := new(bytes.Buffer)
+	d := datastore.NewChangelogDatastore(datastore.New(ts.TS.URL, updater), buf, 2)
+	if _, err := d.Get(context.Background(), "user/1/name", "user/2/name", "user/3/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	updater.Send(map[string]json.RawMessage{
+		"user/1/name": []byte(`"x"`),
+		"user/2/name": []byte(`"x"`),
+		"user/3/name": []byte(`"x"`),
+	})
+	if _, err := d.KeysChanged(); err != nil {
+		t.Fatalf("KeysChanged() returned an unexpected error: %v", err)
+	}
+
+	if got := bytes.Count(buf.Bytes(), []byte("\n")); got != 2 {
+		t.Errorf("Got %d log lines, expected 2", got)
+	}
+}
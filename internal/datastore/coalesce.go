@@ -0,0 +1,65 @@
+package datastore
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MergeUpdates merges many updates into one map. If a key is in more then one
+// update, the value of the later update is used.
+func MergeUpdates(updates []map[string]json.RawMessage) map[string]json.RawMessage {
+	size := 0
+	for _, update := range updates {
+		size += len(update)
+	}
+
+	merged := make(map[string]json.RawMessage, size)
+	for _, update := range updates {
+		for k, v := range update {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// coalescedUpdate blocks until there is an update. Then it waits for the
+// coalesce window and merges all updates that are received in this time.
+//
+// An error that is received in the window is returned by the next call.
+func (d *Datastore) coalescedUpdate() (map[string]json.RawMessage, error) {
+	if err := d.coalesceErr; err != nil {
+		d.coalesceErr = nil
+		return nil, err
+	}
+
+	first, err := d.nextUpdate()
+	if err != nil {
+		return nil, err
+	}
+
+	updates := []map[string]json.RawMessage{first}
+	timer := time.NewTimer(d.coalesceWindow)
+	defer timer.Stop()
+
+	for {
+		select {
+		case u := <-d.updates:
+			if u.err != nil {
+				d.coalesceErr = u.err
+				return MergeUpdates(updates), nil
+			}
+			updates = append(updates, u.data)
+
+		case <-d.localSignal:
+			if local := d.takeLocal(); len(local) > 0 {
+				updates = append(updates, local)
+			}
+
+		case <-timer.C:
+			return MergeUpdates(updates), nil
+
+		case <-d.closed:
+			return MergeUpdates(updates), nil
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package datastore_test
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestMergeUpdates(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		updates []map[string]json.RawMessage
+		expect  map[string]string
+	}{
+		{
+			"empty",
+			nil,
+			map[string]string{},
+		},
+		{
+			"single",
+			[]map[string]json.RawMessage{
+				{"user/1/name": []byte(`"foo"`)},
+			},
+			map[string]string{"user/1/name": `"foo"`},
+		},
+		{
+			"conflicting keys",
+			[]map[string]json.RawMessage{
+				{"user/1/name": []byte(`"foo"`), "user/2/name": []byte(`"bar"`)},
+				{"user/1/name": []byte(`"new"`)},
+				{"user/1/name": []byte(`"newer"`), "user/3/name": nil},
+			},
+			map[string]string{"user/1/name": `"newer"`, "user/2/name": `"bar"`, "user/3/name": ``},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := datastore.MergeUpdates(tt.updates)
+
+			if len(got) != len(tt.expect) {
+				t.Errorf("Got %d keys, expected %d", len(got), len(tt.expect))
+			}
+			for k, v := range tt.expect {
+				value, ok := got[k]
+				if !ok {
+					t.Errorf("Key %s is missing", k)
+					continue
+				}
+				if string(value) != v {
+					t.Errorf("Got %s for %s, expected %s", value, k, v)
+				}
+			}
+		})
+	}
+}
+
+func TestCoalesceWindow(t *testing.T) {
+	updater := test.NewUpdaterMock()
+	defer updater.Close()
+	d := datastore.New("", updater, datastore.WithCoalesceWindow(50*time.Millisecond))
+
+	go func() {
+		updater.Send(map[string]json.RawMessage{"user/1/name": []byte(`"foo"`)})
+		updater.Send(map[string]json.RawMessage{"user/1/name": []byte(`"bar"`), "user/2/name": []byte(`"bar"`)})
+	}()
+
+	keys, err := d.KeysChanged()
+	if err != nil {
+		t.Fatalf("KeysChanged() returned an unexpected error: %v", err)
+	}
+
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "user/1/name" || keys[1] != "user/2/name" {
+		t.Errorf("Got keys %v, expected [user/1/name user/2/name]", keys)
+	}
+}
+
+func TestCoalesceWindowClose(t *testing.T) {
+	updater := test.NewUpdaterMock()
+	defer updater.Close()
+	d := datastore.New("", updater, datastore.WithCoalesceWindow(50*time.Millisecond))
+	d.Close()
+
+	done := make(chan error)
+	go func() {
+		_, err := d.KeysChanged()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("KeysChanged() after Close() returned no error")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("KeysChanged() after Close() did not return")
+	}
+}
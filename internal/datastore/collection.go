@@ -0,0 +1,55 @@
+package datastore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// CollectionIDs returns the ids of all objects of a collection. It asks the
+// datastore-service with the get_all method and does not use the cache.
+func (d *Datastore) CollectionIDs(ctx context.Context, collection string) ([]int, error) {
+	requestData, err := json.Marshal(map[string]interface{}{
+		"collection":    collection,
+		"mapped_fields": []string{"id"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating GetAllRequest: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.getAllURL, bytes.NewReader(requestData))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting collection %s: %w", collection, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("datastore returned status %s", resp.Status)
+	}
+
+	var objects map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&objects); err != nil {
+		return nil, fmt.Errorf("decoding responce: %w", err)
+	}
+
+	ids := make([]int, 0, len(objects))
+	for rawID := range objects {
+		id, err := strconv.Atoi(rawID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q in responce", rawID)
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
@@ -0,0 +1,33 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestCollectionIDs(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	ts.Data = map[string]json.RawMessage{
+		"motion/1/id":    []byte(`1`),
+		"motion/1/title": []byte(`"first"`),
+		"motion/5/id":    []byte(`5`),
+		"motion/7/id":    []byte(`null`),
+		"user/2/id":      []byte(`2`),
+	}
+	ts.OnlyData = true
+	d := New(ts.TS.URL, new(test.UpdaterMock))
+
+	ids, err := d.CollectionIDs(context.Background(), "motion")
+	if err != nil {
+		t.Fatalf("CollectionIDs() returned an unexpected error: %v", err)
+	}
+
+	if expect := []int{1, 5}; !reflect.DeepEqual(ids, expect) {
+		t.Errorf("CollectionIDs() returned %v, expected %v", ids, expect)
+	}
+}
@@ -14,43 +14,117 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sync"
+	"time"
 )
 
-const urlPath = "/internal/datastore/reader/get_many"
+const (
+	urlPath       = "/internal/datastore/reader/get_many"
+	getAllURLPath = "/internal/datastore/reader/get_all"
+)
 
 // Datastore can be used to get values from the datastore-service.
 //
 // Has to be created with datastore.New().
 type Datastore struct {
 	url        string
-	cache      *cache
+	getAllURL  string
+	cache      *shardedCache
 	keychanger Updater
+
+	setterTimeout time.Duration
+	cacheShards   int
+	cacheOptions  []cacheOption
+
+	coalesceWindow time.Duration
+	startReceive   sync.Once
+	updates        chan update
+	coalesceErr    error
+
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// local holds the values, that were changed in this process. They are
+	// returned by the next call to KeysChanged().
+	localMu     sync.Mutex
+	local       map[string]json.RawMessage
+	localSignal chan struct{}
 }
 
 // New returns a new Datastore object.
-func New(url string, keychanger Updater) *Datastore {
-	return &Datastore{
-		cache:      newCache(),
-		url:        url + urlPath,
-		keychanger: keychanger,
+func New(url string, keychanger Updater, options ...Option) *Datastore {
+	d := &Datastore{
+		url:         url + urlPath,
+		getAllURL:   url + getAllURLPath,
+		keychanger:  keychanger,
+		closed:      make(chan struct{}),
+		localSignal: make(chan struct{}, 1),
+	}
+	for _, o := range options {
+		o(d)
 	}
+	d.cache = newShardedCache(d.cacheShards, d.cacheOptions...)
+	return d
+}
+
+// Close stops the background goroutine, that receives the updates. It returns
+// at once. The goroutine stops, after the running call to Updater.Update()
+// returns.
+//
+// It can be called more then once.
+func (d *Datastore) Close() error {
+	d.closeOnce.Do(func() { close(d.closed) })
+	return nil
 }
 
 // Get returns the value for one or many keys.
 func (d *Datastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
-	values, err := d.cache.GetOrSet(ctx, keys, func(keys []string) (map[string]json.RawMessage, error) {
+	values, err := d.GetMany(ctx, [][]string{keys})
+	if err != nil {
+		return nil, err
+	}
+
+	return values[0], nil
+}
+
+// GetMany returns the values for many groups of keys. Missing keys of all
+// groups are requested together with one request to the datastore-service.
+//
+// The returned values have the same order as the given groups.
+func (d *Datastore) GetMany(ctx context.Context, keyGroups [][]string) ([][]json.RawMessage, error) {
+	values, err := d.cache.GetOrSetMany(ctx, keyGroups, func(keys []string) (map[string]json.RawMessage, error) {
 		return d.requestKeys(keys)
 	})
 	if err != nil {
-		return nil, fmt.Errorf("getOrSet for keys `%s`: %w", keys, err)
+		return nil, fmt.Errorf("getOrSet for keys `%s`: %w", keyGroups, err)
 	}
 
 	return values, nil
 }
 
+// CacheStats returns the counters of the cache.
+func (d *Datastore) CacheStats() CacheStats {
+	return d.cache.Stats()
+}
+
+// ShardStats returns the counters of each shard of the cache. A shard with
+// much more hits than the others is a hot shard.
+func (d *Datastore) ShardStats() []CacheStats {
+	return d.cache.ShardStats()
+}
+
 // KeysChanged blocks until some key have changed. Then, it returns the keys.
+// This are updates from the Updater and changes by methods like
+// CompareAndSwap().
+//
+// It is not save to call KeysChanged concurrently.
 func (d *Datastore) KeysChanged() ([]string, error) {
-	data, err := d.keychanger.Update()
+	receive := d.nextUpdate
+	if d.coalesceWindow > 0 {
+		receive = d.coalescedUpdate
+	}
+
+	data, err := receive()
 	if err != nil {
 		return nil, err
 	}
@@ -65,20 +139,151 @@ func (d *Datastore) KeysChanged() ([]string, error) {
 	return keys, nil
 }
 
-// requestKeys request a list of keys by the datastore. If an error happens, no
-// key is returned.
-func (d *Datastore) requestKeys(keys []string) (map[string]json.RawMessage, error) {
+// SetIfExist updates the values in the cache. Keys that are not in the cache
+// are ignored.
+func (d *Datastore) SetIfExist(data map[string]json.RawMessage) {
+	d.cache.SetIfExist(data)
+}
+
+// Invalidate removes the given keys from the cache. The next call to Get()
+// fetches them again from the datastore-service.
+func (d *Datastore) Invalidate(keys ...string) {
+	d.cache.DeleteKeys(keys...)
+}
+
+// ForEach calls fn for each key and value in the cache. fn must not call
+// methods of the datastore.
+func (d *Datastore) ForEach(fn func(key string, value json.RawMessage)) {
+	d.cache.ForEach(fn)
+}
+
+// Refresh fetches the given keys again and updates the cache. The etags of
+// the last fetch are sent to the datastore-service, so unchanged values are
+// not transferred again.
+func (d *Datastore) Refresh(ctx context.Context, keys ...string) error {
+	data, etags, err := d.FetchWithETag(ctx, keys, d.cache.ETags(keys))
+	if err != nil {
+		return fmt.Errorf("fetch keys: %w", err)
+	}
+
+	d.cache.SetIfExist(data)
+	d.cache.SetETags(etags)
+	return nil
+}
+
+// FetchWithETag requests the given keys from the datastore-service with
+// conditional requests. Keys with the same etag are requested together with
+// this etag as If-None-Match. Keys without an etag are requested together
+// without a condition.
+//
+// If the datastore-service responds with 304 Not Modified, the keys of the
+// request are not in the returned data but their etag is in the returned
+// etags.
+func (d *Datastore) FetchWithETag(ctx context.Context, keys []string, etags map[string]string) (data map[string]json.RawMessage, newEtags map[string]string, err error) {
+	data = make(map[string]json.RawMessage, len(keys))
+	newEtags = make(map[string]string, len(keys))
+
+	var order []string
+	byETag := make(map[string][]string)
+	for _, key := range keys {
+		etag := etags[key]
+		if _, ok := byETag[etag]; !ok {
+			order = append(order, etag)
+		}
+		byETag[etag] = append(byETag[etag], key)
+	}
+
+	for _, etag := range order {
+		group := byETag[etag]
+		req, err := d.newGetManyRequest(ctx, group)
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating request for keys %v: %w", group, err)
+		}
+
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		values, newETag, notModified, err := d.fetchConditional(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("requesting keys %v: %w", group, err)
+		}
+
+		if notModified && newETag == "" {
+			// The old etag is still valid. A 200 response without an etag
+			// removes the etag of the keys.
+			newETag = etag
+		}
+
+		for _, key := range group {
+			if !notModified {
+				data[key] = values[key]
+			}
+			if newETag != "" {
+				newEtags[key] = newETag
+			}
+		}
+	}
+	return data, newEtags, nil
+}
+
+// fetchConditional sends one conditional request. It returns the values and
+// the etag of the response. The bool is true, if the datastore-service
+// responded with 304 Not Modified. In this case, the values are nil.
+func (d *Datastore) fetchConditional(req *http.Request) (map[string]json.RawMessage, string, bool, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, resp.Header.Get("ETag"), true, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", false, fmt.Errorf("datastore returned status %s", resp.Status)
+	}
+
+	responseData, err := getManyResponceToKeyValue(resp.Body)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("parse responce: %w", err)
+	}
+
+	return responseData, resp.Header.Get("ETag"), false, nil
+}
+
+// newGetManyRequest creates a request to the get_many url of the
+// datastore-service.
+func (d *Datastore) newGetManyRequest(ctx context.Context, keys []string) (*http.Request, error) {
 	requestData, err := keysToGetManyRequest(keys)
 	if err != nil {
 		return nil, fmt.Errorf("creating GetManyRequest: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", d.url, bytes.NewReader(requestData))
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url, bytes.NewReader(requestData))
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// requestKeys request a list of keys by the datastore. If an error happens, no
+// key is returned.
+func (d *Datastore) requestKeys(keys []string) (map[string]json.RawMessage, error) {
+	ctx := context.Background()
+	if d.setterTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.setterTimeout)
+		defer cancel()
+	}
+
+	req, err := d.newGetManyRequest(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
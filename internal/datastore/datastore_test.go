@@ -2,7 +2,9 @@ package datastore_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
 	"github.com/openslides/openslides-autoupdate-service/internal/test"
@@ -43,3 +45,42 @@ func TestDataStoreGetMultiValue(t *testing.T) {
 		t.Errorf("Got %d requests to the datastore, expected 1", ts.RequestCount)
 	}
 }
+
+func TestDataStoreGetMany(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	d := datastore.New(ts.TS.URL, new(test.UpdaterMock))
+
+	got, err := d.GetMany(context.Background(), [][]string{{"collection/1/field"}, {"collection/1/field", "collection/2/field"}})
+	if err != nil {
+		t.Fatalf("GetMany() returned an unexpected error: %v", err)
+	}
+
+	if len(got) != 2 || len(got[0]) != 1 || len(got[1]) != 2 {
+		t.Fatalf("GetMany() returned %v, expected one value in the first group and two in the second", got)
+	}
+
+	if ts.RequestCount != 1 {
+		t.Errorf("Got %d requests to the datastore, expected 1", ts.RequestCount)
+	}
+}
+
+func TestDataStoreSetterTimeout(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	ts.SimulateLatency("collection/1/field", 50*time.Millisecond)
+	d := datastore.New(ts.TS.URL, new(test.UpdaterMock), datastore.WithSetterTimeout(10*time.Millisecond))
+
+	_, err := d.Get(context.Background(), "collection/1/field")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() with latency returned error `%v`, expected context.DeadlineExceeded", err)
+	}
+
+	got, err := d.Get(context.Background(), "collection/1/field")
+	if err != nil {
+		t.Fatalf("Second Get() returned an unexpected error: %v", err)
+	}
+
+	if len(got) != 1 || string(got[0]) != `"Hello World"` {
+		t.Errorf("Second Get() returned `%s`, expected `\"Hello World\"`", got)
+	}
+}
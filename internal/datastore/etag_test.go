@@ -0,0 +1,198 @@
+package datastore_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+// etagServer is a fake datastore-service that supports etags. The etag of
+// every key is its version. Requests with the current version as
+// If-None-Match get the status 304.
+//
+// It returns the same value for every requested field of user/1.
+type etagServer struct {
+	value    string
+	version  int
+	requests int
+	ts       *httptest.Server
+
+	// noETag lets the server answer without etags like an old
+	// datastore-service.
+	noETag bool
+}
+
+func newETagServer() *etagServer {
+	s := &etagServer{value: `"v1"`, version: 1}
+	s.ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests++
+		etag := fmt.Sprintf(`"%d"`, s.version)
+		if !s.noETag && r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		var body struct {
+			Requests []string `json:"requests"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fields := make([]string, len(body.Requests))
+		for i, key := range body.Requests {
+			fields[i] = fmt.Sprintf(`"%s":%s`, strings.TrimPrefix(key, "user/1/"), s.value)
+		}
+
+		if !s.noETag {
+			w.Header().Set("ETag", etag)
+		}
+		fmt.Fprintf(w, `{"user":{"1":{%s}}}`, strings.Join(fields, ","))
+	}))
+	return s
+}
+
+func TestFetchWithETag(t *testing.T) {
+	s := newETagServer()
+	defer s.ts.Close()
+	d := datastore.New(s.ts.URL, new(test.UpdaterMock))
+
+	data, etags, err := d.FetchWithETag(context.Background(), test.Str("user/1/name"), nil)
+	if err != nil {
+		t.Fatalf("FetchWithETag() returned an unexpected error: %v", err)
+	}
+	if got := string(data["user/1/name"]); got != `"v1"` {
+		t.Errorf("Got value %s, expected \"v1\"", got)
+	}
+	if got := etags["user/1/name"]; got != `"1"` {
+		t.Errorf("Got etag %s, expected \"1\"", got)
+	}
+
+	data, etags, err = d.FetchWithETag(context.Background(), test.Str("user/1/name"), etags)
+	if err != nil {
+		t.Fatalf("Second FetchWithETag() returned an unexpected error: %v", err)
+	}
+	if _, ok := data["user/1/name"]; ok {
+		t.Errorf("Got value for not modified key")
+	}
+	if got := etags["user/1/name"]; got != `"1"` {
+		t.Errorf("Got etag %s after 304, expected \"1\"", got)
+	}
+}
+
+func TestFetchWithETagResponseWithoutETag(t *testing.T) {
+	s := newETagServer()
+	defer s.ts.Close()
+	s.noETag = true
+	d := datastore.New(s.ts.URL, new(test.UpdaterMock))
+
+	data, etags, err := d.FetchWithETag(context.Background(), test.Str("user/1/name"), map[string]string{"user/1/name": `"1"`})
+	if err != nil {
+		t.Fatalf("FetchWithETag() returned an unexpected error: %v", err)
+	}
+	if got := string(data["user/1/name"]); got != `"v1"` {
+		t.Errorf("Got value %s, expected \"v1\"", got)
+	}
+	if got, ok := etags["user/1/name"]; ok {
+		t.Errorf("Got etag %s for a response without etag, expected none", got)
+	}
+}
+
+func TestRefreshNotModified(t *testing.T) {
+	s := newETagServer()
+	defer s.ts.Close()
+	d := datastore.New(s.ts.URL, new(test.UpdaterMock))
+
+	if _, err := d.Get(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+	if err := d.Refresh(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Refresh() returned an unexpected error: %v", err)
+	}
+
+	// The server returns 304 for the second refresh. The value has to stay
+	// in the cache.
+	s.value = `"not sent"`
+	if err := d.Refresh(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Second Refresh() returned an unexpected error: %v", err)
+	}
+
+	got, err := d.Get(context.Background(), "user/1/name")
+	if err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+	if string(got[0]) != `"v1"` {
+		t.Errorf("Got value %s, expected \"v1\"", got[0])
+	}
+
+	// A new version is sent to the client.
+	s.version = 2
+	if err := d.Refresh(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Third Refresh() returned an unexpected error: %v", err)
+	}
+
+	got, _ = d.Get(context.Background(), "user/1/name")
+	if string(got[0]) != `"not sent"` {
+		t.Errorf("Got value %s after the value changed, expected \"not sent\"", got[0])
+	}
+}
+
+func TestFetchWithETagBatched(t *testing.T) {
+	s := newETagServer()
+	defer s.ts.Close()
+	d := datastore.New(s.ts.URL, new(test.UpdaterMock))
+
+	data, etags, err := d.FetchWithETag(context.Background(), test.Str("user/1/name", "user/1/password"), nil)
+	if err != nil {
+		t.Fatalf("FetchWithETag() returned an unexpected error: %v", err)
+	}
+	if len(data) != 2 || len(etags) != 2 {
+		t.Errorf("Got %d values and %d etags, expected 2 of each", len(data), len(etags))
+	}
+
+	// Keys with the same etag are requested together. A key without an etag
+	// needs a second request.
+	if _, _, err := d.FetchWithETag(context.Background(), test.Str("user/1/name", "user/1/password", "user/1/email"), etags); err != nil {
+		t.Fatalf("Second FetchWithETag() returned an unexpected error: %v", err)
+	}
+
+	if s.requests != 3 {
+		t.Errorf("Got %d requests, expected 3", s.requests)
+	}
+}
+
+func TestETagDroppedOnUpdate(t *testing.T) {
+	s := newETagServer()
+	defer s.ts.Close()
+	d := datastore.New(s.ts.URL, new(test.UpdaterMock))
+
+	if _, err := d.Get(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+	if err := d.Refresh(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Refresh() returned an unexpected error: %v", err)
+	}
+
+	// The value in the cache changes without a new etag. The next refresh
+	// has to fetch the value, even when the server did not change.
+	d.SetIfExist(map[string]json.RawMessage{"user/1/name": []byte(`"local"`)})
+	if err := d.Refresh(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Second Refresh() returned an unexpected error: %v", err)
+	}
+
+	got, err := d.Get(context.Background(), "user/1/name")
+	if err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+	if string(got[0]) != `"v1"` {
+		t.Errorf("Got value %s, expected \"v1\"", got[0])
+	}
+}
@@ -0,0 +1,71 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// CacheExporter reports the number of cached keys per collection to a
+// CollectionGauge.
+//
+// Has to be created with datastore.NewCacheExporter().
+type CacheExporter struct {
+	cache Cache
+	gauge CollectionGauge
+
+	// seen holds all collections that were exported before. They are set to
+	// 0, when they are not in the cache anymore.
+	seen map[string]bool
+}
+
+// NewCacheExporter creates a CacheExporter.
+func NewCacheExporter(cache Cache, gauge CollectionGauge) *CacheExporter {
+	return &CacheExporter{
+		cache: cache,
+		gauge: gauge,
+		seen:  make(map[string]bool),
+	}
+}
+
+// Export counts the keys in the cache and sets the gauge for each collection.
+//
+// Export is not save for concurrent use.
+func (e *CacheExporter) Export() {
+	counts := make(map[string]int)
+	e.cache.ForEach(func(key string, _ json.RawMessage) {
+		idx := strings.IndexByte(key, '/')
+		if idx == -1 {
+			return
+		}
+		counts[key[:idx]]++
+	})
+
+	for collection := range e.seen {
+		if _, ok := counts[collection]; !ok {
+			e.gauge.Set(collection, 0)
+		}
+	}
+
+	for collection, count := range counts {
+		e.seen[collection] = true
+		e.gauge.Set(collection, float64(count))
+	}
+}
+
+// Run calls Export every interval until the context is done.
+func (e *CacheExporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.Export()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
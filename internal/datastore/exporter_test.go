@@ -0,0 +1,104 @@
+package datastore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+// mapGauge is a CollectionGauge that saves the values in a map.
+type mapGauge struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func (g *mapGauge) Set(collection string, count float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.values == nil {
+		g.values = make(map[string]float64)
+	}
+	g.values[collection] = count
+}
+
+func (g *mapGauge) get(collection string) (float64, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	v, ok := g.values[collection]
+	return v, ok
+}
+
+func TestCacheExporter(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	d := datastore.New(ts.TS.URL, new(test.UpdaterMock))
+
+	keys := test.Str("user/1/name", "user/2/name", "user/3/name", "agenda_item/1/weight", "agenda_item/2/weight")
+	if _, err := d.Get(context.Background(), keys...); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	gauge := new(mapGauge)
+	exporter := datastore.NewCacheExporter(d, gauge)
+	exporter.Export()
+
+	for collection, expect := range map[string]float64{"user": 3, "agenda_item": 2} {
+		got, ok := gauge.get(collection)
+		if !ok {
+			t.Errorf("Gauge for %s was not set", collection)
+			continue
+		}
+		if got != expect {
+			t.Errorf("Got %v for %s, expected %v", got, collection, expect)
+		}
+	}
+
+	// A collection that is removed from the cache is set to 0.
+	d.Invalidate("agenda_item/1/weight", "agenda_item/2/weight")
+	exporter.Export()
+	if got, _ := gauge.get("agenda_item"); got != 0 {
+		t.Errorf("Got %v for agenda_item after invalidate, expected 0", got)
+	}
+}
+
+func TestCacheExporterRun(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	d := datastore.New(ts.TS.URL, new(test.UpdaterMock))
+
+	gauge := new(mapGauge)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		datastore.NewCacheExporter(d, gauge).Run(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	if _, err := d.Get(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	timeout := time.After(time.Second)
+	for {
+		if got, _ := gauge.get("user"); got == 1 {
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("Gauge was not set after one second")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Run() did not return after the context was canceled")
+	}
+}
@@ -0,0 +1,49 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// FallbackDatastore wrapps a datastore and returns default values for keys,
+// that do not exist in the inner datastore.
+//
+// Has to be created with datastore.NewFallbackDatastore().
+type FallbackDatastore struct {
+	inner    Source
+	defaults map[string]json.RawMessage
+}
+
+// NewFallbackDatastore creates a FallbackDatastore. defaults maps keys to the
+// values that are returned, when the inner datastore returns nil for the key.
+func NewFallbackDatastore(inner Source, defaults map[string]json.RawMessage) *FallbackDatastore {
+	return &FallbackDatastore{
+		inner:    inner,
+		defaults: defaults,
+	}
+}
+
+// Get returns the values from the inner datastore. Missing values are replaced
+// by the defaults.
+func (d *FallbackDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	values, err := d.inner.Get(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		if values[i] != nil {
+			continue
+		}
+
+		if value, ok := d.defaults[key]; ok {
+			values[i] = value
+		}
+	}
+	return values, nil
+}
+
+// KeysChanged returns the changed keys from the inner datastore.
+func (d *FallbackDatastore) KeysChanged() ([]string, error) {
+	return d.inner.KeysChanged()
+}
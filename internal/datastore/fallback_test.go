@@ -0,0 +1,33 @@
+package datastore_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestFallbackDatastore(t *testing.T) {
+	inner := test.NewMockDatastore()
+	defer inner.Close()
+	inner.OnlyData = true
+	inner.Update(map[string]json.RawMessage{"user/2/is_active": []byte("false")})
+
+	d := datastore.NewFallbackDatastore(inner, map[string]json.RawMessage{
+		"user/1/is_active": []byte("true"),
+		"user/2/is_active": []byte("true"),
+	})
+
+	values, err := d.Get(context.Background(), "user/1/is_active", "user/2/is_active", "user/3/is_active")
+	if err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	for i, expect := range []string{"true", "false", ""} {
+		if got := string(values[i]); got != expect {
+			t.Errorf("Got value `%s` for key %d, expected `%s`", got, i, expect)
+		}
+	}
+}
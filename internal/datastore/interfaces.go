@@ -1,9 +1,64 @@
 package datastore
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+)
 
 // Updater returns keys that have changes. Blocks until there is
 // changed data.
 type Updater interface {
 	Update() (map[string]json.RawMessage, error)
 }
+
+// Source gets values for keys and informs, if they change. It is implemented
+// by Datastore and can be wrapped by the other datastores in this package.
+type Source interface {
+	Get(ctx context.Context, keys ...string) ([]json.RawMessage, error)
+	KeysChanged() ([]string, error)
+}
+
+// Cache holds values that can be removed, so they are fetched again. It is
+// implemented by Datastore.
+type Cache interface {
+	Invalidate(keys ...string)
+	ForEach(fn func(key string, value json.RawMessage))
+}
+
+// CollectionGauge receives the number of cached keys of a collection. A
+// prometheus.GaugeVec with the label "collection" can be used with a small
+// wrapper that calls WithLabelValues(collection).Set(count).
+type CollectionGauge interface {
+	Set(collection string, count float64)
+}
+
+// ETagFetcher fetches keys with conditional requests. It is implemented by
+// Datastore.
+type ETagFetcher interface {
+	FetchWithETag(ctx context.Context, keys []string, etags map[string]string) (data map[string]json.RawMessage, newEtags map[string]string, err error)
+}
+
+// PartialUpdateDatastore changes parts of json objects. It is implemented by
+// Datastore.
+type PartialUpdateDatastore interface {
+	ApplyMergePatch(ctx context.Context, key string, patch json.RawMessage) error
+}
+
+// VersionedCache changes values with optimistic locking. It is implemented by
+// Datastore.
+type VersionedCache interface {
+	CompareAndSwap(key string, expected, new json.RawMessage) (bool, error)
+}
+
+// Tracer starts spans. It is like the start method of an opentelemetry tracer,
+// so it can be implemented with a small adapter.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a traced duration that was started by a Tracer. An adapter for
+// opentelemetry can convert the attributes to attribute.KeyValue.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	End()
+}
@@ -0,0 +1,59 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// KeyFilterDatastore wrapps a datastore and only fetches keys from it, that
+// are allowed by a filter function. This can be used for keys, that are never
+// in the backend, for example keys that are computed locally.
+//
+// Has to be created with datastore.NewKeyFilterDatastore().
+type KeyFilterDatastore struct {
+	inner       Source
+	shouldFetch func(key string) bool
+}
+
+// NewKeyFilterDatastore creates a KeyFilterDatastore. shouldFetch is called
+// for each requested key. Only keys where it returns true are requested from
+// the inner datastore.
+func NewKeyFilterDatastore(inner Source, shouldFetch func(key string) bool) *KeyFilterDatastore {
+	return &KeyFilterDatastore{
+		inner:       inner,
+		shouldFetch: shouldFetch,
+	}
+}
+
+// Get returns the values for the keys. Keys that are filtered out get the
+// value nil.
+func (d *KeyFilterDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	fetchKeys := make([]string, 0, len(keys))
+	positions := make([]int, 0, len(keys))
+	for i, key := range keys {
+		if d.shouldFetch(key) {
+			fetchKeys = append(fetchKeys, key)
+			positions = append(positions, i)
+		}
+	}
+
+	values := make([]json.RawMessage, len(keys))
+	if len(fetchKeys) == 0 {
+		return values, nil
+	}
+
+	fetched, err := d.inner.Get(ctx, fetchKeys...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, pos := range positions {
+		values[pos] = fetched[i]
+	}
+	return values, nil
+}
+
+// KeysChanged returns the changed keys from the inner datastore.
+func (d *KeyFilterDatastore) KeysChanged() ([]string, error) {
+	return d.inner.KeysChanged()
+}
@@ -0,0 +1,70 @@
+package datastore_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestKeyFilterDatastore(t *testing.T) {
+	inner := test.NewMockDatastore()
+	defer inner.Close()
+	observed := datastore.NewObservableDatastore(inner)
+	var fetched [][]string
+	observed.OnFetch(func(keys []string) {
+		fetched = append(fetched, keys)
+	})
+
+	d := datastore.NewKeyFilterDatastore(observed, func(key string) bool {
+		return !strings.HasPrefix(key, "user/0/")
+	})
+
+	values, err := d.Get(context.Background(), "user/0/name", "user/1/name", "user/0/password")
+	if err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	if len(values) != 3 {
+		t.Fatalf("Got %d values, expected 3", len(values))
+	}
+	if values[0] != nil || values[2] != nil {
+		t.Errorf("Got values %s and %s for user/0, expected nil", values[0], values[2])
+	}
+	if got := string(values[1]); got != `"Hello World"` {
+		t.Errorf("Got value %s for user/1/name, expected \"Hello World\"", got)
+	}
+
+	if expect := [][]string{{"user/1/name"}}; !reflect.DeepEqual(fetched, expect) {
+		t.Errorf("Inner datastore was called with %v, expected %v", fetched, expect)
+	}
+}
+
+func TestKeyFilterDatastoreOnlyFiltered(t *testing.T) {
+	inner := test.NewMockDatastore()
+	defer inner.Close()
+	observed := datastore.NewObservableDatastore(inner)
+	called := false
+	observed.OnFetch(func([]string) {
+		called = true
+	})
+
+	d := datastore.NewKeyFilterDatastore(observed, func(key string) bool {
+		return !strings.HasPrefix(key, "user/0/")
+	})
+
+	values, err := d.Get(context.Background(), "user/0/name")
+	if err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	if len(values) != 1 || values[0] != nil {
+		t.Errorf("Got values %v, expected [nil]", values)
+	}
+	if called {
+		t.Errorf("Inner datastore was called")
+	}
+}
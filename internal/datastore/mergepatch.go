@@ -0,0 +1,104 @@
+package datastore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ApplyMergePatch changes the value of the key with a JSON Merge Patch as
+// described in RFC 7396. Fields of the patch are added to the value, fields
+// with the value null are removed and all other values are replaced. Objects
+// are merged recursively.
+//
+// The current value is read from the cache. If it is not there, it is fetched
+// from the datastore-service. The result is saved in the cache and returned by
+// the next call to KeysChanged(), so all subscribers of the key get the new
+// value.
+func (d *Datastore) ApplyMergePatch(ctx context.Context, key string, patch json.RawMessage) error {
+	if !json.Valid(patch) {
+		return fmt.Errorf("patch for key %s is not valid json", key)
+	}
+
+	if _, err := d.Get(ctx, key); err != nil {
+		return fmt.Errorf("get current value: %w", err)
+	}
+
+	value, err := d.cache.Patch(key, patch)
+	if err != nil {
+		return err
+	}
+
+	d.publishLocal(map[string]json.RawMessage{key: value})
+	return nil
+}
+
+// Patch applies a JSON Merge Patch to an existing key and returns the new
+// value. Returns an error, if the key does not exist in the cache or if the
+// result is bigger then the max value size. In this cases, the cache is not
+// changed.
+func (c *cache) Patch(key string, patch json.RawMessage) (json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keyState(key) != stExist {
+		return nil, fmt.Errorf("key %s is not in the cache", key)
+	}
+
+	value := mergePatch(c.data[key], patch)
+	if c.tooBig(key, value) {
+		return nil, fmt.Errorf("patched value for key %s is too big for the cache", key)
+	}
+	c.set(key, value)
+	return value, nil
+}
+
+// Patch is like cache.Patch.
+func (c *shardedCache) Patch(key string, patch json.RawMessage) (json.RawMessage, error) {
+	return c.shards[c.shardIndex(key)].Patch(key, patch)
+}
+
+// mergePatch applies the JSON Merge Patch patch to base like described in RFC
+// 7396. patch has to be valid json. A base, that is empty or not a json
+// object, is handled like an empty object, if the patch is an object.
+func mergePatch(base, patch json.RawMessage) json.RawMessage {
+	patchObject, ok := jsonObject(patch)
+	if !ok {
+		return patch
+	}
+
+	target, ok := jsonObject(base)
+	if !ok {
+		target = make(map[string]json.RawMessage, len(patchObject))
+	}
+
+	for name, value := range patchObject {
+		if string(bytes.TrimSpace(value)) == "null" {
+			delete(target, name)
+			continue
+		}
+		target[name] = mergePatch(target[name], value)
+	}
+
+	merged, err := json.Marshal(target)
+	if err != nil {
+		// All values are valid json, so this can not happen.
+		panic(fmt.Sprintf("encode merged object: %v", err))
+	}
+	return merged
+}
+
+// jsonObject decodes the value, if it is a json object.
+func jsonObject(value json.RawMessage) (map[string]json.RawMessage, bool) {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 || value[0] != '{' {
+		return nil, false
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(value, &object); err != nil {
+		return nil, false
+	}
+	return object, true
+}
@@ -0,0 +1,131 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestMergePatch(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		base   string
+		patch  string
+		expect string
+	}{
+		{"add field", `{"a":1}`, `{"b":2}`, `{"a":1,"b":2}`},
+		{"remove field", `{"a":1,"b":2}`, `{"b":null}`, `{"a":1}`},
+		{"remove unknown field", `{"a":1}`, `{"b":null}`, `{"a":1}`},
+		{"replace field", `{"a":1}`, `{"a":"x"}`, `{"a":"x"}`},
+		{"update nested field", `{"a":{"b":1,"c":2}}`, `{"a":{"c":3}}`, `{"a":{"b":1,"c":3}}`},
+		{"remove nested field", `{"a":{"b":1,"c":2}}`, `{"a":{"b":null}}`, `{"a":{"c":2}}`},
+		{"create nested object", `{"a":1}`, `{"b":{"c":null,"d":4}}`, `{"a":1,"b":{"d":4}}`},
+		{"replace array", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{"patch is no object", `{"a":1}`, `[1]`, `[1]`},
+		{"base is no object", `"text"`, `{"a":1}`, `{"a":1}`},
+		{"empty base", ``, `{"a":1}`, `{"a":1}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergePatch(json.RawMessage(tt.base), json.RawMessage(tt.patch))
+
+			if string(got) != tt.expect {
+				t.Errorf("Got %s, expected %s", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	ts.Data = map[string]json.RawMessage{
+		"motion/1/options": []byte(`{"color":"red","size":{"width":1,"height":2}}`),
+	}
+	ts.OnlyData = true
+	d := New(ts.TS.URL, new(test.UpdaterMock))
+
+	for _, tt := range []struct {
+		name   string
+		patch  string
+		expect string
+	}{
+		{"add field", `{"visible":true}`, `{"color":"red","size":{"width":1,"height":2},"visible":true}`},
+		{"remove field", `{"color":null}`, `{"size":{"width":1,"height":2},"visible":true}`},
+		{"update nested field", `{"size":{"width":5}}`, `{"size":{"height":2,"width":5},"visible":true}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := d.ApplyMergePatch(context.Background(), "motion/1/options", json.RawMessage(tt.patch)); err != nil {
+				t.Fatalf("ApplyMergePatch() returned an unexpected error: %v", err)
+			}
+
+			got, err := d.Get(context.Background(), "motion/1/options")
+			if err != nil {
+				t.Fatalf("Get() returned an unexpected error: %v", err)
+			}
+
+			if string(got[0]) != tt.expect {
+				t.Errorf("Got %s, expected %s", got[0], tt.expect)
+			}
+		})
+	}
+
+	if ts.RequestCount != 1 {
+		t.Errorf("Got %d requests to the datastore, expected 1", ts.RequestCount)
+	}
+}
+
+func TestApplyMergePatchInvalid(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	d := New(ts.TS.URL, new(test.UpdaterMock))
+
+	if err := d.ApplyMergePatch(context.Background(), "motion/1/options", json.RawMessage(`{"a":`)); err == nil {
+		t.Errorf("ApplyMergePatch() returned no error for an invalid patch")
+	}
+}
+
+func TestApplyMergePatchPublishes(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	ts.Data = map[string]json.RawMessage{"motion/1/options": []byte(`{"color":"red"}`)}
+	ts.OnlyData = true
+	updater := test.NewUpdaterMock()
+	defer updater.Close()
+	d := New(ts.TS.URL, updater)
+	defer d.Close()
+
+	if err := d.ApplyMergePatch(context.Background(), "motion/1/options", json.RawMessage(`{"color":"blue"}`)); err != nil {
+		t.Fatalf("ApplyMergePatch() returned an unexpected error: %v", err)
+	}
+
+	keys, err := d.KeysChanged()
+	if err != nil {
+		t.Fatalf("KeysChanged() returned an unexpected error: %v", err)
+	}
+
+	if len(keys) != 1 || keys[0] != "motion/1/options" {
+		t.Errorf("KeysChanged() returned %v, expected [motion/1/options]", keys)
+	}
+}
+
+func TestApplyMergePatchTooBig(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	ts.Data = map[string]json.RawMessage{"motion/1/options": []byte(`{"a":1}`)}
+	ts.OnlyData = true
+	d := New(ts.TS.URL, new(test.UpdaterMock), WithMaxValueBytes(10))
+
+	if err := d.ApplyMergePatch(context.Background(), "motion/1/options", json.RawMessage(`{"b":"a very long value"}`)); err == nil {
+		t.Errorf("ApplyMergePatch() returned no error for a result, that is too big")
+	}
+
+	got, err := d.Get(context.Background(), "motion/1/options")
+	if err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+	if string(got[0]) != `{"a":1}` {
+		t.Errorf("Got %s, expected the old value", got[0])
+	}
+}
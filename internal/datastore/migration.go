@@ -0,0 +1,54 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MigrationHelper updates the cache of a Datastore after the schema of the
+// datastore-service has changed.
+//
+// Has to be created with datastore.NewMigrationHelper().
+type MigrationHelper struct {
+	datastore *Datastore
+}
+
+// NewMigrationHelper creates a MigrationHelper for the given datastore.
+func NewMigrationHelper(d *Datastore) *MigrationHelper {
+	return &MigrationHelper{datastore: d}
+}
+
+// RenameField moves the values of all cached keys collection/*/oldField to
+// collection/*/newField. The new keys are only updated, if they are already in
+// the cache. The old keys are removed from the cache.
+func (m *MigrationHelper) RenameField(ctx context.Context, collection, oldField, newField string) error {
+	var oldKeys []string
+	for _, key := range m.datastore.cache.Keys() {
+		keyParts := strings.SplitN(key, "/", 3)
+		if len(keyParts) != 3 || keyParts[0] != collection || keyParts[2] != oldField {
+			continue
+		}
+		oldKeys = append(oldKeys, key)
+	}
+
+	if len(oldKeys) == 0 {
+		return nil
+	}
+
+	values, err := m.datastore.Get(ctx, oldKeys...)
+	if err != nil {
+		return fmt.Errorf("get values for field %s/%s: %w", collection, oldField, err)
+	}
+
+	newData := make(map[string]json.RawMessage, len(oldKeys))
+	for i, key := range oldKeys {
+		newKey := strings.TrimSuffix(key, oldField) + newField
+		newData[newKey] = values[i]
+	}
+
+	m.datastore.cache.SetIfExist(newData)
+	m.datastore.cache.DeleteKeys(oldKeys...)
+	return nil
+}
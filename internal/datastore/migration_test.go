@@ -0,0 +1,49 @@
+package datastore_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestMigrationRenameField(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	ts.Data = map[string]json.RawMessage{
+		"user/1/name":     []byte(`"old value"`),
+		"user/1/username": []byte(`"new value"`),
+	}
+	ts.OnlyData = true
+	d := datastore.New(ts.TS.URL, new(test.UpdaterMock))
+
+	// Load both keys into the cache.
+	if _, err := d.Get(context.Background(), "user/1/name", "user/1/username"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	if err := datastore.NewMigrationHelper(d).RenameField(context.Background(), "user", "name", "username"); err != nil {
+		t.Fatalf("RenameField() returned an unexpected error: %v", err)
+	}
+
+	requests := ts.RequestCount
+	got, err := d.Get(context.Background(), "user/1/username")
+	if err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	if string(got[0]) != `"old value"` {
+		t.Errorf("Got value %s for the new key, expected `\"old value\"`", got[0])
+	}
+	if ts.RequestCount != requests {
+		t.Errorf("The new key was requested from the datastore, expected it to be in the cache")
+	}
+
+	if _, err := d.Get(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+	if ts.RequestCount != requests+1 {
+		t.Errorf("The old key was not requested from the datastore, expected it to be removed from the cache")
+	}
+}
@@ -0,0 +1,42 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ObservableDatastore wrapps a datastore and calls hooks with the keys of each
+// call to Get. It can be used in tests to see, which keys are requested.
+//
+// The hooks are called without a lock. So all hooks have to be registered
+// with OnFetch() before the datastore is used.
+//
+// Has to be created with datastore.NewObservableDatastore().
+type ObservableDatastore struct {
+	inner Source
+	hooks []func(keys []string)
+}
+
+// NewObservableDatastore creates an ObservableDatastore.
+func NewObservableDatastore(inner Source) *ObservableDatastore {
+	return &ObservableDatastore{inner: inner}
+}
+
+// OnFetch registers a hook that is called with the keys of each call to Get.
+// The hooks are called in the order they were registered.
+func (d *ObservableDatastore) OnFetch(fn func(keys []string)) {
+	d.hooks = append(d.hooks, fn)
+}
+
+// Get calls the hooks and returns the values from the inner datastore.
+func (d *ObservableDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	for _, hook := range d.hooks {
+		hook(keys)
+	}
+	return d.inner.Get(ctx, keys...)
+}
+
+// KeysChanged returns the changed keys from the inner datastore.
+func (d *ObservableDatastore) KeysChanged() ([]string, error) {
+	return d.inner.KeysChanged()
+}
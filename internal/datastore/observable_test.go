@@ -0,0 +1,39 @@
+package datastore_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestObservableDatastore(t *testing.T) {
+	inner := test.NewMockDatastore()
+	defer inner.Close()
+	d := datastore.NewObservableDatastore(inner)
+
+	var first, second [][]string
+	d.OnFetch(func(keys []string) {
+		first = append(first, keys)
+	})
+	d.OnFetch(func(keys []string) {
+		second = append(second, keys)
+	})
+
+	if _, err := d.Get(context.Background(), "user/1/name", "user/2/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+	if _, err := d.Get(context.Background(), "motion/1/title"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	expect := [][]string{{"user/1/name", "user/2/name"}, {"motion/1/title"}}
+	if !reflect.DeepEqual(first, expect) {
+		t.Errorf("First hook was called with %v, expected %v", first, expect)
+	}
+	if !reflect.DeepEqual(second, expect) {
+		t.Errorf("Second hook was called with %v, expected %v", second, expect)
+	}
+}
@@ -0,0 +1,44 @@
+package datastore
+
+import "time"
+
+// Option is an optional argument for datastore.New().
+type Option func(*Datastore)
+
+// WithSetterTimeout sets a timeout for the requests to the datastore-service,
+// that fill the cache. If a request takes longer, Get() returns an error that
+// wraps context.DeadlineExceeded and the keys are requested again by the next
+// call.
+func WithSetterTimeout(d time.Duration) Option {
+	return func(ds *Datastore) {
+		ds.setterTimeout = d
+	}
+}
+
+// WithMaxValueBytes sets the size of the biggest value in bytes that is saved
+// in the cache. Bigger values are not stored and requested from the
+// datastore-service each time they are needed. This prevents that one huge
+// value fills the memory.
+func WithMaxValueBytes(n int) Option {
+	return func(ds *Datastore) {
+		ds.cacheOptions = append(ds.cacheOptions, withMaxValueBytes(n))
+	}
+}
+
+// WithCacheShards splits the cache into n parts. Each part has its own lock,
+// so many parallel requests block each other less. The default is one part.
+func WithCacheShards(n int) Option {
+	return func(ds *Datastore) {
+		ds.cacheShards = n
+	}
+}
+
+// WithCoalesceWindow waits the duration d after an update was received and
+// merges all updates of this time. So subscribers get one frame instead of
+// many small ones, when keys change rapidly. The default is 0, which sends
+// each update on its own.
+func WithCoalesceWindow(d time.Duration) Option {
+	return func(ds *Datastore) {
+		ds.coalesceWindow = d
+	}
+}
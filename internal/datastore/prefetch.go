@@ -0,0 +1,74 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+)
+
+// PrefetchRule tells the AsyncDatastore to fetch the PrefetchKeys, when the
+// TriggerKey is requested.
+type PrefetchRule struct {
+	TriggerKey   string
+	PrefetchKeys []string
+}
+
+// AsyncDatastore wrapps a datastore and fetches keys in the background, that
+// are often requested together with other keys. This warms the cache of the
+// inner datastore.
+//
+// Has to be created with datastore.NewAsyncDatastore().
+type AsyncDatastore struct {
+	inner Source
+	rules map[string][]string
+}
+
+// NewAsyncDatastore creates an AsyncDatastore.
+func NewAsyncDatastore(inner Source, prefetchRules []PrefetchRule) *AsyncDatastore {
+	rules := make(map[string][]string, len(prefetchRules))
+	for _, r := range prefetchRules {
+		rules[r.TriggerKey] = append(rules[r.TriggerKey], r.PrefetchKeys...)
+	}
+
+	return &AsyncDatastore{
+		inner: inner,
+		rules: rules,
+	}
+}
+
+// Get returns the values from the inner datastore. If one of the keys is the
+// trigger key of a rule, the prefetch keys of the rule are fetched in the
+// background.
+//
+// Get does not wait for the prefetch.
+func (d *AsyncDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	values, err := d.inner.Get(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefetch []string
+	for _, key := range keys {
+		prefetch = append(prefetch, d.rules[key]...)
+	}
+
+	if len(prefetch) > 0 {
+		go d.prefetch(prefetch)
+	}
+	return values, nil
+}
+
+// KeysChanged returns the changed keys from the inner datastore.
+func (d *AsyncDatastore) KeysChanged() ([]string, error) {
+	return d.inner.KeysChanged()
+}
+
+// prefetch requests the keys from the inner datastore and drops the values.
+//
+// It does not use the context of the request, so the prefetch is not stopped,
+// when the request is done.
+func (d *AsyncDatastore) prefetch(keys []string) {
+	if _, err := d.inner.Get(context.Background(), keys...); err != nil {
+		log.Printf("Can not prefetch keys %v: %v", keys, err)
+	}
+}
@@ -0,0 +1,83 @@
+package datastore_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+// recordingSource is a datastore.Source that sends the keys of each call to
+// Get to a channel. Calls for the key "slow" block until release is closed.
+type recordingSource struct {
+	*test.MockDatastore
+	calls   chan []string
+	release chan struct{}
+}
+
+func newRecordingSource() *recordingSource {
+	return &recordingSource{
+		MockDatastore: test.NewMockDatastore(),
+		calls:         make(chan []string, 10),
+		release:       make(chan struct{}),
+	}
+}
+
+func (s *recordingSource) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	s.calls <- keys
+	for _, key := range keys {
+		if key == "user/1/slow" {
+			<-s.release
+		}
+	}
+	return s.MockDatastore.Get(ctx, keys...)
+}
+
+func TestAsyncDatastorePrefetch(t *testing.T) {
+	inner := newRecordingSource()
+	defer inner.Close()
+	d := datastore.NewAsyncDatastore(inner, []datastore.PrefetchRule{
+		{TriggerKey: "user/1/name", PrefetchKeys: []string{"user/1/email", "user/1/group_ids"}},
+	})
+
+	if _, err := d.Get(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	if got := <-inner.calls; len(got) != 1 || got[0] != "user/1/name" {
+		t.Errorf("First call got keys %v, expected [user/1/name]", got)
+	}
+
+	select {
+	case got := <-inner.calls:
+		if len(got) != 2 || got[0] != "user/1/email" || got[1] != "user/1/group_ids" {
+			t.Errorf("Prefetch got keys %v, expected [user/1/email user/1/group_ids]", got)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Prefetch was not called")
+	}
+}
+
+func TestAsyncDatastorePrefetchDoesNotBlock(t *testing.T) {
+	inner := newRecordingSource()
+	defer inner.Close()
+	defer close(inner.release)
+	d := datastore.NewAsyncDatastore(inner, []datastore.PrefetchRule{
+		{TriggerKey: "user/1/name", PrefetchKeys: []string{"user/1/slow"}},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		d.Get(context.Background(), "user/1/name")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Get() was blocked by the prefetch")
+	}
+}
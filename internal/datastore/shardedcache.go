@@ -0,0 +1,285 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// shardedCache distributes the keys over many caches. Each shard has its own
+// lock, so requests for different keys block each other less.
+//
+// A key is always in the same shard. The set function is still called only
+// once for all missing keys of a request.
+//
+// A new shardedCache has to be created with newShardedCache().
+type shardedCache struct {
+	shards []*cache
+}
+
+// newShardedCache creates a shardedCache with the given number of shards. Each
+// shard is created with the given options.
+func newShardedCache(shards int, options ...cacheOption) *shardedCache {
+	if shards < 1 {
+		shards = 1
+	}
+
+	c := &shardedCache{shards: make([]*cache, shards)}
+	for i := range c.shards {
+		c.shards[i] = newCache(options...)
+	}
+	return c
+}
+
+// shardIndex returns the index of the shard for the key.
+//
+// It uses the same hash as fnv.New32() but without allocations.
+func (c *shardedCache) shardIndex(key string) int {
+	if len(c.shards) == 1 {
+		return 0
+	}
+
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for i := 0; i < len(key); i++ {
+		h *= prime32
+		h ^= uint32(key[i])
+	}
+	return int(h % uint32(len(c.shards)))
+}
+
+// singleShard returns the shard of the keys, if all keys are in the same
+// shard. Returns nil otherwise.
+func (c *shardedCache) singleShard(keys []string) *cache {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	idx := c.shardIndex(keys[0])
+	for _, key := range keys[1:] {
+		if c.shardIndex(key) != idx {
+			return nil
+		}
+	}
+	return c.shards[idx]
+}
+
+// split groups the keys by their shard. The positions are the indexes of the
+// keys in the given list.
+func (c *shardedCache) split(keys []string) (shardKeys [][]string, positions [][]int) {
+	shardKeys = make([][]string, len(c.shards))
+	positions = make([][]int, len(c.shards))
+	for i, key := range keys {
+		idx := c.shardIndex(key)
+		shardKeys[idx] = append(shardKeys[idx], key)
+		positions[idx] = append(positions[idx], i)
+	}
+	return shardKeys, positions
+}
+
+// GetOrSet is like cache.GetOrSet. The missing keys of all shards are fetched
+// with one call to the set function.
+func (c *shardedCache) GetOrSet(ctx context.Context, keys []string, set cacheSetFunc) ([]json.RawMessage, error) {
+	if shard := c.singleShard(keys); shard != nil {
+		return shard.GetOrSet(ctx, keys, set)
+	}
+
+	shardKeys, positions := c.split(keys)
+
+	missing := make([][]string, len(c.shards))
+	var allMissing []string
+	for i, shard := range c.shards {
+		if len(shardKeys[i]) == 0 {
+			continue
+		}
+		missing[i] = shard.startFetching(shardKeys[i])
+		allMissing = append(allMissing, missing[i]...)
+	}
+
+	var oversized map[string]json.RawMessage
+	if len(allMissing) > 0 {
+		// Fetch missing keys in the background like cache.GetOrSet.
+		type result struct {
+			oversized map[string]json.RawMessage
+			err       error
+		}
+		resultChan := make(chan result, 1)
+		go func() {
+			data, err := set(allMissing)
+
+			var r result
+			for i, shard := range c.shards {
+				if len(missing[i]) == 0 {
+					continue
+				}
+
+				// storeMissing has to be called for each shard, even after
+				// an error, so the pending keys get closed.
+				o, storeErr := shard.storeMissing(missing[i], data, err)
+				if storeErr != nil && r.err == nil {
+					r.err = storeErr
+				}
+
+				for k, v := range o {
+					if r.oversized == nil {
+						r.oversized = make(map[string]json.RawMessage)
+					}
+					r.oversized[k] = v
+				}
+			}
+			resultChan <- r
+		}()
+
+		select {
+		case r := <-resultChan:
+			if r.err != nil {
+				return nil, fmt.Errorf("fetching key: %w", r.err)
+			}
+			oversized = r.oversized
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	values := make([]json.RawMessage, len(keys))
+	for i, shard := range c.shards {
+		if len(shardKeys[i]) == 0 {
+			continue
+		}
+
+		shardValues, err := shard.values(ctx, shardKeys[i], oversized, set)
+		if err != nil {
+			return nil, err
+		}
+
+		for j, pos := range positions[i] {
+			values[pos] = shardValues[j]
+		}
+	}
+	return values, nil
+}
+
+// GetOrSetMany is like cache.GetOrSetMany.
+func (c *shardedCache) GetOrSetMany(ctx context.Context, keyGroups [][]string, set cacheSetFunc) ([][]json.RawMessage, error) {
+	return getOrSetMany(ctx, c.GetOrSet, keyGroups, set)
+}
+
+// SetIfExist is like cache.SetIfExist.
+func (c *shardedCache) SetIfExist(data map[string]json.RawMessage) {
+	if len(c.shards) == 1 {
+		c.shards[0].SetIfExist(data)
+		return
+	}
+
+	shardData := make([]map[string]json.RawMessage, len(c.shards))
+	for key, value := range data {
+		idx := c.shardIndex(key)
+		if shardData[idx] == nil {
+			shardData[idx] = make(map[string]json.RawMessage)
+		}
+		shardData[idx][key] = value
+	}
+
+	for i, shard := range c.shards {
+		if shardData[i] != nil {
+			shard.SetIfExist(shardData[i])
+		}
+	}
+}
+
+// DeleteKeys is like cache.DeleteKeys.
+func (c *shardedCache) DeleteKeys(keys ...string) {
+	shardKeys, _ := c.split(keys)
+	for i, shard := range c.shards {
+		if len(shardKeys[i]) > 0 {
+			shard.DeleteKeys(shardKeys[i]...)
+		}
+	}
+}
+
+// Keys returns all keys that exist in the cache.
+func (c *shardedCache) Keys() []string {
+	var keys []string
+	for _, shard := range c.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+// ForEach is like cache.ForEach. Only one shard is locked at a time.
+func (c *shardedCache) ForEach(fn func(key string, value json.RawMessage)) {
+	for _, shard := range c.shards {
+		shard.ForEach(fn)
+	}
+}
+
+// KeysWithPrefix is like cache.KeysWithPrefix. The keys are sorted.
+func (c *shardedCache) KeysWithPrefix(prefix string) []string {
+	var keys []string
+	for _, shard := range c.shards {
+		keys = append(keys, shard.KeysWithPrefix(prefix)...)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ETags is like cache.ETags.
+func (c *shardedCache) ETags(keys []string) map[string]string {
+	etags := make(map[string]string, len(keys))
+	shardKeys, _ := c.split(keys)
+	for i, shard := range c.shards {
+		if len(shardKeys[i]) == 0 {
+			continue
+		}
+
+		for k, v := range shard.ETags(shardKeys[i]) {
+			etags[k] = v
+		}
+	}
+	return etags
+}
+
+// SetETags is like cache.SetETags.
+func (c *shardedCache) SetETags(etags map[string]string) {
+	shardETags := make([]map[string]string, len(c.shards))
+	for key, etag := range etags {
+		idx := c.shardIndex(key)
+		if shardETags[idx] == nil {
+			shardETags[idx] = make(map[string]string)
+		}
+		shardETags[idx][key] = etag
+	}
+
+	for i, shard := range c.shards {
+		if shardETags[i] != nil {
+			shard.SetETags(shardETags[i])
+		}
+	}
+}
+
+// Stats returns the sum of the counters of all shards.
+func (c *shardedCache) Stats() CacheStats {
+	var stats CacheStats
+	for _, shard := range c.shards {
+		s := shard.Stats()
+		stats.Hits += s.Hits
+		stats.Misses += s.Misses
+		stats.Pending += s.Pending
+		stats.Entries += s.Entries
+	}
+	return stats
+}
+
+// ShardStats returns the counters of each shard. The index of the stats is the
+// index of the shard.
+func (c *shardedCache) ShardStats() []CacheStats {
+	stats := make([]CacheStats, len(c.shards))
+	for i, shard := range c.shards {
+		stats[i] = shard.Stats()
+	}
+	return stats
+}
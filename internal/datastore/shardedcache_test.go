@@ -0,0 +1,168 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestShardedCacheGetOrSet(t *testing.T) {
+	c := newShardedCache(8)
+
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("user/%d/name", i)
+	}
+
+	var calls int
+	set := func(keys []string) (map[string]json.RawMessage, error) {
+		calls++
+		data := make(map[string]json.RawMessage, len(keys))
+		for _, key := range keys {
+			data[key] = json.RawMessage(`"` + key + `"`)
+		}
+		return data, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		got, err := c.GetOrSet(context.Background(), keys, set)
+		if err != nil {
+			t.Fatalf("GetOrSet() returned the unexpected error: %v", err)
+		}
+
+		for j, key := range keys {
+			if expect := `"` + key + `"`; string(got[j]) != expect {
+				t.Errorf("Got value %s for key %s, expected %s", got[j], key, expect)
+			}
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Set function was called %d times, expected 1", calls)
+	}
+
+	if got := c.Stats(); got.Entries != 20 || got.Hits != 20 || got.Misses != 20 {
+		t.Errorf("Got stats %+v, expected 20 entries, 20 hits and 20 misses", got)
+	}
+}
+
+func TestShardedCacheUpdate(t *testing.T) {
+	c := newShardedCache(8)
+	c.GetOrSet(context.Background(), []string{"user/1/name", "user/2/name", "user/3/name"}, func([]string) (map[string]json.RawMessage, error) {
+		return nil, nil
+	})
+
+	c.SetIfExist(map[string]json.RawMessage{"user/1/name": json.RawMessage(`"new"`), "user/4/name": json.RawMessage(`"new"`)})
+	c.DeleteKeys("user/2/name")
+
+	keys := c.Keys()
+	sort.Strings(keys)
+	if expect := []string{"user/1/name", "user/3/name"}; fmt.Sprint(keys) != fmt.Sprint(expect) {
+		t.Errorf("Keys() returned %v, expected %v", keys, expect)
+	}
+
+	got, err := c.GetOrSet(context.Background(), []string{"user/1/name"}, benchmarkSetFunc)
+	if err != nil {
+		t.Fatalf("GetOrSet() returned the unexpected error: %v", err)
+	}
+	if string(got[0]) != `"new"` {
+		t.Errorf("Got value %s, expected \"new\"", got[0])
+	}
+}
+
+// BenchmarkShardedCache runs GetOrSet and SetIfExist from 100 goroutines on
+// caches with different numbers of shards. The values are in the cache, so
+// only the locks are measured.
+func BenchmarkShardedCache(b *testing.B) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("user/%d/name", i)
+	}
+
+	for _, shards := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("shards %d", shards), func(b *testing.B) {
+			c := newShardedCache(shards)
+			c.GetOrSet(context.Background(), keys, benchmarkSetFunc)
+
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			for g := 0; g < 100; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for n := 0; n < b.N; n++ {
+						key := keys[(g*31+n)%len(keys)]
+						c.GetOrSet(context.Background(), []string{key}, benchmarkSetFunc)
+						if n%10 == 0 {
+							c.SetIfExist(map[string]json.RawMessage{key: json.RawMessage(`"value"`)})
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestShardedCacheShardStats(t *testing.T) {
+	c := newShardedCache(4)
+	set := func(keys []string) (map[string]json.RawMessage, error) {
+		data := make(map[string]json.RawMessage, len(keys))
+		for _, key := range keys {
+			data[key] = json.RawMessage(`"value"`)
+		}
+		return data, nil
+	}
+
+	// Find some keys of the first shard and one key for each other shard.
+	var hotKeys []string
+	otherKeys := make(map[int]string)
+	for i := 0; len(hotKeys) < 10 || len(otherKeys) < 3; i++ {
+		key := fmt.Sprintf("user/%d/name", i)
+		idx := c.shardIndex(key)
+		if idx == 0 {
+			if len(hotKeys) < 10 {
+				hotKeys = append(hotKeys, key)
+			}
+			continue
+		}
+		otherKeys[idx] = key
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := c.GetOrSet(context.Background(), hotKeys, set); err != nil {
+			t.Fatalf("GetOrSet() returned the unexpected error: %v", err)
+		}
+	}
+	for _, key := range otherKeys {
+		if _, err := c.GetOrSet(context.Background(), []string{key}, set); err != nil {
+			t.Fatalf("GetOrSet() returned the unexpected error: %v", err)
+		}
+	}
+
+	stats := c.ShardStats()
+	if len(stats) != 4 {
+		t.Fatalf("Got stats for %d shards, expected 4", len(stats))
+	}
+
+	if stats[0].Hits != 40 || stats[0].Entries != 10 {
+		t.Errorf("Got stats %+v for the hot shard, expected 40 hits and 10 entries", stats[0])
+	}
+
+	for i, s := range stats[1:] {
+		if s.Hits >= stats[0].Hits {
+			t.Errorf("Shard %d has %d hits, expected less then the hot shard", i+1, s.Hits)
+		}
+	}
+
+	var sum uint64
+	for _, s := range stats {
+		sum += s.Hits
+	}
+	if total := c.Stats().Hits; sum != total {
+		t.Errorf("Sum of shard hits is %d, Stats() returned %d", sum, total)
+	}
+}
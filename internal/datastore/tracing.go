@@ -0,0 +1,48 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// TracingDatastore wrapps a datastore and creates a span for each call to Get.
+//
+// The span is called datastore.fetch and has the attributes num_keys and
+// cache_hit. cache_hit is only set, if the inner datastore has a method
+// CacheStats() like Datastore. It is true, when no key had to be fetched.
+// Since the stats are shared by all calls, a concurrent call can set it to
+// false.
+//
+// Has to be created with datastore.NewTracingDatastore().
+type TracingDatastore struct {
+	inner  Source
+	tracer Tracer
+}
+
+// NewTracingDatastore creates a TracingDatastore.
+func NewTracingDatastore(inner Source, tracer Tracer) *TracingDatastore {
+	return &TracingDatastore{inner: inner, tracer: tracer}
+}
+
+// Get returns the values from the inner datastore inside a span.
+func (d *TracingDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	ctx, span := d.tracer.Start(ctx, "datastore.fetch")
+	defer span.End()
+
+	span.SetAttribute("num_keys", len(keys))
+
+	stats, ok := d.inner.(interface{ CacheStats() CacheStats })
+	if !ok {
+		return d.inner.Get(ctx, keys...)
+	}
+
+	misses := stats.CacheStats().Misses
+	values, err := d.inner.Get(ctx, keys...)
+	span.SetAttribute("cache_hit", stats.CacheStats().Misses == misses)
+	return values, err
+}
+
+// KeysChanged returns the changed keys from the inner datastore.
+func (d *TracingDatastore) KeysChanged() ([]string, error) {
+	return d.inner.KeysChanged()
+}
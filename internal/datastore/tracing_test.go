@@ -0,0 +1,88 @@
+package datastore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/datastore"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+type mockSpan struct {
+	name       string
+	attributes map[string]interface{}
+	ended      bool
+}
+
+func (s *mockSpan) SetAttribute(key string, value interface{}) {
+	s.attributes[key] = value
+}
+
+func (s *mockSpan) End() {
+	s.ended = true
+}
+
+type mockTracer struct {
+	spans []*mockSpan
+}
+
+func (t *mockTracer) Start(ctx context.Context, name string) (context.Context, datastore.Span) {
+	s := &mockSpan{name: name, attributes: make(map[string]interface{})}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestTracingDatastore(t *testing.T) {
+	ts := test.NewDatastoreServer()
+	defer ts.TS.Close()
+	tracer := new(mockTracer)
+	d := datastore.NewTracingDatastore(datastore.New(ts.TS.URL, new(test.UpdaterMock)), tracer)
+
+	keys := test.Str("user/1/name", "user/2/name")
+	for i := 0; i < 2; i++ {
+		if _, err := d.Get(context.Background(), keys...); err != nil {
+			t.Fatalf("Get() returned an unexpected error: %v", err)
+		}
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("Got %d spans, expected 2", len(tracer.spans))
+	}
+
+	for i, expectHit := range []bool{false, true} {
+		span := tracer.spans[i]
+		if span.name != "datastore.fetch" {
+			t.Errorf("Span %d has name %s, expected datastore.fetch", i, span.name)
+		}
+		if !span.ended {
+			t.Errorf("Span %d was not ended", i)
+		}
+		if got := span.attributes["num_keys"]; got != 2 {
+			t.Errorf("Span %d has num_keys %v, expected 2", i, got)
+		}
+		if got := span.attributes["cache_hit"]; got != expectHit {
+			t.Errorf("Span %d has cache_hit %v, expected %v", i, got, expectHit)
+		}
+	}
+}
+
+func TestTracingDatastoreWithoutCache(t *testing.T) {
+	inner := test.NewMockDatastore()
+	defer inner.Close()
+	tracer := new(mockTracer)
+	d := datastore.NewTracingDatastore(inner, tracer)
+
+	if _, err := d.Get(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("Got %d spans, expected 1", len(tracer.spans))
+	}
+	if got := tracer.spans[0].attributes["num_keys"]; got != 1 {
+		t.Errorf("Got num_keys %v, expected 1", got)
+	}
+	if _, ok := tracer.spans[0].attributes["cache_hit"]; ok {
+		t.Errorf("Got attribute cache_hit for a datastore without cache")
+	}
+}
@@ -0,0 +1,80 @@
+package datastore
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// update is one result of Updater.Update().
+type update struct {
+	data map[string]json.RawMessage
+	err  error
+}
+
+// receiveUpdates calls Updater.Update() in a loop and sends the results to
+// d.updates. It runs until the datastore is closed.
+func (d *Datastore) receiveUpdates() {
+	for {
+		data, err := d.keychanger.Update()
+
+		select {
+		case d.updates <- update{data: data, err: err}:
+		case <-d.closed:
+			return
+		}
+	}
+}
+
+// nextUpdate blocks until there is an update from the Updater or a change by
+// publishLocal().
+func (d *Datastore) nextUpdate() (map[string]json.RawMessage, error) {
+	d.startReceive.Do(func() {
+		d.updates = make(chan update)
+		go d.receiveUpdates()
+	})
+
+	for {
+		select {
+		case u := <-d.updates:
+			return u.data, u.err
+
+		case <-d.localSignal:
+			if local := d.takeLocal(); len(local) > 0 {
+				return local, nil
+			}
+
+		case <-d.closed:
+			return nil, errors.New("datastore is closed")
+		}
+	}
+}
+
+// publishLocal sends values, that were changed in this process, to the
+// receivers of KeysChanged(), like an update from the Updater. It does not
+// block.
+func (d *Datastore) publishLocal(data map[string]json.RawMessage) {
+	d.localMu.Lock()
+	if d.local == nil {
+		d.local = make(map[string]json.RawMessage, len(data))
+	}
+	for k, v := range data {
+		d.local[k] = v
+	}
+	d.localMu.Unlock()
+
+	select {
+	case d.localSignal <- struct{}{}:
+	default:
+		// There is already a signal, that was not received.
+	}
+}
+
+// takeLocal returns and removes the values from publishLocal().
+func (d *Datastore) takeLocal() map[string]json.RawMessage {
+	d.localMu.Lock()
+	defer d.localMu.Unlock()
+
+	local := d.local
+	d.local = nil
+	return local
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+// batchFrame is one line from one of the subscriptions of a batch request. A
+// frame with a nil line means, that the subscription ended.
+type batchFrame struct {
+	index int
+	line  []byte
+}
+
+// batch handles requests to the batch url. The body has to be a list of key
+// request bodies.
+//
+// The response is a multipart/mixed stream. Each update of a subscription is
+// one part. The header X-Subscription is the index of the subscription in the
+// request body.
+func (h *Handler) batch(w http.ResponseWriter, r *http.Request) error {
+	uid, err := h.auth.Authenticate(r.Context(), r)
+	if err != nil {
+		return fmt.Errorf("authenticate request: %w", err)
+	}
+
+	s, ns, err := h.service(r)
+	if err != nil {
+		return err
+	}
+
+	var bodies []json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&bodies); err != nil {
+		return invalidRequestError{msg: fmt.Sprintf("Body has to be a list of key requests: %v", err)}
+	}
+
+	// Closing the readers stops the subscriptions and unblocks the
+	// readBatchFrames goroutines, when the handler returns.
+	readers := make([]io.ReadCloser, 0, len(bodies))
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+
+	for i, body := range bodies {
+		reader, err := s.SubscribeJSON(r.Context(), uid, body)
+		if err != nil {
+			return fmt.Errorf("subscription %d: %w", i, err)
+		}
+		readers = append(readers, reader)
+	}
+
+	frames := make(chan batchFrame)
+	for i, reader := range readers {
+		go readBatchFrames(i, reader, frames, r.Context().Done())
+	}
+
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+
+	for running := len(readers); running > 0; {
+		var f batchFrame
+		select {
+		case f = <-frames:
+		case <-r.Context().Done():
+			return nil
+		}
+
+		if f.line == nil {
+			running--
+			continue
+		}
+
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":   {"application/json"},
+			"X-Subscription": {strconv.Itoa(f.index)},
+		})
+		if err != nil {
+			return noStatusCodeError{fmt.Errorf("create part: %w", err)}
+		}
+
+		line, err := addNamespaceToLine(ns, f.line)
+		if err != nil {
+			return noStatusCodeError{fmt.Errorf("add namespace: %w", err)}
+		}
+
+		if _, err := part.Write(line); err != nil {
+			return noStatusCodeError{fmt.Errorf("write part: %w", err)}
+		}
+		w.(http.Flusher).Flush()
+	}
+
+	if err := mw.Close(); err != nil {
+		return noStatusCodeError{fmt.Errorf("close multipart writer: %w", err)}
+	}
+	return nil
+}
+
+// addNamespaceToLine adds the namespace to the keys of one json encoded
+// update.
+func addNamespaceToLine(ns key.Namespace, line []byte) ([]byte, error) {
+	if ns == "" {
+		return line, nil
+	}
+
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(line, &data); err != nil {
+		return nil, fmt.Errorf("decode update: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := NewStableJSONEncoder(buf).Encode(addNamespace(ns, data)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readBatchFrames sends each line of the reader to the frames channel. In the
+// end, a frame without a line is sent.
+func readBatchFrames(index int, r io.Reader, frames chan<- batchFrame, done <-chan struct{}) {
+	send := func(line []byte) bool {
+		select {
+		case frames <- batchFrame{index: index, line: line}:
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 && !send(line) {
+			return
+		}
+
+		if err != nil {
+			send(nil)
+			return
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package http_test
+
+import (
+	"bufio"
+	"context"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestBatch(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	body := `[
+		[{"ids":[1],"collection":"user","fields":{"name":null}}],
+		[{"ids":[2],"collection":"user","fields":{"name":null}}]
+	]`
+	req, err := http.NewRequestWithContext(ctx, "POST", srv.URL+"/system/autoupdate/batch", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Got content type %s (err: %v), expected multipart/mixed", resp.Header.Get("Content-Type"), err)
+	}
+
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	got := make(map[string]string)
+	for i := 0; i < 2; i++ {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("Can not read part %d: %v", i, err)
+		}
+
+		// The end of a part is only known, when the next part starts. So
+		// only the first line is read.
+		content, err := bufio.NewReader(part).ReadString('\n')
+		if err != nil {
+			t.Fatalf("Can not read content of part %d: %v", i, err)
+		}
+		got[part.Header.Get("X-Subscription")] = content
+	}
+
+	expect := map[string]string{
+		"0": "{\"user/1/name\":\"Hello World\"}\n",
+		"1": "{\"user/2/name\":\"Hello World\"}\n",
+	}
+	for sub, content := range expect {
+		if got[sub] != content {
+			t.Errorf("Subscription %s returned %q, expected %q", sub, got[sub], content)
+		}
+	}
+}
+
+func TestBatchInvalid(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	handler := ahttp.New(s, mockAuth{1}, 0)
+
+	for _, tt := range []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"get", "GET", `[]`, http.StatusMethodNotAllowed},
+		{"no list", "POST", `{}`, http.StatusBadRequest},
+		{"invalid key request", "POST", `[[{"ids":[1],"collection":"user","fields":{"name":null}}], [{"collection":"user"}]]`, http.StatusBadRequest},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/system/autoupdate/batch", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// IPBlocklistMiddleware rejects requests from clients with an ip in one of
+// the blocked ranges with the status 403. Requests, where the ip can not be
+// parsed, are also rejected.
+//
+// The blocked ranges can be replaced at runtime by sending them to the reload
+// channel. The channel can be nil. The background job stops, when the channel
+// is closed.
+func IPBlocklistMiddleware(blocked []net.IPNet, reload <-chan []net.IPNet) func(http.Handler) http.Handler {
+	var mu sync.RWMutex
+
+	if reload != nil {
+		go func() {
+			for nets := range reload {
+				mu.Lock()
+				blocked = nets
+				mu.Unlock()
+			}
+		}()
+	}
+
+	isBlocked := func(ip net.IP) bool {
+		mu.RLock()
+		defer mu.RUnlock()
+
+		for _, n := range blocked {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := realIP(r); ip == nil || isBlocked(ip) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package http_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestIPBlocklist(t *testing.T) {
+	reload := make(chan []net.IPNet)
+	defer close(reload)
+	handler := ahttp.IPBlocklistMiddleware(cidrs(t, "10.0.0.0/8"), reload)(okHandler)
+
+	for _, tt := range []struct {
+		name   string
+		ip     string
+		status int
+	}{
+		{"inside", "10.1.2.3", http.StatusForbidden},
+		{"outside", "192.168.1.1", http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requestFromIP(handler, tt.ip); got != tt.status {
+				t.Errorf("Got status %d, expected %d", got, tt.status)
+			}
+		})
+	}
+
+	t.Run("reload", func(t *testing.T) {
+		reload <- cidrs(t, "10.0.0.0/8", "192.168.0.0/16")
+
+		timeout := time.After(time.Second)
+		for requestFromIP(handler, "192.168.1.1") != http.StatusForbidden {
+			select {
+			case <-timeout:
+				t.Fatalf("Reloaded range is not blocked")
+			default:
+				time.Sleep(time.Millisecond)
+			}
+		}
+	})
+
+	t.Run("invalid ip", func(t *testing.T) {
+		if got := requestFromIP(handler, "not-an-ip"); got != http.StatusForbidden {
+			t.Errorf("Got status %d, expected %d", got, http.StatusForbidden)
+		}
+	})
+}
+
+func TestIPBlocklistForwarded(t *testing.T) {
+	blocklist := ahttp.IPBlocklistMiddleware(cidrs(t, "10.0.0.0/8"), nil)
+	handler := ahttp.TrustedProxyMiddleware(cidrs(t, "172.16.0.0/12"))(blocklist(okHandler))
+
+	for _, tt := range []struct {
+		name      string
+		remote    string
+		forwarded string
+		status    int
+	}{
+		{"trusted proxy", "172.16.0.1", "10.1.2.3", http.StatusForbidden},
+		{"trusted proxy chain", "172.16.0.1", "10.1.2.3, 172.16.0.2", http.StatusForbidden},
+		{"spoofed by client behind proxy", "172.16.0.1", "10.1.2.3, 192.168.1.1", http.StatusOK},
+		{"untrusted remote", "192.168.1.1", "10.1.2.3", http.StatusOK},
+		{"blocked remote", "10.1.2.3", "192.168.1.1", http.StatusForbidden},
+		{"invalid forwarded ip", "172.16.0.1", "unknown", http.StatusForbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote + ":12345"
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func requestFromIP(h http.Handler, ip string) int {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = ip + ":12345"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func cidrs(t *testing.T, ranges ...string) []net.IPNet {
+	nets := make([]net.IPNet, len(ranges))
+	for i, r := range ranges {
+		_, n, err := net.ParseCIDR(r)
+		if err != nil {
+			t.Fatalf("Invalid cidr %s: %v", r, err)
+		}
+		nets[i] = *n
+	}
+	return nets
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// RequestBodyCachingMiddleware reads the body of the request into a buffer.
+// The next handler gets a body that reads from this buffer. Middlewares that
+// are called later can get the buffer with CachedBody(), so they can read the
+// body without removing it for the handler.
+//
+// Bodies bigger then maxBytes are rejected with the status 413.
+func RequestBodyCachingMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+			if err != nil {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				fmt.Fprintf(w, `{"error": {"type": "InvalidRequestError", "msg": "%s"}}`, quote("can not read body: "+err.Error()))
+				return
+			}
+			r.Body.Close()
+
+			r = r.WithContext(context.WithValue(r.Context(), bodyKey, body))
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CachedBody returns the body of the request, that was read by
+// RequestBodyCachingMiddleware. The second return value is false, if the
+// middleware was not used.
+//
+// The returned slice must not be modified.
+func CachedBody(r *http.Request) ([]byte, bool) {
+	body, ok := r.Context().Value(bodyKey).([]byte)
+	return body, ok
+}
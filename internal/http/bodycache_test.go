@@ -0,0 +1,74 @@
+package http_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestRequestBodyCachingMiddleware(t *testing.T) {
+	body := `[{"ids":[1],"collection":"user","fields":{"name":null}}]`
+
+	var handlerBody string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Can not read body in handler: %v", err)
+		}
+		handlerBody = string(b)
+	})
+
+	// middleware reads the body before the handler.
+	var middlewareBody string
+	middleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, ok := ahttp.CachedBody(r)
+			if !ok {
+				t.Errorf("CachedBody() did not find a body")
+			}
+			middlewareBody = string(b)
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	h := ahttp.RequestBodyCachingMiddleware(1 << 20)(middleware(handler))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/system/autoupdate", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Got status %d, expected %d", rec.Code, http.StatusOK)
+	}
+	if middlewareBody != body {
+		t.Errorf("Middleware got body `%s`, expected `%s`", middlewareBody, body)
+	}
+	if handlerBody != body {
+		t.Errorf("Handler got body `%s`, expected `%s`", handlerBody, body)
+	}
+}
+
+func TestRequestBodyCachingMiddlewareTooBig(t *testing.T) {
+	called := false
+	h := ahttp.RequestBodyCachingMiddleware(5)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/system/autoupdate", strings.NewReader("more then five bytes")))
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Got status %d, expected %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	if called {
+		t.Errorf("Handler was called")
+	}
+}
+
+func TestCachedBodyWithoutMiddleware(t *testing.T) {
+	if _, ok := ahttp.CachedBody(httptest.NewRequest("GET", "/", nil)); ok {
+		t.Errorf("CachedBody() found a body without the middleware")
+	}
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// BodyHashMiddleware checks the body of a request against the base64 encoded
+// sha256 hash in the given header. If the hash does not match, the status 400
+// is returned.
+//
+// Requests without the header are passed to the next handler without a
+// check. The body is read completely before the next handler is called.
+func BodyHashMiddleware(header string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoded := r.Header.Get(header)
+			if encoded == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			expected, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil || len(expected) != sha256.Size {
+				writeBodyHashError(w, "header "+header+" is not a base64 encoded sha256 hash")
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				writeBodyHashError(w, "can not read body: "+err.Error())
+				return
+			}
+			r.Body.Close()
+
+			hash := sha256.Sum256(body)
+			if subtle.ConstantTimeCompare(hash[:], expected) != 1 {
+				writeBodyHashError(w, "body does not match the hash in header "+header)
+				return
+			}
+
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeBodyHashError(w http.ResponseWriter, msg string) {
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprintf(w, `{"error": {"type": "InvalidRequestError", "msg": "%s"}}`, quote(msg))
+}
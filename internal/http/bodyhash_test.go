@@ -0,0 +1,64 @@
+package http_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestBodyHashMiddleware(t *testing.T) {
+	var gotBody string
+	handler := ahttp.BodyHashMiddleware("X-Body-Hash")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Can not read body in next handler: %v", err)
+		}
+		gotBody = string(body)
+	}))
+
+	body := `[{"ids":[1],"collection":"user","fields":{"name":null}}]`
+	hash := sha256.Sum256([]byte(body))
+	otherHash := sha256.Sum256([]byte("other body"))
+
+	for _, tt := range []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"matching hash", base64.StdEncoding.EncodeToString(hash[:]), http.StatusOK},
+		{"mismatching hash", base64.StdEncoding.EncodeToString(otherHash[:]), http.StatusBadRequest},
+		{"invalid header", "not base64", http.StatusBadRequest},
+		{"no header", "", http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBody = ""
+			req := httptest.NewRequest("POST", "/system/autoupdate", strings.NewReader(body))
+			if tt.header != "" {
+				req.Header.Set("X-Body-Hash", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if tt.status != http.StatusOK {
+				if gotBody != "" {
+					t.Errorf("Next handler was called")
+				}
+				return
+			}
+
+			if gotBody != body {
+				t.Errorf("Next handler got body `%s`, expected `%s`", gotBody, body)
+			}
+		})
+	}
+}
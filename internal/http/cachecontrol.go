@@ -0,0 +1,38 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// StaticCacheControlMiddleware allows caches to store one-shot responses for
+// the duration maxAge.
+//
+// Only GET requests to the once url are one-shot. All other autoupdate urls
+// stream their responses and get no caching headers.
+//
+// Responses to requests with credentials (an authorization header or a cookie)
+// contain data for one user. Only the browser of the user is allowed to cache
+// them.
+func StaticCacheControlMiddleware(maxAge time.Duration) func(http.Handler) http.Handler {
+	seconds := int(maxAge.Seconds())
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && r.URL.Path == onceURL {
+				scope := "public"
+				if hasCredentials(r) {
+					scope = "private"
+				}
+				w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, seconds))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hasCredentials returns true, if the request could be authenticated.
+func hasCredentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || len(r.Cookies()) > 0
+}
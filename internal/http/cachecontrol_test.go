@@ -0,0 +1,41 @@
+package http_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestStaticCacheControl(t *testing.T) {
+	handler := ahttp.StaticCacheControlMiddleware(30 * time.Second)(okHandler)
+
+	for _, tt := range []struct {
+		name   string
+		method string
+		url    string
+		header map[string]string
+		expect string
+	}{
+		{"one-shot", "GET", "/system/autoupdate/once?user/1/name", nil, "public, max-age=30"},
+		{"one-shot with cookie", "GET", "/system/autoupdate/once?user/1/name", map[string]string{"Cookie": "session=abc"}, "private, max-age=30"},
+		{"one-shot with authorization", "GET", "/system/autoupdate/once?user/1/name", map[string]string{"Authorization": "Bearer abc"}, "private, max-age=30"},
+		{"streaming", "GET", "/system/autoupdate/keys?user/1/name", nil, ""},
+		{"streaming with connection close", "GET", "/system/autoupdate/keys?user/1/name", map[string]string{"Connection": "close"}, ""},
+		{"post", "POST", "/system/autoupdate/once?user/1/name", nil, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Cache-Control"); got != tt.expect {
+				t.Errorf("Got Cache-Control `%s`, expected `%s`", got, tt.expect)
+			}
+		})
+	}
+}
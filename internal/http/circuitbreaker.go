@@ -0,0 +1,24 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// CircuitBreakerMiddleware returns the status 503 for all requests, while the
+// circuit breaker is open. Without this middleware, each request would wait
+// until the call to the unavailable backend fails.
+func CircuitBreakerMiddleware(breaker CircuitBreaker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if breaker.Open() {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintf(w, `{"error": {"type": "CircuitOpen", "msg": "%s"}}`, quote("service temporarily unavailable"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+type mockBreaker struct {
+	open int32
+}
+
+func (b *mockBreaker) Open() bool {
+	return atomic.LoadInt32(&b.open) == 1
+}
+
+func (b *mockBreaker) set(open bool) {
+	var v int32
+	if open {
+		v = 1
+	}
+	atomic.StoreInt32(&b.open, v)
+}
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	breaker := new(mockBreaker)
+	handler := ahttp.CircuitBreakerMiddleware(breaker)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	t.Run("closed", func(t *testing.T) {
+		breaker.set(false)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Got status %d, expected 200", rec.Code)
+		}
+		if got := rec.Body.String(); got != "ok" {
+			t.Errorf("Got body `%s`, expected `ok`", got)
+		}
+	})
+
+	t.Run("open", func(t *testing.T) {
+		breaker.set(true)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate", nil))
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Got status %d, expected 503", rec.Code)
+		}
+
+		var body struct {
+			Error struct {
+				Type string `json:"type"`
+				Msg  string `json:"msg"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Got invalid json: %v", err)
+		}
+		if body.Error.Type != "CircuitOpen" || body.Error.Msg != "service temporarily unavailable" {
+			t.Errorf("Got error %v, expected CircuitOpen", body.Error)
+		}
+	})
+}
+
+func TestHandlerCircuitBreaker(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	breaker := new(mockBreaker)
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0, ahttp.WithCircuitBreaker(breaker)))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		open   bool
+		status int
+	}{
+		{false, http.StatusOK},
+		{true, http.StatusServiceUnavailable},
+		{false, http.StatusOK},
+	} {
+		breaker.set(tt.open)
+		resp, err := http.Get(srv.URL + "/system/autoupdate/once?user/1/name")
+		if err != nil {
+			t.Fatalf("Can not send request: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("Got status %d with open=%t, expected %d", resp.StatusCode, tt.open, tt.status)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORSPreflightHandler returns a handler that answers CORS preflight requests
+// for the autoupdate urls.
+//
+// An origin is allowed, if it is in the list of origins or if the list
+// contains "*". The allowed headers are sent to the client as
+// Access-Control-Allow-Headers.
+func CORSPreflightHandler(origins []string, allowedHeaders []string) http.Handler {
+	allowed := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		allowed[o] = true
+	}
+	headers := strings.Join(allowedHeaders, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if r.URL.Path != "/system/autoupdate" && r.URL.Path != simpleURL && r.URL.Path != onceURL {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if origin == "" || !(allowed[origin] || allowed["*"]) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		if headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,62 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	handler := ahttp.New(s, mockAuth{1}, 0, ahttp.WithCORSPreflight(
+		[]string{"https://example.com"},
+		[]string{"Content-Type", "Authorization"},
+	))
+
+	for _, tt := range []struct {
+		name   string
+		url    string
+		origin string
+		status int
+		allow  string
+	}{
+		{"allowed", "/system/autoupdate", "https://example.com", http.StatusNoContent, "https://example.com"},
+		{"allowed simple", "/system/autoupdate/keys", "https://example.com", http.StatusNoContent, "https://example.com"},
+		{"disallowed", "/system/autoupdate", "https://evil.com", http.StatusForbidden, ""},
+		{"no origin", "/system/autoupdate", "", http.StatusForbidden, ""},
+		{"unknown url", "/system/other", "https://example.com", http.StatusNotFound, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.url, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			req.Header.Set("Access-Control-Request-Method", "POST")
+			req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allow {
+				t.Errorf("Got Access-Control-Allow-Origin `%s`, expected `%s`", got, tt.allow)
+			}
+
+			if tt.allow == "" {
+				return
+			}
+
+			if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, Authorization" {
+				t.Errorf("Got Access-Control-Allow-Headers `%s`, expected `Content-Type, Authorization`", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DuplicateRequestFilterMiddleware lets identical requests share one
+// response. Clients sometimes send the same subscription twice, for example
+// on a reconnect.
+//
+// Two requests are identical, if they have the same method, url, body and
+// credentials (the authorization header and the cookies). The credentials
+// stand for the user id, so requests of different users never share a
+// response.
+//
+// The first request is handled as usual. A duplicate that comes within ttl
+// after the first request started gets the same response. It starts with the
+// data, that was already sent, and then gets the same updates as the first
+// request. If the first request has finished, the duplicate gets the whole
+// response at once.
+//
+// Only the last maxSharedBody bytes of a response are kept. When a long living
+// stream has sent more data, new duplicates are handled as new requests and
+// followers, that are already reading, only get the live tail. A follower
+// that falls behind the kept data is closed.
+func DuplicateRequestFilterMiddleware(ttl time.Duration) func(http.Handler) http.Handler {
+	var mu sync.Mutex
+	inflight := make(map[[sha256.Size]byte]*sharedResponse)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, ok := CachedBody(r)
+			if !ok {
+				var err error
+				body, err = ioutil.ReadAll(r.Body)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprintf(w, `{"error": {"type": "InvalidRequestError", "msg": "%s"}}`, quote("can not read body: "+err.Error()))
+					return
+				}
+				r.Body.Close()
+				r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			}
+
+			reqHash := requestHash(r, body)
+
+			mu.Lock()
+			shared, exists := inflight[reqHash]
+			if exists && !shared.join() {
+				exists = false
+			}
+			if !exists {
+				shared = newSharedResponse()
+				inflight[reqHash] = shared
+				time.AfterFunc(ttl, func() {
+					mu.Lock()
+					if inflight[reqHash] == shared {
+						delete(inflight, reqHash)
+					}
+					mu.Unlock()
+					shared.expire()
+				})
+			}
+			mu.Unlock()
+
+			if exists {
+				defer shared.leave()
+				shared.copyTo(w, r)
+				return
+			}
+
+			next.ServeHTTP(&sharingWriter{ResponseWriter: w, shared: shared}, r)
+			shared.finish()
+		})
+	}
+}
+
+// requestHash returns a hash of all parts of the request, that make it
+// unique.
+func requestHash(r *http.Request, body []byte) [sha256.Size]byte {
+	h := sha256.New()
+	for _, part := range []string{
+		r.Method,
+		r.URL.RequestURI(),
+		r.Header.Get("Authorization"),
+		r.Header.Get("Cookie"),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// maxSharedBody is the number of bytes, that are kept for the followers of a
+// request.
+const maxSharedBody = 1 << 20
+
+// sharedResponse records the response of a request for its duplicates.
+type sharedResponse struct {
+	mu     sync.Mutex
+	code   int
+	header http.Header
+	body   []byte
+	done   bool
+
+	// base is the offset of the first byte of body in the whole response. It
+	// is greater then 0, after old data was dropped.
+	base    int
+	expired bool
+
+	// followers is the number of duplicates, that read the response.
+	followers int
+
+	// changed is closed and replaced, when new data is recorded.
+	changed chan struct{}
+}
+
+func newSharedResponse() *sharedResponse {
+	return &sharedResponse{changed: make(chan struct{})}
+}
+
+// notify wakes up all followers.
+//
+// Has to be called with the lock.
+func (s *sharedResponse) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// recording returns true, if new data has to be saved. After the ttl, the
+// data is only needed for the followers, that are already reading.
+//
+// Has to be called with the lock.
+func (s *sharedResponse) recording() bool {
+	return !s.expired || s.followers > 0
+}
+
+func (s *sharedResponse) writeHeader(code int, header http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.code = code
+	s.header = header.Clone()
+	s.notify()
+}
+
+func (s *sharedResponse) write(p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.recording() {
+		return
+	}
+	s.body = append(s.body, p...)
+	if over := len(s.body) - maxSharedBody; over > 0 {
+		s.body = append(s.body[:0:0], s.body[over:]...)
+		s.base += over
+	}
+	s.notify()
+}
+
+func (s *sharedResponse) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.done = true
+	s.notify()
+}
+
+// expire is called after the ttl. The response can not be joined anymore.
+func (s *sharedResponse) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expired = true
+	if !s.recording() {
+		s.body = nil
+	}
+}
+
+// join adds a follower. It returns false, if the start of the response was
+// already dropped.
+func (s *sharedResponse) join() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.base > 0 {
+		return false
+	}
+	s.followers++
+	return true
+}
+
+// leave removes a follower.
+func (s *sharedResponse) leave() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.followers--
+	if !s.recording() {
+		s.body = nil
+	}
+}
+
+// copyTo writes the recorded response to w. It blocks until the response is
+// finished or the request is canceled. It also returns, if the follower was to
+// slow and the data it needs was already dropped.
+func (s *sharedResponse) copyTo(w http.ResponseWriter, r *http.Request) {
+	var offset int
+	var wroteHeader bool
+	for {
+		s.mu.Lock()
+		code := s.code
+		header := s.header
+		if offset < s.base {
+			s.mu.Unlock()
+			return
+		}
+		chunk := s.body[offset-s.base:]
+		done := s.done
+		changed := s.changed
+		s.mu.Unlock()
+
+		if code != 0 && !wroteHeader {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(code)
+			wroteHeader = true
+		}
+
+		if len(chunk) > 0 {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			offset += len(chunk)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+
+		if done {
+			s.mu.Lock()
+			finished := offset >= s.base+len(s.body)
+			s.mu.Unlock()
+			if finished {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// sharingWriter records the response of the first request.
+type sharingWriter struct {
+	http.ResponseWriter
+	shared      *sharedResponse
+	wroteHeader bool
+}
+
+func (w *sharingWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.shared.writeHeader(code, w.Header())
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sharingWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.shared.write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends the buffered data to the client.
+func (w *sharingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,186 @@
+package http_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestDuplicateRequestFilterMiddleware(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		secondAuth  string
+		expectCalls int
+	}{
+		{"identical requests", "token", 1},
+		{"other user", "other token", 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			datastore := test.NewMockDatastore()
+			defer datastore.Close()
+			datastore.SimulateLatency("user/1/name", 100*time.Millisecond)
+			s := autoupdate.New(datastore, new(test.MockRestricter))
+			defer s.Close()
+
+			started := make(chan struct{})
+			var once sync.Once
+			handler := ahttp.New(s, mockAuth{1}, 0, ahttp.WithDuplicateRequestFilter(time.Second))
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				once.Do(func() { close(started) })
+				handler.ServeHTTP(w, r)
+			}))
+			defer srv.Close()
+
+			get := func(auth string, body *string, wg *sync.WaitGroup) {
+				defer wg.Done()
+
+				req := mustRequest(http.NewRequest("GET", srv.URL+"/system/autoupdate/once?user/1/name", nil))
+				req.Header.Set("Authorization", auth)
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Errorf("Can not send request: %v", err)
+					return
+				}
+				defer resp.Body.Close()
+
+				data, err := ioutil.ReadAll(resp.Body)
+				if err != nil {
+					t.Errorf("Can not read body: %v", err)
+				}
+				*body = string(data)
+			}
+
+			var first, second string
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go get("token", &first, &wg)
+			<-started
+			go get(tt.secondAuth, &second, &wg)
+			wg.Wait()
+
+			if expect := `{"user/1/name":"Hello World"}` + "\n"; first != expect || second != expect {
+				t.Errorf("Got bodies `%s` and `%s`, expected `%s` for both", first, second, expect)
+			}
+
+			if got := datastore.Calls(); got != tt.expectCalls {
+				t.Errorf("Datastore was called %d times, expected %d", got, tt.expectCalls)
+			}
+		})
+	}
+}
+
+func TestDuplicateRequestFilterMiddlewareStream(t *testing.T) {
+	release := make(chan struct{})
+	var calls int
+	var mu sync.Mutex
+	handler := ahttp.DuplicateRequestFilterMiddleware(time.Second)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("first\n"))
+			w.(http.Flusher).Flush()
+			<-release
+			w.Write([]byte("second\n"))
+		}),
+	)
+
+	firstRec := httptest.NewRecorder()
+	firstDone := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(firstRec, httptest.NewRequest("POST", "/system/autoupdate", nil))
+		close(firstDone)
+	}()
+
+	// Wait until the first request has written its first line.
+	for i := 0; ; i++ {
+		mu.Lock()
+		c := calls
+		mu.Unlock()
+		if c > 0 {
+			break
+		}
+		if i > 1000 {
+			t.Fatalf("Handler was not called")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	secondRec := httptest.NewRecorder()
+	secondDone := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(secondRec, httptest.NewRequest("POST", "/system/autoupdate", nil))
+		close(secondDone)
+	}()
+
+	close(release)
+	<-firstDone
+	<-secondDone
+
+	if calls != 1 {
+		t.Errorf("Handler was called %d times, expected 1", calls)
+	}
+
+	if got := secondRec.Body.String(); got != "first\nsecond\n" {
+		t.Errorf("Duplicate got body `%s`, expected `first\\nsecond\\n`", got)
+	}
+
+	if got := secondRec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Duplicate got Content-Type `%s`, expected application/json", got)
+	}
+}
+
+func TestDuplicateRequestFilterMiddlewareLongStream(t *testing.T) {
+	release := make(chan struct{})
+	written := make(chan struct{}, 2)
+	var calls int
+	var mu sync.Mutex
+	handler := ahttp.DuplicateRequestFilterMiddleware(time.Second)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+
+			w.Write(bytes.Repeat([]byte("x"), 2<<20))
+			written <- struct{}{}
+			<-release
+		}),
+	)
+
+	firstDone := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/system/autoupdate", nil))
+		close(firstDone)
+	}()
+	<-written
+
+	secondDone := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/system/autoupdate", nil))
+		close(secondDone)
+	}()
+
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatalf("Duplicate of a long stream was not handled as a new request")
+	}
+
+	close(release)
+	<-firstDone
+	<-secondDone
+
+	if calls != 2 {
+		t.Errorf("Handler was called %d times, expected 2", calls)
+	}
+}
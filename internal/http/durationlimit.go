@@ -0,0 +1,27 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DurationLimitMiddleware sets a deadline on the context of each request. A
+// request can hold the resources of the server at most for the duration max.
+// This includes streaming requests, so clients have to reconnect afterwards.
+func DurationLimitMiddleware(max time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), max)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				log.Printf("Warning: Request %s %s reached the duration limit of %s", r.Method, r.URL.Path, max)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package http_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestDurationLimitMiddleware(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	sleep := time.Second
+	handler := ahttp.DurationLimitMiddleware(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(sleep):
+		case <-r.Context().Done():
+		}
+	}))
+
+	start := time.Now()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/system/autoupdate", nil))
+
+	if d := time.Since(start); d >= sleep {
+		t.Errorf("Request took %s, expected it to return before the handler finished sleeping", d)
+	}
+}
+
+func TestDurationLimitMiddlewareAutoupdate(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	// The keep alive is shorter then the duration limit, so the connection
+	// has to end although keep alive messages are sent.
+	handler := ahttp.DurationLimitMiddleware(50 * time.Millisecond)(ahttp.New(s, mockAuth{1}, time.Millisecond))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	// The client cancels the request after one second. If the handler ignores
+	// the duration limit, reading the body returns the context error.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/autoupdate/keys?user/1/name", nil)
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if _, err := ioutil.ReadAll(resp.Body); err != nil {
+		t.Errorf("Request did not end after the duration limit: %v", err)
+	}
+}
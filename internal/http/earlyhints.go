@@ -0,0 +1,21 @@
+package http
+
+import "net/http"
+
+// earlyHints sends a 103 Early Hints response with a prefetch link to the once
+// url of each key. The links are removed afterwards, so they are not part of
+// the final response.
+//
+// Informational responses are only supported since go 1.19. With older
+// versions, nothing is sent.
+func earlyHints(w http.ResponseWriter, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	for _, key := range keys {
+		w.Header().Add("Link", "<"+onceURL+"?"+key+">; rel=prefetch")
+	}
+	sendEarlyHints(w)
+	w.Header().Del("Link")
+}
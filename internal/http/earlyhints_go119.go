@@ -0,0 +1,11 @@
+//go:build go1.19
+// +build go1.19
+
+package http
+
+import "net/http"
+
+// sendEarlyHints sends the current headers with the status 103.
+func sendEarlyHints(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusEarlyHints)
+}
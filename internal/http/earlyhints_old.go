@@ -0,0 +1,10 @@
+//go:build !go1.19
+// +build !go1.19
+
+package http
+
+import "net/http"
+
+// sendEarlyHints does nothing, since the http server does not support
+// informational responses before go 1.19.
+func sendEarlyHints(w http.ResponseWriter) {}
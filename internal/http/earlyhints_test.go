@@ -0,0 +1,93 @@
+//go:build go1.19
+// +build go1.19
+
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"sync"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestEarlyHints(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0, ahttp.WithEarlyHints(true)))
+	defer srv.Close()
+
+	body, err := new(autoupdate.KeyRequestBuilder).
+		AddCollection("user", []int{1}).
+		AddField("name").
+		AddRelation("group_ids", autoupdate.RelationList("group", map[string]*autoupdate.FieldDescription{"name": nil})).
+		Build()
+	if err != nil {
+		t.Fatalf("Can not build key request: %v", err)
+	}
+
+	var mu sync.Mutex
+	var links []string
+	var codes []int
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			mu.Lock()
+			defer mu.Unlock()
+			codes = append(codes, code)
+			links = append(links, header["Link"]...)
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, srv.URL+"/system/autoupdate", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Got status %s, expected 200", resp.Status)
+	}
+
+	var data map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		t.Fatalf("Got invalid json: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(codes) != 1 || codes[0] != http.StatusEarlyHints {
+		t.Errorf("Got informational responses %v, expected [103]", codes)
+	}
+
+	// Only the keys found by the relation are hinted. They point to the once
+	// url, which is finite.
+	expect := keys(
+		"</system/autoupdate/once?group/1/name>; rel=prefetch",
+		"</system/autoupdate/once?group/2/name>; rel=prefetch",
+	)
+	if !cmpSlice(links, expect) {
+		t.Errorf("Got links %v, expected %v", links, expect)
+	}
+
+	if got := resp.Header.Values("Link"); len(got) != 0 {
+		t.Errorf("Final response has the link headers %v, expected none", got)
+	}
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// StableJSONEncoder writes update frames to a writer. In contrast to the
+// default encoding, the keys of each frame are sorted alphabetically.
+//
+// Has to be created with NewStableJSONEncoder().
+type StableJSONEncoder struct {
+	w io.Writer
+}
+
+// NewStableJSONEncoder creates a StableJSONEncoder that writes to w.
+func NewStableJSONEncoder(w io.Writer) *StableJSONEncoder {
+	return &StableJSONEncoder{w: w}
+}
+
+// Encode writes the data as one line to the writer. Empty values are written
+// as null.
+func (e *StableJSONEncoder) Encode(data map[string]json.RawMessage) error {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('"')
+		buf.WriteString(key)
+		buf.WriteString(`":`)
+		if len(data[key]) == 0 {
+			buf.WriteString("null")
+			continue
+		}
+		buf.Write(data[key])
+	}
+	buf.WriteString("}\n")
+
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,50 @@
+package http_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestStableJSONEncoder(t *testing.T) {
+	data := map[string]json.RawMessage{
+		"user/2/name":  []byte(`"hugo"`),
+		"motion/1/id":  []byte(`1`),
+		"user/1/name":  []byte(`"emma"`),
+		"agenda/5/ids": []byte(`[1,2]`),
+	}
+
+	buf := new(bytes.Buffer)
+	if err := ahttp.NewStableJSONEncoder(buf).Encode(data); err != nil {
+		t.Fatalf("Encode returned an unexpected error: %v", err)
+	}
+
+	expect := `{"agenda/5/ids":[1,2],"motion/1/id":1,"user/1/name":"emma","user/2/name":"hugo"}` + "\n"
+	if got := buf.String(); got != expect {
+		t.Errorf("Got `%s`, expected `%s`", got, expect)
+	}
+}
+
+func TestStableJSONEncoderNull(t *testing.T) {
+	data := map[string]json.RawMessage{
+		"user/1/name":  nil,
+		"user/1/email": []byte{},
+		"user/1/id":    []byte(`1`),
+	}
+
+	buf := new(bytes.Buffer)
+	if err := ahttp.NewStableJSONEncoder(buf).Encode(data); err != nil {
+		t.Fatalf("Encode returned an unexpected error: %v", err)
+	}
+
+	expect := `{"user/1/email":null,"user/1/id":1,"user/1/name":null}` + "\n"
+	if got := buf.String(); got != expect {
+		t.Errorf("Got `%s`, expected `%s`", got, expect)
+	}
+
+	if !json.Valid(buf.Bytes()) {
+		t.Errorf("Encoded data is not valid json")
+	}
+}
@@ -1,5 +1,10 @@
 package http
 
+import (
+	"fmt"
+	"net/http"
+)
+
 // noStatusCodeError helps the errorHandler do decide, if an status code can be
 // set.
 type noStatusCodeError struct {
@@ -9,3 +14,68 @@ type noStatusCodeError struct {
 func (e noStatusCodeError) Error() string {
 	return e.wrapped.Error()
 }
+
+// invalidRequestError is returned, when the body of a request can not be
+// parsed.
+type invalidRequestError struct {
+	msg string
+}
+
+func (e invalidRequestError) Error() string {
+	return e.msg
+}
+
+// Type returns the name of the error.
+func (e invalidRequestError) Type() string {
+	return "InvalidRequestError"
+}
+
+// unknownNamespaceError is returned, when a request uses a namespace, that is
+// not configured.
+type unknownNamespaceError struct {
+	namespace string
+}
+
+func (e unknownNamespaceError) Error() string {
+	return fmt.Sprintf("unknown namespace %s", e.namespace)
+}
+
+// Type returns the name of the error.
+func (e unknownNamespaceError) Type() string {
+	return "NamespaceError"
+}
+
+// forbiddenError is returned, when the user is not allowed to use an url.
+type forbiddenError struct{}
+
+func (e forbiddenError) Error() string {
+	return "you are not allowed to use this url"
+}
+
+// Type returns the name of the error.
+func (e forbiddenError) Type() string {
+	return "ForbiddenError"
+}
+
+// StatusCode returns the http status code for the error.
+func (e forbiddenError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// unknownSubscriptionError is returned, when a pong is sent for a
+// subscription, that does not exist.
+type unknownSubscriptionError struct{}
+
+func (e unknownSubscriptionError) Error() string {
+	return "unknown subscription"
+}
+
+// Type returns the name of the error.
+func (e unknownSubscriptionError) Type() string {
+	return "UnknownSubscriptionError"
+}
+
+// StatusCode returns the http status code for the error.
+func (e unknownSubscriptionError) StatusCode() int {
+	return http.StatusNotFound
+}
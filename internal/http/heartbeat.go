@@ -0,0 +1,183 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// pongURL is the url, where clients answer the pings of a subscription.
+const pongURL = "/system/autoupdate/pong"
+
+// subscriptionTokenHeader is the header, that contains the token of a
+// subscription.
+const subscriptionTokenHeader = "X-Subscription-Token"
+
+// HeartbeatChecker finds subscriptions, where the client is gone but the tcp
+// connection is still open.
+//
+// The handler sends `{"ping":N}` frames in the stream of each subscription.
+// The client has to answer each ping with a request to the pong url with the
+// body `{"pong":N}`. If the client misses two pongs, the subscription is
+// closed.
+//
+// Has to be created with NewHeartbeatChecker().
+type HeartbeatChecker struct {
+	mu   sync.Mutex
+	subs map[string]*heartbeat
+}
+
+// heartbeat is the state of one subscription.
+type heartbeat struct {
+	lastPing int
+	lastPong int
+}
+
+// NewHeartbeatChecker creates a HeartbeatChecker.
+func NewHeartbeatChecker() *HeartbeatChecker {
+	return &HeartbeatChecker{subs: make(map[string]*heartbeat)}
+}
+
+// Add starts checking the subscription with the token.
+func (c *HeartbeatChecker) Add(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subs[token] = new(heartbeat)
+}
+
+// Remove stops checking the subscription.
+func (c *HeartbeatChecker) Remove(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.subs, token)
+}
+
+// Ping returns the number of the next ping for the subscription. The second
+// return value is false, if the client has missed two pongs. In this case,
+// the subscription should be closed.
+func (c *HeartbeatChecker) Ping(token string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hb, ok := c.subs[token]
+	if !ok {
+		return 0, false
+	}
+
+	if hb.lastPing-hb.lastPong >= 2 {
+		return 0, false
+	}
+
+	hb.lastPing++
+	return hb.lastPing, true
+}
+
+// Pong saves the answer of the client to the ping n.
+func (c *HeartbeatChecker) Pong(token string, n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hb, ok := c.subs[token]
+	if !ok {
+		return unknownSubscriptionError{}
+	}
+
+	if n > hb.lastPing {
+		return invalidRequestError{msg: fmt.Sprintf("ping %d was not sent", n)}
+	}
+
+	if n > hb.lastPong {
+		hb.lastPong = n
+	}
+	return nil
+}
+
+// startHeartbeat sends pings to the client until the context is done. If the
+// client misses two pongs, a close frame is sent and cancel is called.
+func startHeartbeat(ctx context.Context, cancel context.CancelFunc, checker *HeartbeatChecker, token string, interval time.Duration, w *frameWriter) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, alive := checker.Ping(token)
+		if !alive {
+			w.writeFrame([]byte(`{"close":"heartbeat"}` + "\n"))
+			cancel()
+			return
+		}
+
+		w.writeFrame([]byte(fmt.Sprintf(`{"ping":%d}`+"\n", n)))
+	}
+}
+
+// pong handles the answers of the clients to the pings.
+func (h *Handler) pong(w http.ResponseWriter, r *http.Request) error {
+	if h.heartbeat == nil {
+		return unknownSubscriptionError{}
+	}
+
+	var body struct {
+		Pong int `json:"pong"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return invalidRequestError{msg: fmt.Sprintf("invalid body: %v", err)}
+	}
+
+	return h.heartbeat.Pong(r.Header.Get(subscriptionTokenHeader), body.Pong)
+}
+
+// frameWriter buffers the writes of a frame until Flush is called. Then the
+// frame is written at once, so frames from different goroutines do not mix.
+type frameWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+
+	mu  sync.Mutex
+	err error
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	err := w.err
+	w.mu.Unlock()
+
+	if err != nil {
+		return 0, err
+	}
+	return w.buf.Write(p)
+}
+
+// Flush writes the buffered frame.
+func (w *frameWriter) Flush() {
+	w.writeFrame(w.buf.Bytes())
+	w.buf.Reset()
+}
+
+// writeFrame writes and flushes the frame.
+func (w *frameWriter) writeFrame(frame []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return
+	}
+
+	if _, err := w.w.Write(frame); err != nil {
+		w.err = err
+		return
+	}
+	w.w.(http.Flusher).Flush()
+}
@@ -0,0 +1,152 @@
+package http_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestHeartbeatChecker(t *testing.T) {
+	c := ahttp.NewHeartbeatChecker()
+	c.Add("token")
+
+	n, alive := c.Ping("token")
+	if !alive || n != 1 {
+		t.Fatalf("First Ping() returned %d, %t, expected 1, true", n, alive)
+	}
+	if err := c.Pong("token", 1); err != nil {
+		t.Fatalf("Pong() returned an unexpected error: %v", err)
+	}
+
+	// The second and third pong are missed.
+	for _, expect := range []int{2, 3} {
+		n, alive := c.Ping("token")
+		if !alive || n != expect {
+			t.Fatalf("Ping() returned %d, %t, expected %d, true", n, alive, expect)
+		}
+	}
+
+	if _, alive := c.Ping("token"); alive {
+		t.Errorf("Ping() after two missed pongs returned alive")
+	}
+
+	if err := c.Pong("unknown", 1); err == nil {
+		t.Errorf("Pong() for an unknown token did not return an error")
+	}
+}
+
+func TestHandlerHeartbeat(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	// The interval has to be long enough for the first pong to arrive before
+	// the third ping.
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0, ahttp.WithHeartbeat(100*time.Millisecond)))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/system/autoupdate/keys?user/1/name", nil)
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	token := resp.Header.Get("X-Subscription-Token")
+	if token == "" {
+		t.Fatalf("Response has no subscription token")
+	}
+
+	// pong answers a ping like a client.
+	pong := func(n int) {
+		req, err := http.NewRequest("POST", srv.URL+"/system/autoupdate/pong", strings.NewReader(fmt.Sprintf(`{"pong":%d}`, n)))
+		if err != nil {
+			t.Fatalf("Can not create pong request: %v", err)
+		}
+		req.Header.Set("X-Subscription-Token", token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Can not send pong: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Pong returned status %d", resp.StatusCode)
+		}
+	}
+
+	var pings []int
+	var closeReason string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var frame struct {
+			Ping  int    `json:"ping"`
+			Close string `json:"close"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			t.Fatalf("Got invalid frame `%s`: %v", scanner.Bytes(), err)
+		}
+
+		if frame.Close != "" {
+			closeReason = frame.Close
+			continue
+		}
+
+		if frame.Ping == 0 {
+			// Data frame.
+			continue
+		}
+
+		pings = append(pings, frame.Ping)
+		if frame.Ping == 1 {
+			// Only the first ping is answered.
+			pong(frame.Ping)
+		}
+	}
+
+	if ctx.Err() != nil {
+		t.Fatalf("Subscription was not closed")
+	}
+
+	if closeReason != "heartbeat" {
+		t.Errorf("Got close reason `%s`, expected `heartbeat`", closeReason)
+	}
+
+	if len(pings) != 3 {
+		t.Errorf("Got pings %v, expected [1 2 3]", pings)
+	}
+}
+
+func TestHandlerPongUnknownSubscription(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	handler := ahttp.New(s, mockAuth{1}, 0, ahttp.WithHeartbeat(time.Second))
+
+	req := httptest.NewRequest("POST", "/system/autoupdate/pong", strings.NewReader(`{"pong":1}`))
+	req.Header.Set("X-Subscription-Token", "unknown")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Got status %d, expected %d", rec.Code, http.StatusNotFound)
+	}
+}
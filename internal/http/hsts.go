@@ -0,0 +1,25 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HSTSMiddleware sets the Strict-Transport-Security header on each response.
+// Browsers then only use https for the host for the duration maxAge.
+//
+// It should only be used, when the service is reachable with tls.
+func HSTSMiddleware(maxAge time.Duration, includeSubdomains bool) func(http.Handler) http.Handler {
+	value := fmt.Sprintf("max-age=%d", int(maxAge.Seconds()))
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Strict-Transport-Security", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
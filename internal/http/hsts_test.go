@@ -0,0 +1,30 @@
+package http_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestHSTSMiddleware(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		includeSubdomains bool
+		expect            string
+	}{
+		{"host only", false, "max-age=31536000"},
+		{"with subdomains", true, "max-age=31536000; includeSubDomains"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ahttp.HSTSMiddleware(365*24*time.Hour, tt.includeSubdomains)(okHandler)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate", nil))
+
+			if got := rec.Header().Get("Strict-Transport-Security"); got != tt.expect {
+				t.Errorf("Got Strict-Transport-Security `%s`, expected `%s`", got, tt.expect)
+			}
+		})
+	}
+}
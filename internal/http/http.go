@@ -2,6 +2,7 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -13,53 +14,158 @@ import (
 	"time"
 
 	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
 	"github.com/openslides/openslides-autoupdate-service/internal/keysbuilder"
 )
 
+const simpleURL = "/system/autoupdate/keys"
+
+// namespaceHeader is the header, that sets the namespace of the keys.
+const namespaceHeader = "X-Autoupdate-Namespace"
+
 // Handler is an http handler for the autoupdate service.
 type Handler struct {
-	s         *autoupdate.Autoupdate
-	mux       *http.ServeMux
-	auth      Authenticator
-	keepAlive time.Duration
+	s          autoupdate.Service
+	namespaces map[key.Namespace]autoupdate.Service
+	router     *Router
+	auth       Authenticator
+	keepAlive  time.Duration
+
+	writeTimeout time.Duration
+	http2Push    bool
+	earlyHints   bool
+
+	stableKeyOrder bool
+	version        bool
+	tracer         Tracer
+	ttfb           TTFBObserver
+
+	corsPreflight http.Handler
+	debug         bool
+
+	admins      AdminChecker
+	schema      *key.Schema
+	maxKeyRange int
+
+	heartbeat         *HeartbeatChecker
+	heartbeatInterval time.Duration
 }
 
 // New create a new Handler with the correct urls.
-func New(s *autoupdate.Autoupdate, auth Authenticator, keepAlive time.Duration) *Handler {
+func New(s autoupdate.Service, auth Authenticator, keepAlive time.Duration, options ...Option) *Handler {
 	h := &Handler{
 		s:         s,
-		mux:       http.NewServeMux(),
+		router:    NewRouter(),
 		auth:      auth,
 		keepAlive: keepAlive,
+		tracer:    noopTracer{},
+
+		maxKeyRange: key.DefaultMaxRange,
+	}
+	for _, o := range options {
+		o(h)
 	}
-	h.mux.Handle("/system/autoupdate", h.autoupdate(h.complex))
-	h.mux.Handle("/system/autoupdate/keys", h.autoupdate(h.simple))
+
+	complexHandler := h.autoupdate(h.complex)
+	h.router.Handle(http.MethodGet, "/system/autoupdate", complexHandler)
+	h.router.Handle(http.MethodPost, "/system/autoupdate", complexHandler)
+	h.router.Handle(http.MethodGet, simpleURL, h.autoupdate(h.simple), QueryParamValidationMiddleware())
+	h.router.Handle(http.MethodGet, onceURL, errHandleFunc(h.once))
+	h.router.Handle(http.MethodGet, "/system/autoupdate/version", http.HandlerFunc(VersionHandler))
+	h.router.Handle(http.MethodGet, "/system/autoupdate/subscriptions", errHandleFunc(h.subscriptionStats))
+	h.router.Handle(http.MethodPost, "/system/autoupdate/batch", errHandleFunc(h.batch))
+	h.router.Handle(http.MethodPost, pongURL, errHandleFunc(h.pong))
 	return h
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h.mux.ServeHTTP(w, r)
+	defer recoverPanic(w, h.debug)
+
+	if conn := connFromContext(r.Context()); h.writeTimeout > 0 && conn != nil {
+		w = &timeoutWriter{ResponseWriter: w, conn: conn, timeout: h.writeTimeout}
+	}
+
+	if h.corsPreflight != nil && r.Method == http.MethodOptions {
+		h.corsPreflight.ServeHTTP(w, r)
+		return
+	}
+	h.router.ServeHTTP(w, r)
+}
+
+// service returns the autoupdate service for the namespace of the request. The
+// namespace is set with the header X-Autoupdate-Namespace. Without the header,
+// the default service is returned.
+func (h *Handler) service(r *http.Request) (autoupdate.Service, key.Namespace, error) {
+	ns := key.Namespace(r.Header.Get(namespaceHeader))
+	if ns == "" {
+		return h.s, "", nil
+	}
+
+	s, ok := h.namespaces[ns]
+	if !ok {
+		return nil, "", unknownNamespaceError{namespace: string(ns)}
+	}
+	return s, ns, nil
 }
 
 // autoupdate creates a Handler for a specific Keysbuilder.
-func (h *Handler) autoupdate(kbg func(*http.Request, int) (autoupdate.KeysBuilder, error)) errHandleFunc {
+func (h *Handler) autoupdate(kbg func(*http.Request, autoupdate.Service, int) (autoupdate.KeysBuilder, error)) errHandleFunc {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		w.Header().Set("Content-Type", "application/octet-stream")
 
+		// The span measures the time until the first data is sent to the
+		// client.
+		start := time.Now()
+		ctx, span := h.tracer.Start(r.Context(), "subscription.ttfb")
+		r = r.WithContext(ctx)
+		spanEnded := false
+		endSpan := func() {
+			if !spanEnded {
+				spanEnded = true
+				span.End()
+			}
+		}
+		defer endSpan()
+
 		uid, err := h.auth.Authenticate(r.Context(), r)
 		if err != nil {
 			return fmt.Errorf("authenticate request: %w", err)
 		}
 
+		r, err = h.withWriteVersion(r)
+		if err != nil {
+			return err
+		}
+
+		s, ns, err := h.service(r)
+		if err != nil {
+			return err
+		}
+
 		// Save tid before the keybuilder is generated. If the datastore gets an
 		// update, the update can be handeled.
-		tid := h.s.LastID()
+		tid := s.LastID()
 
-		kb, err := kbg(r, uid)
+		kb, err := kbg(r, s, uid)
 		if err != nil {
 			return fmt.Errorf("build keysbuilder: %w", err)
 		}
 
+		if err := h.validateSchema(kb.Keys()); err != nil {
+			return err
+		}
+		setResponseInfo(r.Context(), uid, len(kb.Keys()))
+
+		if h.http2Push && r.URL.Path != simpleURL {
+			push(w, kb.Keys())
+		}
+
+		if h.earlyHints && r.URL.Path != simpleURL {
+			if related, ok := kb.(interface{ RelatedKeys() []string }); ok {
+				earlyHints(w, related.RelatedKeys())
+			}
+		}
+
 		defer func() {
 			// After this line, it is not allowed for the handler to set a
 			// status error.
@@ -68,20 +174,104 @@ func (h *Handler) autoupdate(kbg func(*http.Request, int) (autoupdate.KeysBuilde
 			}
 		}()
 
-		connection := h.s.Connect(uid, kb, tid)
+		connection := s.Connect(uid, kb, tid)
+		subscription := &autoupdate.Subscription{UserID: uid, Connection: connection}
+		registered := s.Subscribers().Register(subscription)
+		defer s.Subscribers().Deregister(registered)
+		token := string(registered)
+		var sw io.Writer = connection.StatsWriter(w)
+
+		var version *frameVersion
+		if h.version {
+			version = &frameVersion{subscription: subscription}
+			if old := r.Header.Get(subscriptionTokenHeader); old != "" {
+				if last, ok := s.Subscribers().LastVersion(autoupdate.Token(old), uid); ok {
+					version.last = &last
+				}
+			}
+			w.Header().Set(subscriptionTokenHeader, token)
+		}
+
+		ctx = r.Context()
+		if h.heartbeat != nil {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(ctx)
+
+			w.Header().Set(subscriptionTokenHeader, token)
+			h.heartbeat.Add(token)
+			defer h.heartbeat.Remove(token)
+
+			fw := &frameWriter{w: sw}
+			sw = fw
 
-		for {
-			if err := autoupdateLoop(r.Context(), h.keepAlive, w, connection); err != nil {
+			heartbeatDone := make(chan struct{})
+			go func() {
+				defer close(heartbeatDone)
+				startHeartbeat(ctx, cancel, h.heartbeat, token, h.heartbeatInterval, fw)
+			}()
+
+			// The heartbeat must not write after the handler returned.
+			defer func() {
+				cancel()
+				<-heartbeatDone
+			}()
+		}
+
+		for first := true; ; first = false {
+			if err := autoupdateLoop(ctx, h.keepAlive, h.stableKeyOrder, version, ns, sw, connection); err != nil {
 				return err
 			}
+
+			if first {
+				endSpan()
+				if h.ttfb != nil {
+					h.ttfb.ObserveTTFB(time.Since(start))
+				}
+			}
 		}
 	}
 }
 
-func autoupdateLoop(ctx context.Context, timeout time.Duration, w io.Writer, connection *autoupdate.Connection) error {
+// frameVersion wraps each frame into an envelope with the version of the data.
+type frameVersion struct {
+	subscription *autoupdate.Subscription
+
+	// last is the version of the subscription, that the client reconnects
+	// from. It is only sent with the first frame.
+	last *uint64
+}
+
+// write writes the frame with the version envelope to w and saves the version
+// in the subscription.
+func (v *frameVersion) write(w io.Writer, frame []byte, version uint64) error {
+	var last string
+	if v.last != nil {
+		last = fmt.Sprintf(`"last_version":%d,`, *v.last)
+		v.last = nil
+	}
+
+	if _, err := fmt.Fprintf(w, `{"version":%d,%s"data":%s}`+"\n", version, last, bytes.TrimSuffix(frame, []byte("\n"))); err != nil {
+		return err
+	}
+	w.(http.Flusher).Flush()
+
+	v.subscription.SetVersion(version)
+	return nil
+}
+
+// frameBuffer collects one frame, so it can be wrapped in an envelope.
+type frameBuffer struct {
+	bytes.Buffer
+}
+
+// Flush does nothing. The frame is flushed, after it is written to the client.
+func (*frameBuffer) Flush() {}
+
+func autoupdateLoop(reqCtx context.Context, timeout time.Duration, stable bool, version *frameVersion, ns key.Namespace, w io.Writer, connection *autoupdate.Connection) error {
+	ctx := reqCtx
 	if timeout > 0 {
 		var cancel func()
-		ctx, cancel = context.WithTimeout(ctx, timeout)
+		ctx, cancel = context.WithTimeout(reqCtx, timeout)
 		defer cancel()
 	}
 
@@ -90,31 +280,106 @@ func autoupdateLoop(ctx context.Context, timeout time.Duration, w io.Writer, con
 	data, err := connection.Next(ctx)
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
+			// Only the keep alive timeout sends a keep alive. If the request
+			// itself has a deadline, for example from the
+			// DurationLimitMiddleware, the connection ends.
+			if err := reqCtx.Err(); err != nil {
+				return err
+			}
+
 			if err := sendKeepAlive(w); err != nil {
 				return err
 			}
 			return nil
 		}
+
+		var closer interface {
+			CloseFrame() map[string]string
+		}
+		if errors.As(err, &closer) {
+			// The error closes the connection. There is nothing to do, if
+			// the message can not be sent.
+			json.NewEncoder(w).Encode(closer.CloseFrame())
+			w.(http.Flusher).Flush()
+		}
 		return err
 	}
 
+	data = addNamespace(ns, data)
+
+	if version != nil {
+		frame := new(frameBuffer)
+		if err := writeData(frame, stable, data); err != nil {
+			return err
+		}
+		return version.write(w, frame.Bytes(), connection.Version())
+	}
+
+	return writeData(w, stable, data)
+}
+
+// writeData writes the data as one frame to w. If stable is true, the keys are
+// sorted.
+func writeData(w io.Writer, stable bool, data map[string]json.RawMessage) error {
+	if stable {
+		if err := NewStableJSONEncoder(w).Encode(data); err != nil {
+			return err
+		}
+		w.(http.Flusher).Flush()
+		return nil
+	}
+
 	if err := sendData(w, data); err != nil {
 		return err
 	}
 	return nil
 }
 
+// push uses http2 server push to send the simple url for the given keys to the
+// client. Nothing happens, if the ResponseWriter does not support server push.
+func push(w http.ResponseWriter, keys []string) {
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return
+	}
+
+	// Pushing is only an optimization. If it fails, the client can still
+	// request the url.
+	if err := pusher.Push(simpleURL+"?"+strings.Join(keys, ","), nil); err != nil && err != http.ErrNotSupported {
+		log.Printf("Can not push keys: %v", err)
+	}
+}
+
 // complex builds a keysbuilder from the body of a request. The body has to be
 // in the format specified in the keysbuilder package.
-func (h *Handler) complex(r *http.Request, uid int) (autoupdate.KeysBuilder, error) {
+func (h *Handler) complex(r *http.Request, s autoupdate.Service, uid int) (autoupdate.KeysBuilder, error) {
 	defer r.Body.Close()
-	return keysbuilder.ManyFromJSON(r.Context(), r.Body, h.s, uid)
+	return keysbuilder.ManyFromJSON(r.Context(), r.Body, s, uid)
 }
 
 // simple builds a keysbuilder from the url query. It expects a comma separated
 // list of keysname.
-func (h *Handler) simple(r *http.Request, uid int) (autoupdate.KeysBuilder, error) {
-	keys := strings.Split(r.URL.RawQuery, ",")
+//
+// If the request has the header X-Autoupdate-Namespace, all keys need the
+// namespace as prefix. The prefix is removed.
+func (h *Handler) simple(r *http.Request, s autoupdate.Service, uid int) (autoupdate.KeysBuilder, error) {
+	// Id ranges like user/[1-100]/name are expanded before the namespace is
+	// removed, because only single keys can be parsed.
+	keys, err := key.ExpandRanges(strings.Split(r.URL.RawQuery, ","), h.maxKeyRange)
+	if err != nil {
+		return nil, err
+	}
+
+	if ns := key.Namespace(r.Header.Get(namespaceHeader)); ns != "" {
+		for i, k := range keys {
+			stripped, err := ns.Strip(k)
+			if err != nil {
+				return nil, fmt.Errorf("remove namespace: %w", err)
+			}
+			keys[i] = stripped
+		}
+	}
+
 	kb := &keysbuilder.Simple{K: keys}
 	if err := kb.Validate(); err != nil {
 		return nil, err
@@ -122,6 +387,47 @@ func (h *Handler) simple(r *http.Request, uid int) (autoupdate.KeysBuilder, erro
 	return kb, nil
 }
 
+// addNamespace adds the namespace as prefix to all keys of the data. Entries,
+// that are no keys, like the close message, are not changed.
+func addNamespace(ns key.Namespace, data map[string]json.RawMessage) map[string]json.RawMessage {
+	if ns == "" {
+		return data
+	}
+
+	prefixed := make(map[string]json.RawMessage, len(data))
+	for k, v := range data {
+		if _, err := key.Parse(k); err == nil {
+			k = ns.Add(k)
+		}
+		prefixed[k] = v
+	}
+	return prefixed
+}
+
+// validateSchema checks the keys against the schema. Nothing happens, if the
+// handler has no schema.
+func (h *Handler) validateSchema(keys []string) error {
+	if h.schema == nil {
+		return nil
+	}
+	return h.schema.ValidateKeys(keys...)
+}
+
+// withWriteVersion adds the write version from the auth token to the context
+// of the request, if the Authenticator implements WriteVersioner.
+func (h *Handler) withWriteVersion(r *http.Request) (*http.Request, error) {
+	versioner, ok := h.auth.(WriteVersioner)
+	if !ok {
+		return r, nil
+	}
+
+	version, err := versioner.WriteVersion(r)
+	if err != nil {
+		return nil, fmt.Errorf("get write version: %w", err)
+	}
+	return r.WithContext(autoupdate.WithWriteVersion(r.Context(), version)), nil
+}
+
 // errHandleFunc is like a http.Handler, but has a error as return value.
 //
 // If the returned error implements the DefinedError interface, then the error
@@ -140,15 +446,22 @@ func (f errHandleFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		var closing interface {
 			Closing()
 		}
-		if errors.As(err, &closing) || errors.Is(err, context.Canceled) {
-			// Shutdown or connection closed.
+		if errors.As(err, &closing) || errors.Is(err, context.Canceled) || r.Context().Err() != nil {
+			// Shutdown, connection closed or request deadline reached.
 			return
 		}
 
 		var derr DefinedError
 		if errors.As(err, &derr) {
 			if status {
-				w.WriteHeader(http.StatusBadRequest)
+				code := http.StatusBadRequest
+				var withCode interface {
+					StatusCode() int
+				}
+				if errors.As(err, &withCode) {
+					code = withCode.StatusCode()
+				}
+				w.WriteHeader(code)
 			}
 			fmt.Fprintf(w, `{"error": {"type": "%s", "msg": "%s"}}`, derr.Type(), quote(derr.Error()))
 			return
@@ -180,6 +493,9 @@ func sendData(w io.Writer, data map[string]json.RawMessage) error {
 		w.Write([]byte{'"'})
 		w.Write([]byte(key))
 		w.Write([]byte{'"', ':'})
+		if len(value) == 0 {
+			value = []byte("null")
+		}
 		w.Write(value)
 	}
 	w.Write([]byte("}\n"))
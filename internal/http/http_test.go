@@ -1,16 +1,21 @@
 package http_test
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
 	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
 	"github.com/openslides/openslides-autoupdate-service/internal/test"
 )
 
@@ -28,6 +33,9 @@ func TestHandlerTestURLs(t *testing.T) {
 		{"", http.StatusNotFound},
 		{"/system/autoupdate", http.StatusBadRequest},
 		{"/system/autoupdate/keys?user/1/name", http.StatusOK},
+		{"/system/autoupdate/../etc/passwd", http.StatusNotFound},
+		{"/system/autoupdate/%2e%2e/secrets", http.StatusNotFound},
+		{"/system/autoupdate/keys/../../../../etc", http.StatusNotFound},
 	}
 
 	for _, tt := range tc {
@@ -47,6 +55,22 @@ func TestHandlerTestURLs(t *testing.T) {
 			if resp.StatusCode != tt.status {
 				t.Errorf("Handler returned %s, expected %d, %s", resp.Status, tt.status, http.StatusText(tt.status))
 			}
+
+			if resp.StatusCode < 400 {
+				// The body of a successful request is a stream.
+				return
+			}
+
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Can not read body: %v", err)
+			}
+
+			for _, leak := range []string{"/etc", "passwd", "secrets", ".."} {
+				if strings.Contains(string(body), leak) {
+					t.Errorf("Body `%s` contains `%s`", body, leak)
+				}
+			}
 		})
 	}
 }
@@ -66,7 +90,7 @@ func TestSimple(t *testing.T) {
 	}{
 		{"user/1/name", keys("user/1/name"), http.StatusOK, ""},
 		{"user/1/name,user/2/name", keys("user/1/name", "user/2/name"), http.StatusOK, ""},
-		{"key1,key2", keys("key1", "key2"), http.StatusBadRequest, "Invalid keys"},
+		{"key1,key2", keys("key1", "key2"), http.StatusBadRequest, "invalid key key1"},
 	}
 
 	for _, tt := range tc {
@@ -191,7 +215,7 @@ func TestErrors(t *testing.T) {
 			mustRequest(http.NewRequest(
 				"GET",
 				srv.URL+"/system/autoupdate",
-				strings.NewReader(`{"ids":[1],"collection":"foo","fields":{}}`),
+				bytes.NewReader(withoutList(new(autoupdate.KeyRequestBuilder).AddCollection("foo", []int{1}).AddField("name"))),
 			)),
 			400,
 			`SyntaxError`,
@@ -202,18 +226,7 @@ func TestErrors(t *testing.T) {
 			mustRequest(http.NewRequest(
 				"GET",
 				srv.URL+"/system/autoupdate",
-				strings.NewReader(`
-				[{
-					"ids": [1],
-					"collection": "foo",
-					"fields": {
-						"name": {
-							"type": "relation",
-							"collection": "bar",
-							"fields": {}
-						}
-					}
-				}]`),
+				bytes.NewReader(mustBuild(new(autoupdate.KeyRequestBuilder).AddCollection("foo", []int{1}).AddRelation("name", autoupdate.Relation("bar", nil)))),
 			)),
 			400,
 			`ValueError`,
@@ -259,3 +272,562 @@ func TestErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthPropagation(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	restricter := new(recordingRestricter)
+	s := autoupdate.New(datastore, restricter)
+	defer s.Close()
+	srv := httptest.NewServer(ahttp.New(s, headerAuth{}, 0))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/autoupdate/keys?user/1/name", nil)
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+	req.Header.Set("Authorization", "42")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Read the first response, so the restricter was called.
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Got invalid json: %v", err)
+	}
+
+	calls := restricter.Calls()
+	if len(calls) == 0 {
+		t.Fatalf("Restricter was not called")
+	}
+	for _, call := range calls {
+		if call.uid != 42 {
+			t.Errorf("Restricter was called with user id %d, expected 42", call.uid)
+		}
+		if !cmpSlice(call.keys, keys("user/1/name")) {
+			t.Errorf("Restricter was called with keys %v, expected %v", call.keys, keys("user/1/name"))
+		}
+	}
+}
+
+func TestHTTP2Push(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	pusher := &recordPusher{handler: ahttp.New(s, mockAuth{1}, 0, ahttp.WithHTTP2Push(true))}
+	srv := httptest.NewUnstartedServer(pusher)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	body, err := new(autoupdate.KeyRequestBuilder).AddCollection("user", []int{1}).AddField("name").Build()
+	if err != nil {
+		t.Fatalf("Can not build key request: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/autoupdate", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("Got protocol %s, expected HTTP/2", resp.Proto)
+	}
+
+	// Read the first response. The push happens before.
+	var data map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		t.Fatalf("Got invalid json: %v", err)
+	}
+
+	expect := keys("/system/autoupdate/keys?user/1/name")
+	if got := pusher.Targets(); !cmpSlice(got, expect) {
+		t.Errorf("Got pushed targets %v, expected %v", got, expect)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/system/autoupdate/version")
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Got status %s, expected %s", resp.Status, http.StatusText(http.StatusOK))
+	}
+
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Got invalid json: %v", err)
+	}
+
+	for _, field := range []string{"version", "commit"} {
+		if body[field] == "" {
+			t.Errorf("Field %s is empty", field)
+		}
+	}
+}
+
+func TestTTFBSpan(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	tracer := new(mockTracer)
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0, ahttp.WithTracer(tracer)))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/autoupdate/keys?user/1/name", nil)
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Got invalid json: %v", err)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("Got %d spans, expected 1", len(spans))
+	}
+
+	span := spans[0]
+	if span.name != "subscription.ttfb" {
+		t.Errorf("Got span %s, expected subscription.ttfb", span.name)
+	}
+
+	select {
+	case <-span.ended:
+	case <-time.After(time.Second):
+		t.Fatalf("Span was not ended after the first data")
+	}
+
+	if d := span.end.Sub(span.start); d < 0 {
+		t.Errorf("Span has negative duration %v", d)
+	}
+}
+
+func TestHandlerOnce(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/system/autoupdate/once?user/1/name")
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The response has to end after the first data.
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Can not read body: %v", err)
+	}
+
+	if expect := `{"user/1/name":"Hello World"}` + "\n"; string(body) != expect {
+		t.Errorf("Got `%s`, expected `%s`", body, expect)
+	}
+}
+
+func TestHandlerKeyRange(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0, ahttp.WithMaxKeyRange(3), ahttp.WithStableKeyOrder(true)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/system/autoupdate/once?user/[1-2]/name,user/3/name")
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Can not read body: %v", err)
+	}
+
+	if expect := `{"user/1/name":"Hello World","user/2/name":"Hello World","user/3/name":"Hello World"}` + "\n"; string(body) != expect {
+		t.Errorf("Got `%s`, expected `%s`", body, expect)
+	}
+
+	resp, err = http.Get(srv.URL + "/system/autoupdate/once?user/[1-4]/name")
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Got status %d for a too big range, expected 400", resp.StatusCode)
+	}
+}
+
+func TestHandlerIdleTimeout(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+
+	// The fake clock runs out the idle timer as soon as it is started, like a
+	// client that does not read anymore.
+	s := autoupdate.New(datastore, new(test.MockRestricter), autoupdate.WithIdleTimeout(time.Minute), autoupdate.WithClock(newFakeClock()))
+	defer s.Close()
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/system/autoupdate/keys?user/1/name")
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Can not read body: %v", err)
+	}
+
+	if expect := `{"user/1/name":"Hello World"}` + "\n" + `{"close":"idle_timeout"}` + "\n"; string(body) != expect {
+		t.Errorf("Got `%s`, expected `%s`", body, expect)
+	}
+}
+
+func TestTTFBMetric(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	registry := ahttp.NewPrometheusMetricsRegistry()
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0, ahttp.WithTTFBObserver(registry)))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/autoupdate/keys?user/1/name", nil)
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Got invalid json: %v", err)
+	}
+
+	// The time is observed after the data was sent.
+	timeout := time.After(time.Second)
+	for {
+		rec := httptest.NewRecorder()
+		registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		if strings.Contains(rec.Body.String(), "autoupdate_subscription_ttfb_seconds_count 1\n") {
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("TTFB was not observed:\n%s", rec.Body.String())
+		default:
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.Data = map[string]json.RawMessage{"user/1/name": []byte(`"default"`)}
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	prodDatastore := test.NewMockDatastore()
+	defer prodDatastore.Close()
+	prodDatastore.Data = map[string]json.RawMessage{"user/1/name": []byte(`"production"`)}
+	prod := autoupdate.New(prodDatastore, new(test.MockRestricter))
+	defer prod.Close()
+
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0, ahttp.WithNamespace("production", prod)))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		name      string
+		namespace string
+		query     string
+		status    int
+		errType   string
+		key       string
+		value     string
+	}{
+		{"match", "production", "production/user/1/name", http.StatusOK, "", "production/user/1/name", `"production"`},
+		{"mismatch", "production", "staging/user/1/name", http.StatusBadRequest, "NamespaceError", "", ""},
+		{"key without namespace", "production", "user/1/name", http.StatusBadRequest, "NamespaceError", "", ""},
+		{"unknown namespace", "staging", "staging/user/1/name", http.StatusBadRequest, "NamespaceError", "", ""},
+		{"no namespace", "", "user/1/name", http.StatusOK, "", "user/1/name", `"default"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/autoupdate/keys?"+tt.query, nil)
+			if err != nil {
+				t.Fatalf("Can not create request: %v", err)
+			}
+			if tt.namespace != "" {
+				req.Header.Set("X-Autoupdate-Namespace", tt.namespace)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Can not send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("Got status %s, expected %d", resp.Status, tt.status)
+			}
+
+			var body map[string]json.RawMessage
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("Got invalid json: %v", err)
+			}
+
+			var errBody struct {
+				Type string `json:"type"`
+			}
+			if raw, ok := body["error"]; ok {
+				if err := json.Unmarshal(raw, &errBody); err != nil {
+					t.Fatalf("Got invalid error: %v", err)
+				}
+			}
+
+			if errBody.Type != tt.errType {
+				t.Errorf("Got error type `%s`, expected `%s`", errBody.Type, tt.errType)
+			}
+
+			if tt.key == "" {
+				return
+			}
+
+			if got := string(body[tt.key]); got != tt.value {
+				t.Errorf("Got value `%s` for key %s, expected `%s`", got, tt.key, tt.value)
+			}
+		})
+	}
+}
+
+func TestHandlerNullValue(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	datastore.Data = map[string]json.RawMessage{"user/1/name": []byte(`"hugo"`)}
+	datastore.OnlyData = true
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/autoupdate/keys?user/1/name", nil)
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	if _, err := reader.ReadBytes('\n'); err != nil {
+		t.Fatalf("Can not read first message: %v", err)
+	}
+
+	// The key is deleted, so its value is empty.
+	datastore.Data = map[string]json.RawMessage{}
+	s.UpdateKeys(map[string]json.RawMessage{"user/1/name": nil})
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("Can not read second message: %v", err)
+	}
+
+	if expect := `{"user/1/name":null}` + "\n"; string(line) != expect {
+		t.Errorf("Got `%s`, expected `%s`", line, expect)
+	}
+}
+
+func TestHandlerVersion(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0, ahttp.WithVersion(true)))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/autoupdate/keys?user/1/name", nil)
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	stream := bufio.NewReader(resp.Body)
+	var lastVersion uint64
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(fmt.Sprintf(`"value %d"`, i))})
+			datastore.Send(test.Str("user/1/name"))
+		}
+
+		line, err := stream.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("Can not read frame %d: %v", i, err)
+		}
+
+		var frame struct {
+			Version uint64                     `json:"version"`
+			Data    map[string]json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(line, &frame); err != nil {
+			t.Fatalf("Got invalid json: %v", err)
+		}
+
+		if _, ok := frame.Data["user/1/name"]; !ok {
+			t.Errorf("Frame %d has no data for user/1/name: %s", i, line)
+		}
+
+		if i > 0 && frame.Version != lastVersion+1 {
+			t.Errorf("Got version %d in frame %d, expected %d", frame.Version, i, lastVersion+1)
+		}
+		lastVersion = frame.Version
+	}
+
+	token := resp.Header.Get("X-Subscription-Token")
+	if token == "" {
+		t.Fatalf("Response has no subscription token")
+	}
+	cancel()
+	resp.Body.Close()
+
+	t.Run("reconnect", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// The old subscription is deregistered, after the handler noticed
+		// the closed connection.
+		var frame struct {
+			LastVersion *uint64 `json:"last_version"`
+		}
+		for i := 0; i < 100; i++ {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/autoupdate/keys?user/1/name", nil)
+			if err != nil {
+				t.Fatalf("Can not create request: %v", err)
+			}
+			req.Header.Set("X-Subscription-Token", token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Can not send request: %v", err)
+			}
+
+			line, err := bufio.NewReader(resp.Body).ReadBytes('\n')
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("Can not read first frame: %v", err)
+			}
+
+			if err := json.Unmarshal(line, &frame); err != nil {
+				t.Fatalf("Got invalid json: %v", err)
+			}
+			if frame.LastVersion != nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if frame.LastVersion == nil || *frame.LastVersion != lastVersion {
+			t.Errorf("Got last_version %v, expected %d", frame.LastVersion, lastVersion)
+		}
+	})
+}
+
+func TestHandlerSchema(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	schema := new(key.Schema)
+	schema.Register("user", []string{"name"})
+	handler := ahttp.New(s, mockAuth{1}, 0, ahttp.WithSchema(schema))
+
+	for _, tt := range []struct {
+		query   string
+		status  int
+		errType string
+	}{
+		{"user/1/name", http.StatusOK, ""},
+		{"user/1/nmae", http.StatusBadRequest, "UnknownFieldError"},
+		{"usr/1/name", http.StatusBadRequest, "UnknownCollectionError"},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate/once?"+tt.query, nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if tt.errType == "" {
+				return
+			}
+
+			var body map[string]map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("Got invalid json: %v", err)
+			}
+			if got := body["error"]["type"]; got != tt.errType {
+				t.Errorf("Got error type %s, expected %s", got, tt.errType)
+			}
+		})
+	}
+}
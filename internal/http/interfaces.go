@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"net/http"
+	"time"
 )
 
 // Authenticator gives an user id for an request.
@@ -11,8 +12,57 @@ type Authenticator interface {
 	Authenticate(context.Context, *http.Request) (int, error)
 }
 
+// WriteVersioner can be implemented by an Authenticator. It returns the
+// version of the last write of the user from the auth token. The autoupdate
+// service can wait for this version before it sends the first data. See
+// autoupdate.WithReadYourWritesConsistency().
+type WriteVersioner interface {
+	WriteVersion(r *http.Request) (uint64, error)
+}
+
+// AdminChecker tells, if a user is allowed to see internal information of the
+// service, like the statistics of the subscriptions.
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, uid int) (bool, error)
+}
+
 // DefinedError is an expected error that are returned to the client.
 type DefinedError interface {
 	Type() string
 	Error() string
 }
+
+// Tracer starts spans. It has the same signature as the start method of an
+// opentelemetry tracer, so it can be implemented with a small adapter.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a traced duration that was started by a Tracer.
+type Span interface {
+	End()
+}
+
+// MetricsRegistry stores metrics about the handled requests.
+type MetricsRegistry interface {
+	ObserveRequest(path string, duration time.Duration)
+}
+
+// TTFBObserver stores the time until the first data of a subscription was
+// sent to the client.
+type TTFBObserver interface {
+	ObserveTTFB(duration time.Duration)
+}
+
+// Clock tells the time and waits for durations.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// CircuitBreaker tells, if the backend of the service, for example the
+// datastore, is not available. While the circuit is open, requests fail
+// without calling the backend.
+type CircuitBreaker interface {
+	Open() bool
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxyMiddleware resolves the ip of the client for requests that are
+// forwarded by one of the trusted proxies.
+//
+// If the remote address of the connection is a trusted proxy, the
+// X-Forwarded-For header is read from right to left. The first address, that is
+// not a trusted proxy, is the client. The header is ignored for all other
+// requests, since a client can send any value.
+//
+// Middlewares that use the ip of the client, like IPBlocklistMiddleware, have
+// to be called after this middleware.
+func TrustedProxyMiddleware(trusted []net.IPNet) func(http.Handler) http.Handler {
+	isTrusted := func(ip net.IP) bool {
+		for _, n := range trusted {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			if ip != nil && isTrusted(ip) {
+				ip = forwardedIP(r.Header.Values("X-Forwarded-For"), ip, isTrusted)
+			}
+
+			ctx := context.WithValue(r.Context(), clientIPKey, ip)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// forwardedIP returns the rightmost address of the X-Forwarded-For headers
+// that is not trusted. If all addresses are trusted, the leftmost is returned.
+// proxy is the address of the connection.
+//
+// Returns nil, if an address before the client can not be parsed.
+func forwardedIP(headers []string, proxy net.IP, isTrusted func(net.IP) bool) net.IP {
+	var addrs []string
+	for _, h := range headers {
+		addrs = append(addrs, strings.Split(h, ",")...)
+	}
+
+	ip := proxy
+	for i := len(addrs) - 1; i >= 0; i-- {
+		ip = net.ParseIP(strings.TrimSpace(addrs[i]))
+		if ip == nil || !isTrusted(ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+// realIP returns the ip of the client. It is the ip resolved by
+// TrustedProxyMiddleware or the remote address of the connection.
+//
+// Returns nil, if the ip can not be parsed.
+func realIP(r *http.Request) net.IP {
+	if ip, ok := r.Context().Value(clientIPKey).(net.IP); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// remoteIP returns the ip of the connection or nil, if it can not be parsed.
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,27 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AllowedMethodsMiddleware rejects requests with a method, that is not in the
+// allowed list, with the status 405. The header Allow of the response lists
+// the allowed methods.
+func AllowedMethodsMiddleware(allowed ...string) func(http.Handler) http.Handler {
+	allow := strings.Join(allowed, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, method := range allowed {
+				if r.Method == method {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			w.Header().Set("Allow", allow)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		})
+	}
+}
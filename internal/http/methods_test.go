@@ -0,0 +1,76 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestAllowedMethodsMiddleware(t *testing.T) {
+	handler := ahttp.AllowedMethodsMiddleware(http.MethodGet, http.MethodPost)(http.HandlerFunc(okHandler))
+
+	for _, tt := range []struct {
+		method string
+		status int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodPost, http.StatusOK},
+		{http.MethodDelete, http.StatusMethodNotAllowed},
+	} {
+		t.Run(tt.method, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/", nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if tt.status != http.StatusMethodNotAllowed {
+				return
+			}
+
+			if got := rec.Header().Get("Allow"); got != "GET, POST" {
+				t.Errorf("Got Allow header `%s`, expected `GET, POST`", got)
+			}
+		})
+	}
+}
+
+func TestHandlerMethodNotAllowed(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	handler := ahttp.New(s, mockAuth{1}, 0)
+
+	for _, tt := range []struct {
+		url   string
+		allow string
+	}{
+		{"/system/autoupdate", "GET, POST"},
+		{"/system/autoupdate/keys?user/1/name", "GET"},
+		{"/system/autoupdate/once?user/1/name", "GET"},
+		{"/system/autoupdate/version", "GET"},
+		{"/system/autoupdate/subscriptions", "GET"},
+		{"/system/autoupdate/batch", "POST"},
+	} {
+		for _, method := range []string{http.MethodDelete, http.MethodPut, http.MethodPatch} {
+			t.Run(method+" "+tt.url, func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(method, tt.url, nil))
+
+				if rec.Code != http.StatusMethodNotAllowed {
+					t.Errorf("Got status %d, expected %d", rec.Code, http.StatusMethodNotAllowed)
+				}
+
+				if got := rec.Header().Get("Allow"); got != tt.allow {
+					t.Errorf("Got Allow header `%s`, expected `%s`", got, tt.allow)
+				}
+			})
+		}
+	}
+}
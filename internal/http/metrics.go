@@ -0,0 +1,199 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricsMiddleware records the duration of each request in the registry.
+//
+// For streaming requests, the duration is the time until the connection was
+// closed.
+func MetricsMiddleware(registry MetricsRegistry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			next.ServeHTTP(w, r)
+			registry.ObserveRequest(r.URL.Path, time.Since(start))
+		})
+	}
+}
+
+// maxMetricPaths is the number of paths that are recorded separately. All
+// other paths are recorded as "other", so unknown urls do not fill the
+// memory.
+const maxMetricPaths = 100
+
+// ttfbSamples is the number of recent observations, that are used to
+// calculate the P95 time to first byte.
+const ttfbSamples = 1000
+
+// latencyBuckets are the upper bounds of the histogram in seconds.
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 30, 60, 300}
+
+// PrometheusMetricsRegistry is a MetricsRegistry that exports the request
+// count and a latency histogram per path in the prometheus text format. The
+// P99 latency can be calculated with histogram_quantile().
+//
+// It is also a TTFBObserver and exports the P95 time to first byte of the
+// last subscriptions as a summary.
+//
+// Has to be created with NewPrometheusMetricsRegistry().
+type PrometheusMetricsRegistry struct {
+	mu    sync.RWMutex
+	paths map[string]*pathMetrics
+
+	ttfbMu    sync.Mutex
+	ttfb      []time.Duration
+	ttfbNext  int
+	ttfbSum   time.Duration
+	ttfbCount uint64
+}
+
+// pathMetrics are the metrics of one path. All fields are used with atomic.
+type pathMetrics struct {
+	count   uint64
+	sumNano uint64
+	buckets []uint64
+}
+
+// NewPrometheusMetricsRegistry creates a PrometheusMetricsRegistry.
+func NewPrometheusMetricsRegistry() *PrometheusMetricsRegistry {
+	return &PrometheusMetricsRegistry{
+		paths: make(map[string]*pathMetrics),
+	}
+}
+
+// ObserveRequest records one request.
+func (p *PrometheusMetricsRegistry) ObserveRequest(path string, duration time.Duration) {
+	m := p.metrics(path)
+
+	atomic.AddUint64(&m.count, 1)
+	atomic.AddUint64(&m.sumNano, uint64(duration))
+
+	seconds := duration.Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			atomic.AddUint64(&m.buckets[i], 1)
+			break
+		}
+	}
+}
+
+// ObserveTTFB records the time to first byte of one subscription.
+func (p *PrometheusMetricsRegistry) ObserveTTFB(duration time.Duration) {
+	p.ttfbMu.Lock()
+	defer p.ttfbMu.Unlock()
+
+	if len(p.ttfb) < ttfbSamples {
+		p.ttfb = append(p.ttfb, duration)
+	} else {
+		p.ttfb[p.ttfbNext] = duration
+		p.ttfbNext = (p.ttfbNext + 1) % ttfbSamples
+	}
+	p.ttfbSum += duration
+	p.ttfbCount++
+}
+
+// ttfbP95 returns the P95 of the recent ttfb samples, the sum and the count of
+// all observations. The P95 is NaN, if there are no observations.
+func (p *PrometheusMetricsRegistry) ttfbP95() (float64, time.Duration, uint64) {
+	p.ttfbMu.Lock()
+	samples := make([]time.Duration, len(p.ttfb))
+	copy(samples, p.ttfb)
+	sum, count := p.ttfbSum, p.ttfbCount
+	p.ttfbMu.Unlock()
+
+	if len(samples) == 0 {
+		return math.NaN(), sum, count
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	idx := int(math.Ceil(0.95*float64(len(samples)))) - 1
+	return samples[idx].Seconds(), sum, count
+}
+
+// metrics returns the metrics for a path. It creates them, if they do not
+// exist.
+func (p *PrometheusMetricsRegistry) metrics(path string) *pathMetrics {
+	p.mu.RLock()
+	m, ok := p.paths[path]
+	p.mu.RUnlock()
+	if ok {
+		return m
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.paths) >= maxMetricPaths {
+		path = "other"
+	}
+
+	m, ok = p.paths[path]
+	if !ok {
+		m = &pathMetrics{buckets: make([]uint64, len(latencyBuckets))}
+		p.paths[path] = m
+	}
+	return m
+}
+
+// ServeHTTP writes the metrics in the prometheus text format.
+func (p *PrometheusMetricsRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	paths := make([]string, 0, len(p.paths))
+	metrics := make(map[string]*pathMetrics, len(p.paths))
+	for path, m := range p.paths {
+		paths = append(paths, path)
+		metrics[path] = m
+	}
+	p.mu.RUnlock()
+	sort.Strings(paths)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP autoupdate_http_requests_total Number of handled requests.")
+	fmt.Fprintln(w, "# TYPE autoupdate_http_requests_total counter")
+	for _, path := range paths {
+		fmt.Fprintf(w, "autoupdate_http_requests_total{path=%s} %d\n", labelValue(path), atomic.LoadUint64(&metrics[path].count))
+	}
+
+	fmt.Fprintln(w, "# HELP autoupdate_http_request_duration_seconds Duration of the requests.")
+	fmt.Fprintln(w, "# TYPE autoupdate_http_request_duration_seconds histogram")
+	for _, path := range paths {
+		m := metrics[path]
+		label := labelValue(path)
+
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += atomic.LoadUint64(&m.buckets[i])
+			fmt.Fprintf(w, "autoupdate_http_request_duration_seconds_bucket{path=%s,le=\"%g\"} %d\n", label, bound, cumulative)
+		}
+
+		count := atomic.LoadUint64(&m.count)
+		fmt.Fprintf(w, "autoupdate_http_request_duration_seconds_bucket{path=%s,le=\"+Inf\"} %d\n", label, count)
+		fmt.Fprintf(w, "autoupdate_http_request_duration_seconds_sum{path=%s} %g\n", label, time.Duration(atomic.LoadUint64(&m.sumNano)).Seconds())
+		fmt.Fprintf(w, "autoupdate_http_request_duration_seconds_count{path=%s} %d\n", label, count)
+	}
+
+	p95, sum, count := p.ttfbP95()
+	fmt.Fprintln(w, "# HELP autoupdate_subscription_ttfb_seconds Time until the first data of a subscription was sent.")
+	fmt.Fprintln(w, "# TYPE autoupdate_subscription_ttfb_seconds summary")
+	fmt.Fprintf(w, "autoupdate_subscription_ttfb_seconds{quantile=\"0.95\"} %g\n", p95)
+	fmt.Fprintf(w, "autoupdate_subscription_ttfb_seconds_sum %g\n", sum.Seconds())
+	fmt.Fprintf(w, "autoupdate_subscription_ttfb_seconds_count %d\n", count)
+}
+
+// labelValue quotes a label value for the prometheus text format.
+func labelValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return `"` + v + `"`
+}
@@ -0,0 +1,97 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	registry := ahttp.NewPrometheusMetricsRegistry()
+	handler := ahttp.MetricsMiddleware(registry)(okHandler)
+
+	for _, path := range []string{"/system/autoupdate", "/system/autoupdate", "/system/autoupdate/keys"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, line := range []string{
+		`autoupdate_http_requests_total{path="/system/autoupdate"} 2`,
+		`autoupdate_http_requests_total{path="/system/autoupdate/keys"} 1`,
+		`autoupdate_http_request_duration_seconds_bucket{path="/system/autoupdate",le="+Inf"} 2`,
+		`autoupdate_http_request_duration_seconds_count{path="/system/autoupdate/keys"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Metrics do not contain `%s`:\n%s", line, body)
+		}
+	}
+}
+
+func TestPrometheusMetricsRegistryBuckets(t *testing.T) {
+	registry := ahttp.NewPrometheusMetricsRegistry()
+	registry.ObserveRequest("/", 2*time.Millisecond)
+	registry.ObserveRequest("/", 2*time.Second)
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, line := range []string{
+		`autoupdate_http_request_duration_seconds_bucket{path="/",le="0.001"} 0`,
+		`autoupdate_http_request_duration_seconds_bucket{path="/",le="0.005"} 1`,
+		`autoupdate_http_request_duration_seconds_bucket{path="/",le="1"} 1`,
+		`autoupdate_http_request_duration_seconds_bucket{path="/",le="5"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Metrics do not contain `%s`:\n%s", line, body)
+		}
+	}
+}
+
+func BenchmarkMetricsMiddleware(b *testing.B) {
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	req := httptest.NewRequest("GET", "/system/autoupdate", nil)
+	w := httptest.NewRecorder()
+
+	b.Run("without", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			noop.ServeHTTP(w, req)
+		}
+	})
+
+	b.Run("with", func(b *testing.B) {
+		handler := ahttp.MetricsMiddleware(ahttp.NewPrometheusMetricsRegistry())(noop)
+		for n := 0; n < b.N; n++ {
+			handler.ServeHTTP(w, req)
+		}
+	})
+}
+
+func TestPrometheusMetricsRegistryTTFB(t *testing.T) {
+	registry := ahttp.NewPrometheusMetricsRegistry()
+	for i := 1; i <= 100; i++ {
+		registry.ObserveTTFB(time.Duration(i) * time.Millisecond)
+	}
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, line := range []string{
+		`# TYPE autoupdate_subscription_ttfb_seconds summary`,
+		`autoupdate_subscription_ttfb_seconds{quantile="0.95"} 0.095`,
+		`autoupdate_subscription_ttfb_seconds_sum 5.05`,
+		`autoupdate_subscription_ttfb_seconds_count 100`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Metrics do not contain `%s`:\n%s", line, body)
+		}
+	}
+}
@@ -2,10 +2,31 @@ package http_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
 )
 
+// mockAdmins is an AdminChecker. All users in the list are admins.
+type mockAdmins []int
+
+func (a mockAdmins) IsAdmin(ctx context.Context, uid int) (bool, error) {
+	for _, id := range a {
+		if id == uid {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func mustRequest(r *http.Request, err error) *http.Request {
 	if err != nil {
 		panic(err)
@@ -13,6 +34,24 @@ func mustRequest(r *http.Request, err error) *http.Request {
 	return r
 }
 
+func mustBuild(b *autoupdate.KeyRequestBuilder) []byte {
+	body, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
+
+// withoutList returns the first key request of the builder without the
+// surrounding list.
+func withoutList(b *autoupdate.KeyRequestBuilder) []byte {
+	var requests []json.RawMessage
+	if err := json.Unmarshal(mustBuild(b), &requests); err != nil {
+		panic(err)
+	}
+	return requests[0]
+}
+
 type mockAuth struct {
 	uid int
 }
@@ -38,3 +77,215 @@ func cmpSlice(one, two []string) bool {
 	}
 	return true
 }
+
+// pipeListener is a net.Listener that creates connections with net.Pipe. A
+// pipe has no buffer, so a write blocks until the other side reads the data.
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closed:
+		return nil, errors.New("listener closed")
+	}
+}
+
+func (l *pipeListener) Close() error {
+	close(l.closed)
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// dial creates a new connection to the listener and returns the client side.
+// The returned channel is closed, when the server closes the connection.
+func (l *pipeListener) dial() (net.Conn, <-chan struct{}) {
+	server, client := net.Pipe()
+	c := &closeConn{Conn: server, closed: make(chan struct{})}
+	l.conns <- c
+	return client, c.closed
+}
+
+// closeConn is a net.Conn that closes a channel, when it is closed.
+type closeConn struct {
+	net.Conn
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (c *closeConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
+
+// headerAuth reads the user id from the Authorization header.
+type headerAuth struct{}
+
+func (headerAuth) Authenticate(_ context.Context, r *http.Request) (int, error) {
+	return strconv.Atoi(r.Header.Get("Authorization"))
+}
+
+// restrictCall is one call to the recordingRestricter.
+type restrictCall struct {
+	uid  int
+	keys []string
+}
+
+// recordingRestricter is a restricter that records each call.
+type recordingRestricter struct {
+	mu    sync.Mutex
+	calls []restrictCall
+}
+
+func (r *recordingRestricter) Restrict(uid int, data map[string]json.RawMessage) error {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, restrictCall{uid: uid, keys: keys})
+	return nil
+}
+
+func (r *recordingRestricter) Calls() []restrictCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+// recordPusher is a middleware that records all targets that are pushed.
+type recordPusher struct {
+	handler http.Handler
+
+	mu      sync.Mutex
+	targets []string
+}
+
+func (p *recordPusher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.handler.ServeHTTP(pushRecorder{ResponseWriter: w, p: p}, r)
+}
+
+func (p *recordPusher) Targets() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.targets
+}
+
+type pushRecorder struct {
+	http.ResponseWriter
+	p *recordPusher
+}
+
+func (w pushRecorder) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w pushRecorder) Push(target string, opts *http.PushOptions) error {
+	w.p.mu.Lock()
+	w.p.targets = append(w.p.targets, target)
+	w.p.mu.Unlock()
+
+	pusher, ok := w.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return pusher.Push(target, opts)
+}
+
+// okHandler is a handler that always writes the status 200.
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// mockTracer records all spans.
+type mockTracer struct {
+	mu    sync.Mutex
+	spans []*mockSpan
+}
+
+func (t *mockTracer) Start(ctx context.Context, name string) (context.Context, ahttp.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &mockSpan{name: name, start: time.Now(), ended: make(chan struct{})}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func (t *mockTracer) Spans() []*mockSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*mockSpan(nil), t.spans...)
+}
+
+type mockSpan struct {
+	name  string
+	start time.Time
+	end   time.Time
+	ended chan struct{}
+}
+
+func (s *mockSpan) End() {
+	s.end = time.Now()
+	close(s.ended)
+}
+
+// fakeClock is an ahttp.Clock and an autoupdate.Clock that only moves, when it
+// is advanced or when someone waits with After() or AfterFunc().
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After advances the clock by d and returns immediately.
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	now := c.Advance(d)
+	ch := make(chan time.Time, 1)
+	ch <- now
+	return ch
+}
+
+// AfterFunc advances the clock by d and calls f immediately. The returned
+// function always returns false, because f was already called.
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.Advance(d)
+	f()
+	return func() bool { return false }
+}
+
+// Advance moves the clock forward and returns the new time.
+func (c *fakeClock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
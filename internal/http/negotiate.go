@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentTypeNegotiationMiddleware selects the content type of the response
+// from the Accept header of the request. The selected type can be read with
+// NegotiatedContentType().
+//
+// The header is parsed as described in RFC 7231 section 5.3.2. The supported
+// type with the highest quality value is used. If more then one type has the
+// same quality, the first one in supported is used. A request without the
+// header accepts all types.
+//
+// If no supported type is accepted, the status 406 is returned.
+func ContentTypeNegotiationMiddleware(supported []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept := strings.Join(r.Header.Values("Accept"), ",")
+			if accept == "" {
+				accept = "*/*"
+			}
+
+			contentType := negotiate(parseAccept(accept), supported)
+			if contentType == "" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotAcceptable)
+				msg := fmt.Sprintf("none of the supported content types %s is accepted", strings.Join(supported, ", "))
+				fmt.Fprintf(w, `{"error": {"type": "NotAcceptableError", "msg": "%s"}}`, quote(msg))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), contentTypeKey, contentType)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// NegotiatedContentType returns the content type, that was selected by
+// ContentTypeNegotiationMiddleware. Returns an empty string, if the middleware
+// was not used.
+func NegotiatedContentType(ctx context.Context) string {
+	contentType, _ := ctx.Value(contentTypeKey).(string)
+	return contentType
+}
+
+// mediaRange is one entry of an Accept header.
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// matches returns, how specific the range matches the content type. It is 0,
+// if it does not match, 1 for */*, 2 for type/* and 3 for type/subtype.
+func (m mediaRange) matches(contentType string) int {
+	typ, subtype := splitMediaType(contentType)
+	switch {
+	case m.typ == "*" && m.subtype == "*":
+		return 1
+	case m.typ == typ && m.subtype == "*":
+		return 2
+	case m.typ == typ && m.subtype == subtype:
+		return 3
+	}
+	return 0
+}
+
+// parseAccept parses the value of an Accept header. Invalid entries are
+// skipped. Parameters other then q are ignored.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, entry := range strings.Split(header, ",") {
+		parts := strings.Split(entry, ";")
+		typ, subtype := splitMediaType(parts[0])
+		if typ == "" || subtype == "" {
+			continue
+		}
+
+		m := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range parts[1:] {
+			name, value := splitParam(param)
+			if name != "q" {
+				continue
+			}
+
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			m.q = q
+		}
+		ranges = append(ranges, m)
+	}
+	return ranges
+}
+
+// negotiate returns the supported content type with the highest quality. The
+// quality of a type is the quality of the most specific range, that matches
+// it. Returns an empty string, if no type has a quality above 0.
+func negotiate(ranges []mediaRange, supported []string) string {
+	var best string
+	var bestQ float64
+	for _, contentType := range supported {
+		specificity := 0
+		var q float64
+		for _, m := range ranges {
+			if s := m.matches(contentType); s > specificity {
+				specificity = s
+				q = m.q
+			}
+		}
+
+		if q > bestQ {
+			best = contentType
+			bestQ = q
+		}
+	}
+	return best
+}
+
+// splitMediaType returns the lower case type and subtype of a media type like
+// text/html.
+func splitMediaType(mediaType string) (string, string) {
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	idx := strings.IndexByte(mediaType, '/')
+	if idx == -1 {
+		return "", ""
+	}
+	return mediaType[:idx], mediaType[idx+1:]
+}
+
+// splitParam returns the lower case name and the value of a parameter like
+// q=0.5.
+func splitParam(param string) (string, string) {
+	idx := strings.IndexByte(param, '=')
+	if idx == -1 {
+		return strings.ToLower(strings.TrimSpace(param)), ""
+	}
+	return strings.ToLower(strings.TrimSpace(param[:idx])), strings.TrimSpace(param[idx+1:])
+}
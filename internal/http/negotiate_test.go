@@ -0,0 +1,58 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestContentTypeNegotiationMiddleware(t *testing.T) {
+	var got string
+	handler := ahttp.ContentTypeNegotiationMiddleware([]string{"application/json", "application/octet-stream", "text/plain"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = ahttp.NegotiatedContentType(r.Context())
+		}),
+	)
+
+	for _, tt := range []struct {
+		name   string
+		accept string
+		status int
+		expect string
+	}{
+		{"no header", "", http.StatusOK, "application/json"},
+		{"all", "*/*", http.StatusOK, "application/json"},
+		{"explicit", "text/plain", http.StatusOK, "text/plain"},
+		{"case insensitive", "Text/Plain", http.StatusOK, "text/plain"},
+		{"quality", "application/json;q=0.5, text/plain;q=0.8", http.StatusOK, "text/plain"},
+		{"wildcard with lower quality", "text/plain;q=0.4, */*;q=0.1", http.StatusOK, "text/plain"},
+		{"type wildcard", "text/html, application/*;q=0.9", http.StatusOK, "application/json"},
+		{"specific range wins", "application/*;q=0.2, application/octet-stream", http.StatusOK, "application/octet-stream"},
+		{"excluded with q=0", "application/json;q=0, */*;q=0.5", http.StatusOK, "application/octet-stream"},
+		{"params are ignored", "text/plain; charset=utf-8; q=0.7, application/json; q=0.3", http.StatusOK, "text/plain"},
+		{"tie uses server order", "text/plain, application/octet-stream", http.StatusOK, "application/octet-stream"},
+		{"not acceptable", "text/html, image/*", http.StatusNotAcceptable, ""},
+		{"all excluded", "*/*;q=0", http.StatusNotAcceptable, ""},
+		{"invalid entry", "invalid, text/plain;q=0.1", http.StatusOK, "text/plain"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+			req := httptest.NewRequest("GET", "/system/autoupdate", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if got != tt.expect {
+				t.Errorf("Got content type `%s`, expected `%s`", got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// onceURL returns the current values of the keys in the url query and closes
+// the connection. It uses the same format as the simple url.
+const onceURL = "/system/autoupdate/once"
+
+// once sends the current data for the keys only one time. In contrast to the
+// other autoupdate urls, the response is finite, so it can be cached.
+func (h *Handler) once(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	uid, err := h.auth.Authenticate(r.Context(), r)
+	if err != nil {
+		return fmt.Errorf("authenticate request: %w", err)
+	}
+
+	r, err = h.withWriteVersion(r)
+	if err != nil {
+		return err
+	}
+
+	s, ns, err := h.service(r)
+	if err != nil {
+		return err
+	}
+
+	tid := s.LastID()
+
+	kb, err := h.simple(r, s, uid)
+	if err != nil {
+		return fmt.Errorf("build keysbuilder: %w", err)
+	}
+
+	if err := h.validateSchema(kb.Keys()); err != nil {
+		return err
+	}
+	setResponseInfo(r.Context(), uid, len(kb.Keys()))
+
+	data, err := s.Connect(uid, kb, tid).Next(r.Context())
+	if err != nil {
+		return fmt.Errorf("get data: %w", err)
+	}
+	data = addNamespace(ns, data)
+
+	if h.stableKeyOrder {
+		return NewStableJSONEncoder(w).Encode(data)
+	}
+	return sendData(w, data)
+}
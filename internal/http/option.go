@@ -0,0 +1,163 @@
+package http
+
+import (
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+// Option is an optional argument for http.New().
+type Option func(*Handler)
+
+// WithWriteTimeout sets a timeout for each write to the client. If a client
+// does not read the data for this duration, the connection is closed.
+//
+// The http.Server has to use ConnContext. Otherwise the handler can not access
+// the connection and the timeout is ignored.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(h *Handler) {
+		h.writeTimeout = d
+	}
+}
+
+// WithHTTP2Push activates http2 server push. If the client uses http2, the
+// simple url for all requested keys is pushed after the request was parsed.
+func WithHTTP2Push(push bool) Option {
+	return func(h *Handler) {
+		h.http2Push = push
+	}
+}
+
+// WithCORSPreflight answers all OPTIONS requests with the
+// CORSPreflightHandler.
+func WithCORSPreflight(origins []string, allowedHeaders []string) Option {
+	return func(h *Handler) {
+		h.corsPreflight = CORSPreflightHandler(origins, allowedHeaders)
+	}
+}
+
+// WithStableKeyOrder sorts the keys of each update frame alphabetically. This
+// costs some time but makes the responses comparable.
+func WithStableKeyOrder(stable bool) Option {
+	return func(h *Handler) {
+		h.stableKeyOrder = stable
+	}
+}
+
+// WithVersion wraps each update frame into an envelope like
+// {"version":5,"data":{...}}. The version is the version of the data from
+// Connection.Version(). It increases with each update of the service, so a
+// client can tell, if an update is newer then an other one.
+//
+// The header X-Subscription-Token of the response contains the token of the
+// subscription. The last sent version is saved with the token. A client, that
+// reconnects with the old token in the same header, gets this version as
+// "last_version" in the envelope of the first frame.
+func WithVersion(version bool) Option {
+	return func(h *Handler) {
+		h.version = version
+	}
+}
+
+// WithTracer sets a tracer. Each autoupdate request creates the span
+// `subscription.ttfb` that ends after the first data was written to the
+// client.
+func WithTracer(t Tracer) Option {
+	return func(h *Handler) {
+		h.tracer = t
+	}
+}
+
+// WithTTFBObserver records the time until the first data of each autoupdate
+// request was written to the client. The PrometheusMetricsRegistry exports the
+// P95 of this time.
+func WithTTFBObserver(o TTFBObserver) Option {
+	return func(h *Handler) {
+		h.ttfb = o
+	}
+}
+
+// WithEarlyHints sends a 103 Early Hints response before the data. It
+// contains a prefetch link to the once url of each key that was found by
+// following a relation of the request. This needs go 1.19 or newer.
+func WithEarlyHints(hints bool) Option {
+	return func(h *Handler) {
+		h.earlyHints = hints
+	}
+}
+
+// WithDebug adds the stack trace to the response, when a handler panics. Only
+// use it in development.
+func WithDebug(debug bool) Option {
+	return func(h *Handler) {
+		h.debug = debug
+	}
+}
+
+// WithAdminChecker allows the users, that are admins for a, to get the
+// statistics of the subscriptions. Without this option, nobody can get them.
+func WithAdminChecker(a AdminChecker) Option {
+	return func(h *Handler) {
+		h.admins = a
+	}
+}
+
+// WithNamespace uses the service s for all requests with the header
+// X-Autoupdate-Namespace set to ns. The keys in the url need the namespace as
+// prefix and the keys in the response get it.
+//
+// Each namespace should use a service with its own datastore, so the keys of
+// different environments are separated. Requests with a namespace, that was not
+// configured, are rejected.
+func WithNamespace(ns string, s autoupdate.Service) Option {
+	return func(h *Handler) {
+		if h.namespaces == nil {
+			h.namespaces = make(map[key.Namespace]autoupdate.Service)
+		}
+		h.namespaces[key.Namespace(ns)] = s
+	}
+}
+
+// WithSchema rejects requests for keys, that are not in the schema. Without
+// this option, unknown keys return null.
+func WithSchema(schema *key.Schema) Option {
+	return func(h *Handler) {
+		h.schema = schema
+	}
+}
+
+// WithMaxKeyRange sets the maximum number of ids of all id ranges like
+// user/[1-100]/name in the url of a request. The default is
+// key.DefaultMaxRange.
+func WithMaxKeyRange(max int) Option {
+	return func(h *Handler) {
+		h.maxKeyRange = max
+	}
+}
+
+// WithHeartbeat sends a ping to each subscriber in the given interval. Clients
+// have to answer each ping. If a client misses two pongs, the subscription is
+// closed. See HeartbeatChecker for the protocol.
+func WithHeartbeat(interval time.Duration) Option {
+	return func(h *Handler) {
+		h.heartbeat = NewHeartbeatChecker()
+		h.heartbeatInterval = interval
+	}
+}
+
+// WithCircuitBreaker rejects all requests with the status 503, while the
+// circuit breaker is open. See CircuitBreakerMiddleware.
+func WithCircuitBreaker(breaker CircuitBreaker) Option {
+	return func(h *Handler) {
+		h.router.Use(CircuitBreakerMiddleware(breaker))
+	}
+}
+
+// WithDuplicateRequestFilter lets identical requests, that come within ttl,
+// share one response. See DuplicateRequestFilterMiddleware.
+func WithDuplicateRequestFilter(ttl time.Duration) Option {
+	return func(h *Handler) {
+		h.router.Use(DuplicateRequestFilterMiddleware(ttl))
+	}
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicMiddleware recovers from a panic in the next handler and sends a 500
+// response.
+//
+// In debug mode, the response contains the stack trace of the panic. This
+// should only be used in development, because it shows internals of the
+// server to the client.
+func PanicMiddleware(debug bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer recoverPanic(w, debug)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// recoverPanic handles a panic of a handler. It has to be called with defer.
+//
+// If the handler already sent data, the status code can not be changed. In
+// this case, the error is written after the data.
+func recoverPanic(w http.ResponseWriter, withStack bool) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	if v == http.ErrAbortHandler {
+		// Let the server abort the response.
+		panic(v)
+	}
+
+	stack := debug.Stack()
+	log.Printf("Panic in handler: %v\n%s", v, stack)
+
+	// The body has the same format as the other errors of the handler.
+	var body struct {
+		Error struct {
+			Type  string `json:"type"`
+			Msg   string `json:"msg"`
+			Stack string `json:"stack,omitempty"`
+		} `json:"error"`
+	}
+	body.Error.Type = "InternalError"
+	body.Error.Msg = "Ups, something went wrong!"
+	if withStack {
+		body.Error.Stack = string(stack)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,91 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+// panicAuth is an authenticator that panics.
+type panicAuth struct{}
+
+func (panicAuth) Authenticate(context.Context, *http.Request) (int, error) {
+	panic("auth is broken")
+}
+
+func TestHandlerPanic(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	for _, tt := range []struct {
+		name  string
+		debug bool
+	}{
+		{"debug", true},
+		{"production", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ahttp.New(s, panicAuth{}, 0, ahttp.WithDebug(tt.debug))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate/keys?user/1/name", nil))
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("Got status %d, expected %d", rec.Code, http.StatusInternalServerError)
+			}
+
+			var body map[string]map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("Body is not valid json: %v", err)
+			}
+
+			if got := body["error"]["type"]; got != "InternalError" {
+				t.Errorf("Got error type `%s`, expected `InternalError`", got)
+			}
+
+			stack, ok := body["error"]["stack"]
+			if tt.debug && !strings.Contains(stack, "panicAuth") {
+				t.Errorf("Got stack `%s`, expected a stack trace containing panicAuth", stack)
+			}
+
+			if !tt.debug && ok {
+				t.Errorf("Got a stack trace in production mode")
+			}
+		})
+	}
+}
+
+func TestPanicMiddleware(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	handler := ahttp.PanicMiddleware(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("broken")
+	}))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Got status %d, expected %d", rec.Code, http.StatusInternalServerError)
+	}
+
+	if got, expect := rec.Body.String(), "{\"error\":{\"type\":\"InternalError\",\"msg\":\"Ups, something went wrong!\"}}\n"; got != expect {
+		t.Errorf("Got body %q, expected %q", got, expect)
+	}
+}
@@ -0,0 +1,306 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PrometheusCollector is a metric, that can be registered in a
+// PrometheusRegisterer. It writes its values in the prometheus text format.
+type PrometheusCollector interface {
+	Name() string
+	WriteMetrics(w io.Writer)
+}
+
+// PrometheusRegisterer registers metrics. It is a small version of the
+// Registerer of the prometheus client library.
+type PrometheusRegisterer interface {
+	Register(c PrometheusCollector) error
+}
+
+// PrometheusRegistry is a PrometheusRegisterer, that serves all registered
+// metrics in the prometheus text format.
+//
+// Has to be created with NewPrometheusRegistry().
+type PrometheusRegistry struct {
+	mu         sync.RWMutex
+	collectors map[string]PrometheusCollector
+}
+
+// NewPrometheusRegistry creates a PrometheusRegistry.
+func NewPrometheusRegistry() *PrometheusRegistry {
+	return &PrometheusRegistry{
+		collectors: make(map[string]PrometheusCollector),
+	}
+}
+
+// DefaultPrometheusRegistry is the registry, that is used by
+// DefaultPrometheusMiddleware().
+var DefaultPrometheusRegistry = NewPrometheusRegistry()
+
+// Register adds the collector. It returns an error, if a collector with the
+// same name is already registered.
+func (p *PrometheusRegistry) Register(c PrometheusCollector) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.collectors[c.Name()]; ok {
+		return fmt.Errorf("metric %s is already registered", c.Name())
+	}
+	p.collectors[c.Name()] = c
+	return nil
+}
+
+// ServeHTTP writes all metrics in the prometheus text format.
+func (p *PrometheusRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	names := make([]string, 0, len(p.collectors))
+	for name := range p.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]PrometheusCollector, len(names))
+	for i, name := range names {
+		collectors[i] = p.collectors[name]
+	}
+	p.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, c := range collectors {
+		c.WriteMetrics(w)
+	}
+}
+
+// PrometheusMiddleware counts the requests in the metric http_requests_total
+// and records their duration in the histogram http_request_duration_seconds.
+// Both have the labels method, path and status.
+//
+// For streaming requests, the duration is the time until the connection was
+// closed.
+//
+// It panics, if the metrics can not be registered, for example when they are
+// already registered in reg.
+func PrometheusMiddleware(reg PrometheusRegisterer) func(http.Handler) http.Handler {
+	labels := []string{"method", "path", "status"}
+	requests := newCounterVec("http_requests_total", "Number of handled requests.", labels)
+	durations := newHistogramVec("http_request_duration_seconds", "Duration of the requests.", latencyBuckets, labels)
+
+	for _, c := range []PrometheusCollector{requests, durations} {
+		if err := reg.Register(c); err != nil {
+			panic(fmt.Sprintf("register prometheus metric: %v", err))
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			values := []string{r.Method, r.URL.Path, strconv.Itoa(status)}
+			requests.inc(values...)
+			durations.observe(time.Since(start).Seconds(), values...)
+		})
+	}
+}
+
+var (
+	defaultPrometheusOnce       sync.Once
+	defaultPrometheusMiddleware func(http.Handler) http.Handler
+)
+
+// DefaultPrometheusMiddleware is like PrometheusMiddleware() with the
+// DefaultPrometheusRegistry. The metrics are only registered once, so it can
+// be called more then once.
+func DefaultPrometheusMiddleware() func(http.Handler) http.Handler {
+	defaultPrometheusOnce.Do(func() {
+		defaultPrometheusMiddleware = PrometheusMiddleware(DefaultPrometheusRegistry)
+	})
+	return defaultPrometheusMiddleware
+}
+
+// statusWriter remembers the status of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush sends the buffered data to the client.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// metricSeries are the values of one combination of label values.
+type metricSeries struct {
+	labels  string
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+// metricVec holds the series of a metric with labels. Like the paths of the
+// PrometheusMetricsRegistry, at most maxMetricPaths series are recorded
+// separately. All others are recorded with the label values "other".
+type metricVec struct {
+	name       string
+	help       string
+	labelNames []string
+	numBuckets int
+
+	mu     sync.Mutex
+	series map[string]*metricSeries
+}
+
+// get returns the series for the label values. It has to be called with the
+// lock.
+func (m *metricVec) get(values []string) *metricSeries {
+	labels := m.labels(values)
+	if s, ok := m.series[labels]; ok {
+		return s
+	}
+
+	if len(m.series) >= maxMetricPaths {
+		other := make([]string, len(values))
+		for i := range other {
+			other[i] = "other"
+		}
+		labels = m.labels(other)
+		if s, ok := m.series[labels]; ok {
+			return s
+		}
+	}
+
+	s := &metricSeries{labels: labels, buckets: make([]uint64, m.numBuckets)}
+	m.series[labels] = s
+	return s
+}
+
+// labels returns the labels in the prometheus text format without the braces.
+func (m *metricVec) labels(values []string) string {
+	parts := make([]string, len(m.labelNames))
+	for i, name := range m.labelNames {
+		parts[i] = name + "=" + labelValue(values[i])
+	}
+	return strings.Join(parts, ",")
+}
+
+// sorted returns a copy of all series sorted by their labels.
+func (m *metricVec) sorted() []metricSeries {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	series := make([]metricSeries, 0, len(m.series))
+	for _, s := range m.series {
+		c := *s
+		c.buckets = append([]uint64(nil), s.buckets...)
+		series = append(series, c)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].labels < series[j].labels })
+	return series
+}
+
+// Name returns the name of the metric.
+func (m *metricVec) Name() string {
+	return m.name
+}
+
+// counterVec is a counter with labels.
+type counterVec struct {
+	metricVec
+}
+
+func newCounterVec(name, help string, labelNames []string) *counterVec {
+	return &counterVec{metricVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		series:     make(map[string]*metricSeries),
+	}}
+}
+
+// inc increases the counter for the label values by one.
+func (c *counterVec) inc(values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(values).count++
+}
+
+// WriteMetrics writes the counter in the prometheus text format.
+func (c *counterVec) WriteMetrics(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", c.name, c.help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+	for _, s := range c.sorted() {
+		fmt.Fprintf(w, "%s{%s} %d\n", c.name, s.labels, s.count)
+	}
+}
+
+// histogramVec is a histogram with labels.
+type histogramVec struct {
+	metricVec
+	bounds []float64
+}
+
+func newHistogramVec(name, help string, bounds []float64, labelNames []string) *histogramVec {
+	return &histogramVec{
+		metricVec: metricVec{
+			name:       name,
+			help:       help,
+			labelNames: labelNames,
+			numBuckets: len(bounds),
+			series:     make(map[string]*metricSeries),
+		},
+		bounds: bounds,
+	}
+}
+
+// observe records one value for the label values.
+func (h *histogramVec) observe(value float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.get(values)
+	s.count++
+	s.sum += value
+	for i, bound := range h.bounds {
+		if value <= bound {
+			s.buckets[i]++
+			break
+		}
+	}
+}
+
+// WriteMetrics writes the histogram in the prometheus text format.
+func (h *histogramVec) WriteMetrics(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", h.name, h.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+	for _, s := range h.sorted() {
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%g\"} %d\n", h.name, s.labels, bound, cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", h.name, s.labels, s.count)
+		fmt.Fprintf(w, "%s_sum{%s} %g\n", h.name, s.labels, s.sum)
+		fmt.Fprintf(w, "%s_count{%s} %d\n", h.name, s.labels, s.count)
+	}
+}
@@ -0,0 +1,66 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestPrometheusMiddleware(t *testing.T) {
+	registry := ahttp.NewPrometheusRegistry()
+	handler := ahttp.PrometheusMiddleware(registry)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/system/autoupdate", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/missing", nil))
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, line := range []string{
+		`# TYPE http_requests_total counter`,
+		`http_requests_total{method="GET",path="/system/autoupdate",status="200"} 1`,
+		`http_requests_total{method="POST",path="/missing",status="404"} 1`,
+		`# TYPE http_request_duration_seconds histogram`,
+		`http_request_duration_seconds_bucket{method="GET",path="/system/autoupdate",status="200",le="+Inf"} 1`,
+		`http_request_duration_seconds_count{method="POST",path="/missing",status="404"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Metrics do not contain `%s`:\n%s", line, body)
+		}
+	}
+}
+
+func TestPrometheusMiddlewareRegisterTwice(t *testing.T) {
+	registry := ahttp.NewPrometheusRegistry()
+	ahttp.PrometheusMiddleware(registry)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Second PrometheusMiddleware() with the same registry did not panic")
+		}
+	}()
+	ahttp.PrometheusMiddleware(registry)
+}
+
+func TestDefaultPrometheusMiddleware(t *testing.T) {
+	handler := ahttp.DefaultPrometheusMiddleware()(okHandler)
+	ahttp.DefaultPrometheusMiddleware()(okHandler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/default/second", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/default", nil))
+
+	rec := httptest.NewRecorder()
+	ahttp.DefaultPrometheusRegistry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if line := `http_requests_total{method="GET",path="/default",status="200"} `; !strings.Contains(rec.Body.String(), line) {
+		t.Errorf("Default registry does not contain `%s`:\n%s", line, rec.Body.String())
+	}
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// QueryParamValidationMiddleware rejects requests with a query parameter,
+// that is not in the allowed list, with the status 400.
+//
+// Only parameters with a value (name=value) are checked. Parts of the query
+// without an equal sign, like the key list of the keys url, are not
+// parameters and are ignored.
+func QueryParamValidationMiddleware(allowed ...string) func(http.Handler) http.Handler {
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		allowedSet[name] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, part := range strings.Split(r.URL.RawQuery, "&") {
+				i := strings.Index(part, "=")
+				if i == -1 {
+					continue
+				}
+
+				name, err := url.QueryUnescape(part[:i])
+				if err != nil {
+					name = part[:i]
+				}
+
+				if !allowedSet[name] {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprintf(w, `{"error": {"type": "SyntaxError", "msg": "%s"}}`, quote("unexpected query parameter: "+name))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
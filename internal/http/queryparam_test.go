@@ -0,0 +1,69 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestQueryParamValidationMiddleware(t *testing.T) {
+	handler := ahttp.QueryParamValidationMiddleware("limit")(http.HandlerFunc(okHandler))
+
+	for _, tt := range []struct {
+		query  string
+		status int
+		errMsg string
+	}{
+		{"", http.StatusOK, ""},
+		{"limit=5", http.StatusOK, ""},
+		{"user/1/name,user/2/name", http.StatusOK, ""},
+		{"keyes=user/1/name", http.StatusBadRequest, "unexpected query parameter: keyes"},
+		{"limit=5&foo=bar", http.StatusBadRequest, "unexpected query parameter: foo"},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/?"+tt.query, nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if tt.errMsg == "" {
+				return
+			}
+
+			var body map[string]map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("Got invalid json: %v", err)
+			}
+
+			if got := body["error"]["type"]; got != "SyntaxError" {
+				t.Errorf("Got error type `%s`, expected `SyntaxError`", got)
+			}
+
+			if got := body["error"]["msg"]; got != tt.errMsg {
+				t.Errorf("Got error message `%s`, expected `%s`", got, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestHandlerUnexpectedQueryParam(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	handler := ahttp.New(s, mockAuth{1}, 0)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate/keys?keyes=user/1/name", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Got status %d, expected %d", rec.Code, http.StatusBadRequest)
+	}
+}
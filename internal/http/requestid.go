@@ -0,0 +1,57 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// requestIDHeader is the header, that holds the id of a request.
+const requestIDHeader = "X-Request-ID"
+
+// InjectRequestIDMiddleware gives each request an id, that can be used to
+// correlate the logs of different services.
+//
+// The id is read from the header X-Request-ID. If the header is missing, a
+// random uuid is created. The id is saved in the context of the request and
+// is sent back to the client in the header X-Request-ID.
+func InjectRequestIDMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestIDHeader)
+			if id == "" {
+				var err error
+				id, err = newUUID()
+				if err != nil {
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			w.Header().Set(requestIDHeader, id)
+			ctx := context.WithValue(r.Context(), requestIDKey, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestID returns the id of the request, that was set by
+// InjectRequestIDMiddleware. Returns an empty string, if there is no id.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// newUUID returns a random uuid in the version 4 of RFC 4122.
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", fmt.Errorf("read random bytes: %w", err)
+	}
+
+	u[6] = (u[6] & 0x0f) | 0x40 // Version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // Variant RFC 4122
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
@@ -0,0 +1,55 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+// uuidV4 matches a uuid in the version 4 of RFC 4122.
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestInjectRequestIDMiddleware(t *testing.T) {
+	var ctxID string
+	handler := ahttp.InjectRequestIDMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxID = ahttp.RequestID(r.Context())
+	}))
+
+	t.Run("missing header", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate", nil))
+
+		got := rec.Header().Get("X-Request-ID")
+		if !uuidV4.MatchString(got) {
+			t.Errorf("Got request id `%s`, expected a uuid v4", got)
+		}
+
+		if ctxID != got {
+			t.Errorf("Got request id `%s` in the context, expected `%s`", ctxID, got)
+		}
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate", nil))
+		if second := rec.Header().Get("X-Request-ID"); second == got {
+			t.Errorf("Got the same request id for two requests")
+		}
+	})
+
+	t.Run("present header", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/system/autoupdate", nil)
+		req.Header.Set("X-Request-ID", "my-id")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("X-Request-ID"); got != "my-id" {
+			t.Errorf("Got request id `%s`, expected `my-id`", got)
+		}
+
+		if ctxID != "my-id" {
+			t.Errorf("Got request id `%s` in the context, expected `my-id`", ctxID)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// errResponseTooLarge is returned by the ResponseWriter of
+// ResponseSizeLimitMiddleware, when the response exceeds the limit.
+var errResponseTooLarge = errors.New("response exceeds the size limit")
+
+// ResponseSizeLimitMiddleware limits the size of the response body to
+// maxBytes. This protects clients from huge responses, for example when a
+// request with a wildcard expands to many keys.
+//
+// The response is buffered until the handler flushes it. If the limit is
+// exceeded before that, the status 500 is returned. If the limit is exceeded
+// in the middle of a stream, the connection is closed, so the client sees an
+// incomplete response.
+func ResponseSizeLimitMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The context is canceled, when the limit is exceeded, so a
+			// streaming handler stops.
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+
+			info := new(responseInfo)
+			ctx = context.WithValue(ctx, responseInfoKey, info)
+
+			lw := &sizeLimitWriter{ResponseWriter: w, max: maxBytes, cancel: cancel}
+			next.ServeHTTP(lw, r.WithContext(ctx))
+
+			if !lw.exceeded {
+				lw.commit()
+				return
+			}
+
+			log.Printf("Warning: Response %s %s for user %d with %d keys exceeded the size limit of %d bytes", r.Method, r.URL.Path, info.uid, info.keys, maxBytes)
+
+			if lw.committed {
+				panic(http.ErrAbortHandler)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, `{"error": {"type": "InternalError", "msg": "%s"}}`, quote(errResponseTooLarge.Error()))
+		})
+	}
+}
+
+// responseInfo holds information about a request that are logged by
+// ResponseSizeLimitMiddleware.
+type responseInfo struct {
+	uid  int
+	keys int
+}
+
+// setResponseInfo saves the user id and the number of requested keys for the
+// log message of ResponseSizeLimitMiddleware. Nothing happens, if the
+// middleware is not used.
+func setResponseInfo(ctx context.Context, uid int, keys int) {
+	if info, ok := ctx.Value(responseInfoKey).(*responseInfo); ok {
+		info.uid = uid
+		info.keys = keys
+	}
+}
+
+// sizeLimitWriter is a http.ResponseWriter that counts the written bytes. If
+// more then max bytes are written, all writes fail and cancel is called.
+//
+// The status and the data are buffered until Flush is called, so a response
+// that exceeds the limit before the first flush can still get an error
+// status.
+type sizeLimitWriter struct {
+	http.ResponseWriter
+	max    int64
+	cancel context.CancelFunc
+
+	buf       bytes.Buffer
+	status    int
+	written   int64
+	committed bool
+	exceeded  bool
+}
+
+func (w *sizeLimitWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *sizeLimitWriter) Write(p []byte) (int, error) {
+	if w.exceeded {
+		return 0, errResponseTooLarge
+	}
+
+	if w.written+int64(len(p)) > w.max {
+		w.exceeded = true
+		w.cancel()
+		return 0, errResponseTooLarge
+	}
+
+	w.written += int64(len(p))
+	return w.buf.Write(p)
+}
+
+// Flush sends the buffered data to the client.
+func (w *sizeLimitWriter) Flush() {
+	if w.exceeded {
+		return
+	}
+
+	w.commit()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// commit writes the status and the buffered data to the wrapped
+// ResponseWriter.
+func (w *sizeLimitWriter) commit() {
+	if !w.committed {
+		w.committed = true
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+	}
+
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
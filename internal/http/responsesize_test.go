@@ -0,0 +1,94 @@
+package http_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestResponseSizeLimitMiddleware(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	handler := ahttp.New(s, mockAuth{1}, 0)
+
+	body := `{"user/1/name":"Hello World"}` + "\n"
+
+	for _, tt := range []struct {
+		name   string
+		limit  int64
+		status int
+		body   string
+	}{
+		{"at limit", int64(len(body)), http.StatusOK, body},
+		{"over limit", int64(len(body)) - 1, http.StatusInternalServerError, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ahttp.ResponseSizeLimitMiddleware(tt.limit)(handler).ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate/once?user/1/name", nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("Got body `%s`, expected `%s`", rec.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestResponseSizeLimitMiddlewareStream(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	// The limit allows the first frame but not the second.
+	first := `{"user/1/name":"Hello World"}` + "\n"
+	srv := httptest.NewServer(ahttp.ResponseSizeLimitMiddleware(int64(len(first)) + 5)(ahttp.New(s, mockAuth{1}, 0)))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/system/autoupdate/keys?user/1/name", nil)
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Can not read first frame: %v", err)
+	}
+	if line != first {
+		t.Errorf("Got first frame `%s`, expected `%s`", line, first)
+	}
+
+	datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(`"new value"`)})
+	datastore.Send(test.Str("user/1/name"))
+
+	rest, err := ioutil.ReadAll(reader)
+	if err == nil {
+		t.Errorf("Response ended without an error after the limit, got `%s`", rest)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("Connection was not closed after the limit")
+	}
+}
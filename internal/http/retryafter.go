@@ -0,0 +1,59 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryAfterMiddleware adds the header Retry-After to responses with the
+// status 429 or 503, so clients do not retry immediately.
+//
+// getRetryAfter is called for each of these responses and returns the time, a
+// client should wait. For example, it can ask a CircuitBreaker or a rate
+// limiter. The header contains the duration in seconds, rounded up. If
+// getRetryAfter returns zero or less, the header is not set.
+func RetryAfterMiddleware(getRetryAfter func() time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&retryAfterWriter{ResponseWriter: w, getRetryAfter: getRetryAfter}, r)
+		})
+	}
+}
+
+// retryAfterWriter sets the header Retry-After, when the status is written.
+type retryAfterWriter struct {
+	http.ResponseWriter
+	getRetryAfter func() time.Duration
+	wroteHeader   bool
+}
+
+func (w *retryAfterWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable {
+		if d := w.getRetryAfter(); d > 0 {
+			seconds := int(math.Ceil(d.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *retryAfterWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends the buffered data to the client.
+func (w *retryAfterWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,66 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestRetryAfterMiddleware(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		status     int
+		retryAfter time.Duration
+		expect     string
+	}{
+		{"service unavailable", http.StatusServiceUnavailable, 5 * time.Second, "5"},
+		{"too many requests", http.StatusTooManyRequests, 2 * time.Second, "2"},
+		{"rounded up", http.StatusTooManyRequests, 1500 * time.Millisecond, "2"},
+		{"no duration", http.StatusServiceUnavailable, 0, ""},
+		{"ok", http.StatusOK, 5 * time.Second, ""},
+		{"other error", http.StatusInternalServerError, 5 * time.Second, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ahttp.RetryAfterMiddleware(func() time.Duration { return tt.retryAfter })(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(tt.status)
+					w.Write([]byte("body"))
+				}),
+			)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate", nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if got := rec.Header().Get("Retry-After"); got != tt.expect {
+				t.Errorf("Got Retry-After `%s`, expected `%s`", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestRetryAfterMiddlewareImplicitOK(t *testing.T) {
+	called := false
+	handler := ahttp.RetryAfterMiddleware(func() time.Duration {
+		called = true
+		return time.Second
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/system/autoupdate", nil))
+
+	if rec.Header().Get("Retry-After") != "" {
+		t.Errorf("Got a Retry-After header on a 200 response")
+	}
+	if called {
+		t.Errorf("getRetryAfter was called for a 200 response")
+	}
+}
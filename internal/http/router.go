@@ -0,0 +1,147 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// prefixRouter sends requests to the handler with the longest matching url
+// prefix.
+type prefixRouter struct {
+	// prefixes is sorted by length, longest first.
+	prefixes []string
+	routes   map[string]http.Handler
+}
+
+// NewPrefixRouter returns a handler that multiplexes requests by the prefix of
+// the url path. This can be used to serve more then one autoupdate service,
+// for example with different schemas, from one binary.
+//
+// The longest matching prefix is used. A prefix only matches whole path
+// segments, so "/v1" matches "/v1/system/autoupdate" but not
+// "/v10/system/autoupdate". The prefix is removed from the path before the
+// request is sent to the handler.
+//
+// Requests without a matching prefix get a 404.
+func NewPrefixRouter(routes map[string]http.Handler) http.Handler {
+	r := prefixRouter{routes: make(map[string]http.Handler, len(routes))}
+	for prefix, handler := range routes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		r.prefixes = append(r.prefixes, prefix)
+		r.routes[prefix] = handler
+	}
+
+	sort.Slice(r.prefixes, func(i, j int) bool {
+		return len(r.prefixes[i]) > len(r.prefixes[j])
+	})
+	return r
+}
+
+func (p prefixRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, prefix := range p.prefixes {
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			continue
+		}
+
+		rest := r.URL.Path[len(prefix):]
+		if rest != "" && rest[0] != '/' {
+			continue
+		}
+
+		http.StripPrefix(prefix, p.routes[prefix]).ServeHTTP(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// Router sends requests to the handler, that was registered for the url path
+// and the method of the request.
+//
+// Requests to an unknown path get a 404. Requests with a method, that is not
+// registered for the path, get a 405 with the allowed methods in the header
+// Allow (see AllowedMethodsMiddleware).
+//
+// All routes and middleware have to be registered before the router is used.
+type Router struct {
+	routes     map[string]*route
+	middleware []func(http.Handler) http.Handler
+
+	// handler is the global middleware around rt.serveRoute. It is built,
+	// when middleware is added.
+	handler http.Handler
+}
+
+// route holds the handlers of one url path.
+type route struct {
+	// methods holds the handlers by method.
+	methods map[string]http.Handler
+
+	// handler calls the handler for the method of the request. It is wrapped
+	// with AllowedMethodsMiddleware.
+	handler http.Handler
+}
+
+// NewRouter creates an empty Router.
+func NewRouter() *Router {
+	rt := &Router{
+		routes: make(map[string]*route),
+	}
+	rt.handler = http.HandlerFunc(rt.serveRoute)
+	return rt
+}
+
+// Handle registers the handler for the method and the url path pattern. The
+// pattern has to match the path exactly.
+//
+// The middleware only runs for this route. The first middleware is the outer
+// most one.
+func (rt *Router) Handle(method, pattern string, h http.Handler, middleware ...func(http.Handler) http.Handler) {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+
+	rc := rt.routes[pattern]
+	if rc == nil {
+		rc = &route{methods: make(map[string]http.Handler)}
+		rt.routes[pattern] = rc
+	}
+	rc.methods[method] = h
+
+	allowed := make([]string, 0, len(rc.methods))
+	for method := range rc.methods {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+
+	methods := rc.methods
+	rc.handler = AllowedMethodsMiddleware(allowed...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods[r.Method].ServeHTTP(w, r)
+	}))
+}
+
+// Use adds middleware, that runs for all requests, also for requests without a
+// matching route. The first middleware is the outer most one.
+func (rt *Router) Use(middleware ...func(http.Handler) http.Handler) {
+	rt.middleware = append(rt.middleware, middleware...)
+
+	var h http.Handler = http.HandlerFunc(rt.serveRoute)
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		h = rt.middleware[i](h)
+	}
+	rt.handler = h
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.handler.ServeHTTP(w, r)
+}
+
+// serveRoute calls the handler of the request without the global middleware.
+func (rt *Router) serveRoute(w http.ResponseWriter, r *http.Request) {
+	rc, ok := rt.routes[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	rc.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,137 @@
+package http_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestPrefixRouter(t *testing.T) {
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + " " + r.URL.Path))
+		})
+	}
+
+	router := ahttp.NewPrefixRouter(map[string]http.Handler{
+		"/v1":    handler("v1"),
+		"/v2":    handler("v2"),
+		"/v2/db": handler("v2db"),
+	})
+
+	for _, tt := range []struct {
+		url    string
+		status int
+		body   string
+	}{
+		{"/v1/system/autoupdate", http.StatusOK, "v1 /system/autoupdate"},
+		{"/v2/system/autoupdate", http.StatusOK, "v2 /system/autoupdate"},
+		{"/v2/db/system/autoupdate", http.StatusOK, "v2db /system/autoupdate"},
+		{"/v3/system/autoupdate", http.StatusNotFound, ""},
+		{"/v10/system/autoupdate", http.StatusNotFound, ""},
+		{"/system/autoupdate", http.StatusNotFound, ""},
+	} {
+		t.Run(tt.url, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", tt.url, nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if tt.body == "" {
+				return
+			}
+
+			body, _ := ioutil.ReadAll(rec.Body)
+			if string(body) != tt.body {
+				t.Errorf("Got body `%s`, expected `%s`", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestRouter(t *testing.T) {
+	var calls []string
+	middleware := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, name)
+		})
+	}
+
+	router := ahttp.NewRouter()
+	router.Use(middleware("global1"), middleware("global2"))
+	router.Handle(http.MethodGet, "/a", handler("a"), middleware("route1"), middleware("route2"))
+	router.Handle(http.MethodGet, "/b", handler("b"))
+	router.Handle(http.MethodPost, "/b", handler("b post"))
+
+	for _, tt := range []struct {
+		method string
+		url    string
+		status int
+		calls  string
+	}{
+		{"GET", "/a", http.StatusOK, "global1 global2 route1 route2 a"},
+		{"GET", "/b", http.StatusOK, "global1 global2 b"},
+		{"POST", "/b", http.StatusOK, "global1 global2 b post"},
+		{"POST", "/a", http.StatusMethodNotAllowed, "global1 global2"},
+		{"GET", "/c", http.StatusNotFound, "global1 global2"},
+	} {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			calls = nil
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("Got status %d, expected %d", rec.Code, tt.status)
+			}
+
+			if got := strings.Join(calls, " "); got != tt.calls {
+				t.Errorf("Got calls `%s`, expected `%s`", got, tt.calls)
+			}
+		})
+	}
+}
+
+func TestRouterAllowHeader(t *testing.T) {
+	router := ahttp.NewRouter()
+	router.Handle(http.MethodPost, "/a", http.NotFoundHandler())
+	router.Handle(http.MethodGet, "/a", http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("DELETE", "/a", nil))
+
+	if got := rec.Header().Get("Allow"); got != "GET, POST" {
+		t.Errorf("Got Allow header `%s`, expected `GET, POST`", got)
+	}
+}
+
+func TestRouterBuildsMiddlewareOnce(t *testing.T) {
+	var built int
+	router := ahttp.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		built++
+		return next
+	})
+	router.Handle(http.MethodGet, "/a", http.NotFoundHandler())
+
+	for i := 0; i < 3; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a", nil))
+	}
+
+	if built != 1 {
+		t.Errorf("Middleware was built %d times, expected 1", built)
+	}
+}
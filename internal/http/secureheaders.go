@@ -0,0 +1,20 @@
+package http
+
+import "net/http"
+
+// SecureHeadersMiddleware sets headers on each response, that tell browsers
+// to not interpret the response as something else then the service sends.
+// The responses are never embedded in an other page, do not load other
+// resources and do not send a referrer.
+func SecureHeadersMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("Content-Security-Policy", "default-src 'none'")
+			h.Set("Referrer-Policy", "no-referrer")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestSecureHeadersMiddleware(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	srv := httptest.NewServer(ahttp.SecureHeadersMiddleware()(ahttp.New(s, mockAuth{1}, 0)))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		name string
+		url  string
+		body string
+	}{
+		{"keys", "/system/autoupdate/keys?user/1/name", ""},
+		{"autoupdate", "/system/autoupdate", `[{"ids":[1],"collection":"user","fields":{"name":null}}]`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+tt.url, strings.NewReader(tt.body))
+			if tt.body == "" {
+				req, err = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+tt.url, nil)
+			}
+			if err != nil {
+				t.Fatalf("Can not create request: %v", err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Can not send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			for header, expect := range map[string]string{
+				"X-Content-Type-Options":  "nosniff",
+				"X-Frame-Options":         "DENY",
+				"Content-Security-Policy": "default-src 'none'",
+				"Referrer-Policy":         "no-referrer",
+			} {
+				if got := resp.Header.Get(header); got != expect {
+					t.Errorf("Got %s `%s`, expected `%s`", header, got, expect)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestSizeLimiterMiddleware limits the bytes per second, that are read from
+// request bodies of one client ip.
+//
+// It uses a token bucket for each ip that can hold the tokens for one second.
+// If the bucket is empty, reading the body blocks until there are new tokens.
+// All requests, where the ip can not be parsed, share one bucket.
+//
+// The ip is only read from the X-Forwarded-For header, if the request was sent
+// by a trusted proxy. See TrustedProxyMiddleware.
+func RequestSizeLimiterMiddleware(bytesPerSecond int, options ...SizeLimiterOption) func(http.Handler) http.Handler {
+	buckets := newBucketStore(bytesPerSecond)
+	for _, o := range options {
+		o(buckets)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				var key string
+				if ip := realIP(r); ip != nil {
+					key = ip.String()
+				}
+
+				r.Body = &limitedBody{
+					ReadCloser: r.Body,
+					ctx:        r.Context(),
+					bucket:     buckets.get(key),
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SizeLimiterOption is an optional argument for
+// RequestSizeLimiterMiddleware().
+type SizeLimiterOption func(*bucketStore)
+
+// WithSizeLimiterClock sets the clock that is used to refill the buckets. The
+// default is the system time. It can be used in tests.
+func WithSizeLimiterClock(c Clock) SizeLimiterOption {
+	return func(s *bucketStore) {
+		s.clock = c
+	}
+}
+
+// systemClock is the Clock that uses the system time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// limitedBody is a request body that takes a token from the bucket for each
+// byte that is read.
+type limitedBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	bucket *tokenBucket
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return b.ReadCloser.Read(p)
+	}
+
+	allowed, err := b.bucket.take(b.ctx, len(p))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := b.ReadCloser.Read(p[:allowed])
+	b.bucket.giveBack(allowed - n)
+	return n, err
+}
+
+// bucketStore holds the token buckets for each ip.
+type bucketStore struct {
+	rate  int
+	clock Clock
+
+	mu          sync.Mutex
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+}
+
+func newBucketStore(rate int) *bucketStore {
+	return &bucketStore{
+		rate:    rate,
+		clock:   systemClock{},
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// get returns the bucket for an ip. Buckets that are full are removed from
+// time to time, since they are the same as a new bucket.
+func (s *bucketStore) get(ip string) *tokenBucket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if s.lastCleanup.IsZero() {
+		s.lastCleanup = now
+	}
+
+	if now.Sub(s.lastCleanup) > time.Minute {
+		for k, b := range s.buckets {
+			if b.full(now) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastCleanup = now
+	}
+
+	b, ok := s.buckets[ip]
+	if !ok {
+		b = &tokenBucket{rate: float64(s.rate), clock: s.clock, tokens: float64(s.rate), last: now}
+		s.buckets[ip] = b
+	}
+	return b
+}
+
+// tokenBucket is refilled with rate tokens per second. It can hold at most
+// rate tokens.
+type tokenBucket struct {
+	rate  float64
+	clock Clock
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens since the last call. Has to be called with the lock.
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+}
+
+// take removes up to max tokens from the bucket and returns the amount. If the
+// bucket has less then one token, it blocks until there is one or the context
+// is done.
+func (b *tokenBucket) take(ctx context.Context, max int) (int, error) {
+	for {
+		b.mu.Lock()
+		b.refill(b.clock.Now())
+		if b.tokens >= 1 {
+			n := int(b.tokens)
+			if n > max {
+				n = max
+			}
+			b.tokens -= float64(n)
+			b.mu.Unlock()
+			return n, nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		select {
+		case <-b.clock.After(wait):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// giveBack returns unused tokens to the bucket.
+func (b *tokenBucket) giveBack(n int) {
+	if n <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += float64(n)
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+}
+
+func (b *tokenBucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	return b.tokens >= b.rate
+}
@@ -0,0 +1,88 @@
+package http_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestRequestSizeLimiter(t *testing.T) {
+	const rate = 100000
+
+	readBody := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.Copy(ioutil.Discard, r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	// post sends a request and returns the time on the clock, that the handler
+	// needed.
+	post := func(t *testing.T, h http.Handler, clock *fakeClock, req *http.Request) time.Duration {
+		rec := httptest.NewRecorder()
+		start := clock.Now()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Got status %d, expected 200", rec.Code)
+		}
+		return clock.Now().Sub(start)
+	}
+
+	request := func(ip string, size int) *http.Request {
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(make([]byte, size)))
+		req.RemoteAddr = ip + ":12345"
+		return req
+	}
+
+	t.Run("bursty sender", func(t *testing.T) {
+		clock := newFakeClock()
+		handler := ahttp.RequestSizeLimiterMiddleware(rate, ahttp.WithSizeLimiterClock(clock))(readBody)
+
+		// The first second is in the bucket. The rest has to wait.
+		if d := post(t, handler, clock, request("10.0.0.1", rate+rate/5)); d < 190*time.Millisecond || d > 210*time.Millisecond {
+			t.Errorf("Burst was read in %v, expected 200ms", d)
+		}
+
+		// Other ips are not affected.
+		if d := post(t, handler, clock, request("10.0.0.2", rate/2)); d != 0 {
+			t.Errorf("Request from other ip took %v, expected no delay", d)
+		}
+	})
+
+	t.Run("steady sender", func(t *testing.T) {
+		clock := newFakeClock()
+		handler := ahttp.RequestSizeLimiterMiddleware(rate, ahttp.WithSizeLimiterClock(clock))(readBody)
+
+		var total time.Duration
+		for i := 0; i < 5; i++ {
+			total += post(t, handler, clock, request("10.0.0.1", rate/10))
+			clock.Advance(100 * time.Millisecond)
+		}
+
+		if total != 0 {
+			t.Errorf("Steady requests took %v, expected no delay", total)
+		}
+	})
+
+	t.Run("rotating X-Forwarded-For", func(t *testing.T) {
+		clock := newFakeClock()
+		handler := ahttp.RequestSizeLimiterMiddleware(rate, ahttp.WithSizeLimiterClock(clock))(readBody)
+
+		// The client is not a trusted proxy, so the header is ignored and
+		// both requests use the same bucket.
+		req := request("10.0.0.1", rate)
+		req.Header.Set("X-Forwarded-For", "192.168.0.1")
+		post(t, handler, clock, req)
+
+		req = request("10.0.0.1", rate/5)
+		req.Header.Set("X-Forwarded-For", "192.168.0.2")
+		if d := post(t, handler, clock, req); d < 190*time.Millisecond {
+			t.Errorf("Request with other X-Forwarded-For took %v, expected 200ms", d)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+)
+
+// subscriptionStats returns the statistics of all running subscriptions as
+// json list.
+//
+// Only admins can see the statistics. Without an AdminChecker, the request is
+// always forbidden.
+func (h *Handler) subscriptionStats(w http.ResponseWriter, r *http.Request) error {
+	uid, err := h.auth.Authenticate(r.Context(), r)
+	if err != nil {
+		return fmt.Errorf("authenticate request: %w", err)
+	}
+
+	if h.admins == nil {
+		return forbiddenError{}
+	}
+
+	admin, err := h.admins.IsAdmin(r.Context(), uid)
+	if err != nil {
+		return fmt.Errorf("check admin: %w", err)
+	}
+
+	if !admin {
+		return forbiddenError{}
+	}
+
+	writeStats(w, h.registries())
+	return nil
+}
+
+// registries returns the subscriber registries of the default service and
+// the services of all namespaces. Services that share a registry, like a
+// FilteredService and its inner service, are only returned once.
+func (h *Handler) registries() []*autoupdate.SubscriberRegistry {
+	seen := make(map[*autoupdate.SubscriberRegistry]bool)
+	var registries []*autoupdate.SubscriberRegistry
+	add := func(s autoupdate.Service) {
+		r := s.Subscribers()
+		if !seen[r] {
+			seen[r] = true
+			registries = append(registries, r)
+		}
+	}
+
+	add(h.s)
+	for _, s := range h.namespaces {
+		add(s)
+	}
+	return registries
+}
+
+// writeStats writes the stats of all subscriptions to w.
+func writeStats(w http.ResponseWriter, registries []*autoupdate.SubscriberRegistry) {
+	stats := make([]autoupdate.SubscriptionStats, 0)
+	for _, r := range registries {
+		for _, sub := range r.List() {
+			stats = append(stats, sub.Connection.Stats())
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		log.Printf("Can not send subscription stats: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package http_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestSubscriptionStats(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+	srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0, ahttp.WithAdminChecker(mockAdmins{1})))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/system/autoupdate/keys?user/1/name", nil)
+	if err != nil {
+		t.Fatalf("Can not create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	stream := bufio.NewReader(resp.Body)
+	var read int
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			datastore.Update(map[string]json.RawMessage{"user/1/name": []byte(fmt.Sprintf(`"value %d"`, i))})
+			datastore.Send(test.Str("user/1/name"))
+		}
+
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Can not read frame %d: %v", i, err)
+		}
+		read += len(line)
+	}
+
+	statsResp, err := http.Get(srv.URL + "/system/autoupdate/subscriptions")
+	if err != nil {
+		t.Fatalf("Can not get subscriptions: %v", err)
+	}
+	defer statsResp.Body.Close()
+
+	var stats []autoupdate.SubscriptionStats
+	if err := json.NewDecoder(statsResp.Body).Decode(&stats); err != nil {
+		t.Fatalf("Got invalid json: %v", err)
+	}
+
+	if len(stats) != 1 {
+		t.Fatalf("Got %d subscriptions, expected 1", len(stats))
+	}
+
+	if stats[0].UpdatesSent != 3 {
+		t.Errorf("UpdatesSent is %d, expected 3", stats[0].UpdatesSent)
+	}
+
+	if stats[0].BytesWritten != uint64(read) {
+		t.Errorf("BytesWritten is %d, expected %d", stats[0].BytesWritten, read)
+	}
+}
+
+func TestSubscriptionStatsForbidden(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	for _, tt := range []struct {
+		name    string
+		options []ahttp.Option
+	}{
+		{"no admin checker", nil},
+		{"no admin", []ahttp.Option{ahttp.WithAdminChecker(mockAdmins{2})}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(ahttp.New(s, mockAuth{1}, 0, tt.options...))
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL + "/system/autoupdate/subscriptions")
+			if err != nil {
+				t.Fatalf("Can not get subscriptions: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusForbidden {
+				t.Errorf("Got status %s, expected %s", resp.Status, http.StatusText(http.StatusForbidden))
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutMiddleware limits the time of each request to the duration d. The
+// context of the request gets a deadline. If the handler does not return in
+// time, for example because it does not watch the context, an error with the
+// message msg is sent to the client and the connection is closed.
+//
+// If the handler did not send anything, the error is sent with the status
+// 503. Otherwise, the error is sent as last frame of the stream.
+func TimeoutMiddleware(d time.Duration, msg string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutGuardWriter{w: w, h: make(http.Header)}
+			done := make(chan struct{})
+			panicChan := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicChan:
+				panic(p)
+
+			case <-done:
+
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+
+				body := fmt.Sprintf(`{"error": {"type": "TimeoutError", "msg": "%s"}}`+"\n", quote(msg))
+				if !tw.wroteHeader {
+					w.Header().Set("Content-Type", "application/json")
+					w.Header().Set("Connection", "close")
+					w.WriteHeader(http.StatusServiceUnavailable)
+					w.Write([]byte(body))
+					return
+				}
+
+				w.Write([]byte(body))
+				if f, ok := w.(http.Flusher); ok {
+					f.Flush()
+				}
+				panic(http.ErrAbortHandler)
+			}
+		})
+	}
+}
+
+// timeoutGuardWriter is a http.ResponseWriter that can not be used anymore,
+// after the TimeoutMiddleware has sent the timeout error.
+//
+// The handler gets its own header map. It is copied to the wrapped
+// ResponseWriter, when the handler writes the status.
+type timeoutGuardWriter struct {
+	w http.ResponseWriter
+	h http.Header
+
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+func (tw *timeoutGuardWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutGuardWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+	tw.writeHeader(code)
+}
+
+// writeHeader copies the headers and sends the status, if this was not done
+// before.
+//
+// Has to be called with the lock.
+func (tw *timeoutGuardWriter) writeHeader(code int) {
+	if tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+
+	dst := tw.w.Header()
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(code)
+}
+
+func (tw *timeoutGuardWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeader(http.StatusOK)
+	return tw.w.Write(p)
+}
+
+// Flush sends the buffered data to the client.
+func (tw *timeoutGuardWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
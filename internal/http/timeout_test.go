@@ -0,0 +1,110 @@
+package http_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		body    string
+	}{
+		{
+			"in time",
+			func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "done")
+			},
+			http.StatusOK,
+			"done",
+		},
+		{
+			"timeout",
+			func(w http.ResponseWriter, r *http.Request) {
+				// Ignores the context like a deadlocked handler.
+				<-block
+			},
+			http.StatusServiceUnavailable,
+			`{"error": {"type": "TimeoutError", "msg": "request took too long"}}` + "\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(ahttp.TimeoutMiddleware(10*time.Millisecond, "request took too long")(tt.handler))
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL)
+			if err != nil {
+				t.Fatalf("Can not send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("Got status %d, expected %d", resp.StatusCode, tt.status)
+			}
+
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Can not read body: %v", err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("Got body `%s`, expected `%s`", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestTimeoutMiddlewareStream(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	handler := ahttp.TimeoutMiddleware(10*time.Millisecond, "request took too long")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"user/1/name":"Hello World"}`)
+		w.(http.Flusher).Flush()
+		<-block
+	}))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Can not send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	if !scanner.Scan() {
+		t.Fatalf("Can not read first frame: %v", scanner.Err())
+	}
+
+	if !scanner.Scan() {
+		t.Fatalf("Can not read error frame: %v", scanner.Err())
+	}
+
+	var body map[string]map[string]string
+	if err := json.Unmarshal(scanner.Bytes(), &body); err != nil {
+		t.Fatalf("Error frame `%s` is invalid json: %v", scanner.Bytes(), err)
+	}
+	if got := body["error"]["type"]; got != "TimeoutError" {
+		t.Errorf("Got error type %s, expected TimeoutError", got)
+	}
+
+	if scanner.Scan() {
+		t.Errorf("Got frame `%s` after the error", scanner.Bytes())
+	}
+	if scanner.Err() == nil {
+		t.Errorf("Connection was closed without an error, expected an aborted response")
+	}
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// NewTLSConfig creates a tls config with the given certificate. It only
+// allows TLS 1.2 or newer and no weak cipher suites.
+//
+// If a file with the name of the certificate and the suffix ".ocsp" exists,
+// it is used as OCSP response for stapling. It has to be DER encoded.
+func NewTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+
+	staple, err := ioutil.ReadFile(certFile + ".ocsp")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read ocsp response: %w", err)
+	}
+	cert.OCSPStaple = staple
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+
+		// Only used for TLS 1.2. The cipher suites of TLS 1.3 are all
+		// secure.
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		},
+	}, nil
+}
+
+// TLSServer starts a https server with the given config. It blocks until the
+// server fails.
+func TLSServer(addr string, h http.Handler, cfg *tls.Config) error {
+	srv := &http.Server{
+		Addr:      addr,
+		Handler:   h,
+		TLSConfig: cfg,
+	}
+	return srv.ListenAndServeTLS("", "")
+}
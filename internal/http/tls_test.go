@@ -0,0 +1,102 @@
+package http_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+)
+
+func TestTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "autoupdate-tls")
+	if err != nil {
+		t.Fatalf("Can not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir)
+
+	cfg, err := ahttp.NewTLSConfig(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewTLSConfig returned an unexpected error: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(okHandler)
+	srv.TLS = cfg
+	srv.StartTLS()
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		name    string
+		version uint16
+		ok      bool
+	}{
+		{"TLS 1.1", tls.VersionTLS11, false},
+		{"TLS 1.2", tls.VersionTLS12, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{
+				InsecureSkipVerify: true,
+				MinVersion:         tls.VersionTLS10,
+				MaxVersion:         tt.version,
+			})
+			if err == nil {
+				conn.Close()
+			}
+
+			if tt.ok && err != nil {
+				t.Errorf("Connection was rejected: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Errorf("Connection was not rejected")
+			}
+		})
+	}
+}
+
+// writeCert creates a self signed certificate and writes it and its key to
+// the directory.
+func writeCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Can not create key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Can not create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Can not marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("Can not write certificate: %v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("Can not write key: %v", err)
+	}
+	return certFile, keyFile
+}
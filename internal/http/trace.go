@@ -0,0 +1,14 @@
+package http
+
+import "context"
+
+// noopTracer is the default tracer that does not trace anything.
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End() {}
@@ -0,0 +1,24 @@
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/version"
+)
+
+// VersionHandler returns the build information of the service as json.
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	info := struct {
+		Version   string `json:"version"`
+		Commit    string `json:"commit"`
+		BuildTime string `json:"build_time"`
+	}{version.Version, version.Commit, version.BuildTime}
+
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		log.Printf("Can not send version: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+type contextKey int
+
+const (
+	connKey contextKey = iota
+	clientIPKey
+	bodyKey
+	responseInfoKey
+	requestIDKey
+	contentTypeKey
+)
+
+// ConnContext saves the connection in the context. It has to be used as
+// http.Server.ConnContext, so the handler can set deadlines on the connection.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connKey, c)
+}
+
+// connFromContext returns the connection saved with ConnContext or nil.
+func connFromContext(ctx context.Context) net.Conn {
+	c, _ := ctx.Value(connKey).(net.Conn)
+	return c
+}
+
+// timeoutWriter is a http.ResponseWriter that sets a write deadline on the
+// underlying connection before each write. If a write runs into the deadline,
+// the connection is closed.
+type timeoutWriter struct {
+	http.ResponseWriter
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	n, err := w.ResponseWriter.Write(p)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		w.conn.Close()
+	}
+	return n, err
+}
+
+// Flush sends the buffered data to the client. Most of the data is written to
+// the connection here and not in Write().
+func (w *timeoutWriter) Flush() {
+	deadline := time.Now().Add(w.timeout)
+	w.conn.SetWriteDeadline(deadline)
+
+	flushErr, ok := w.ResponseWriter.(interface{ FlushError() error })
+	if !ok {
+		// Before go 1.20, Flush does not return an error, so the deadline is
+		// checked afterwards.
+		w.ResponseWriter.(http.Flusher).Flush()
+		if time.Now().After(deadline) {
+			w.conn.Close()
+		}
+		return
+	}
+
+	var netErr net.Error
+	if err := flushErr.FlushError(); errors.As(err, &netErr) && netErr.Timeout() {
+		w.conn.Close()
+	}
+}
+
+// Push implements the http.Pusher interface, if the wrapped ResponseWriter
+// supports it.
+func (w *timeoutWriter) Push(target string, opts *http.PushOptions) error {
+	pusher, ok := w.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return pusher.Push(target, opts)
+}
@@ -0,0 +1,47 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/autoupdate"
+	ahttp "github.com/openslides/openslides-autoupdate-service/internal/http"
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestWriteTimeout(t *testing.T) {
+	datastore := test.NewMockDatastore()
+	defer datastore.Close()
+
+	// The value has to be bigger then the buffers of the http server.
+	datastore.Data = map[string]json.RawMessage{
+		"user/1/name": []byte(`"` + strings.Repeat("x", 1<<16) + `"`),
+	}
+	s := autoupdate.New(datastore, new(test.MockRestricter))
+	defer s.Close()
+
+	l := newPipeListener()
+	srv := &http.Server{
+		Handler:     ahttp.New(s, mockAuth{1}, 0, ahttp.WithWriteTimeout(10*time.Millisecond)),
+		ConnContext: ahttp.ConnContext,
+	}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	conn, closed := l.dial()
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /system/autoupdate/keys?user/1/name HTTP/1.1\r\nHost: localhost\r\n\r\n")
+
+	// Do not read from the connection, so the server runs into the timeout.
+	timer := time.NewTimer(time.Second)
+	defer timer.Stop()
+	select {
+	case <-closed:
+	case <-timer.C:
+		t.Errorf("Connection was not closed by the server")
+	}
+}
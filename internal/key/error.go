@@ -0,0 +1,79 @@
+package key
+
+import "fmt"
+
+// InvalidKeyError is returned, when a key has not the form
+// collection/id/field.
+type InvalidKeyError struct {
+	Key string
+}
+
+func (e InvalidKeyError) Error() string {
+	return fmt.Sprintf("invalid key %s", e.Key)
+}
+
+// Type returns the name of the error.
+func (e InvalidKeyError) Type() string {
+	return "InvalidKeyError"
+}
+
+// NamespaceError is returned, when a key has not the expected namespace.
+type NamespaceError struct {
+	Key       string
+	Namespace string
+}
+
+func (e NamespaceError) Error() string {
+	return fmt.Sprintf("key %s is not in namespace %s", e.Key, e.Namespace)
+}
+
+// Type returns the name of the error.
+func (e NamespaceError) Type() string {
+	return "NamespaceError"
+}
+
+// UnknownCollectionError is returned by Schema.Validate, when the collection
+// of a key is not in the schema.
+type UnknownCollectionError struct {
+	Key string
+}
+
+func (e UnknownCollectionError) Error() string {
+	return fmt.Sprintf("unknown collection in key %s", e.Key)
+}
+
+// Type returns the name of the error.
+func (e UnknownCollectionError) Type() string {
+	return "UnknownCollectionError"
+}
+
+// UnknownFieldError is returned by Schema.Validate, when the field of a key is
+// not in the schema.
+type UnknownFieldError struct {
+	Key string
+}
+
+func (e UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field in key %s", e.Key)
+}
+
+// Type returns the name of the error.
+func (e UnknownFieldError) Type() string {
+	return "UnknownFieldError"
+}
+
+// InvalidRangeError is returned, when the id range of a key is reversed or
+// has too many ids.
+type InvalidRangeError struct {
+	Key string
+	msg string
+}
+
+func (e InvalidRangeError) Error() string {
+	return fmt.Sprintf("invalid id range in key %s: %s", e.Key, e.msg)
+}
+
+// Type returns the name of the error.
+func (e InvalidRangeError) Type() string {
+	return "InvalidRangeError"
+}
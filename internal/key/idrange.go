@@ -0,0 +1,101 @@
+package key
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxRange is the default for the maximum number of ids in an id
+// range.
+const DefaultMaxRange = 1000
+
+// ExpandRange expands a key with an id range like user/[1-100]/name to the
+// keys user/1/name to user/100/name. A key without a range is returned as the
+// only element. It is not validated.
+//
+// Returns an InvalidKeyError, if the range can not be parsed, and an
+// InvalidRangeError, if the start is bigger then the end or the range has
+// more then max ids.
+func ExpandRange(key string, max int) ([]string, error) {
+	parts := strings.Split(key, "/")
+	if len(parts) < 3 {
+		return []string{key}, nil
+	}
+
+	idPart := parts[len(parts)-2]
+	if !strings.HasPrefix(idPart, "[") {
+		return []string{key}, nil
+	}
+
+	bounds := strings.Split(strings.TrimSuffix(strings.TrimPrefix(idPart, "["), "]"), "-")
+	if !strings.HasSuffix(idPart, "]") || len(bounds) != 2 {
+		return nil, InvalidKeyError{Key: key}
+	}
+
+	// The bounds are limited to 32 bit, so end-start can not overflow.
+	start, err := strconv.ParseInt(bounds[0], 10, 32)
+	if err != nil {
+		return nil, InvalidKeyError{Key: key}
+	}
+
+	end, err := strconv.ParseInt(bounds[1], 10, 32)
+	if err != nil {
+		return nil, InvalidKeyError{Key: key}
+	}
+
+	if start > end {
+		return nil, InvalidRangeError{Key: key, msg: "the start is bigger then the end"}
+	}
+
+	if end-start >= int64(max) {
+		return nil, InvalidRangeError{Key: key, msg: "the range has more then " + strconv.Itoa(max) + " ids"}
+	}
+
+	prefix := strings.Join(parts[:len(parts)-2], "/") + "/"
+	suffix := "/" + parts[len(parts)-1]
+	keys := make([]string, 0, end-start+1)
+	for id := start; id <= end; id++ {
+		keys = append(keys, prefix+strconv.FormatInt(id, 10)+suffix)
+	}
+	return keys, nil
+}
+
+// ExpandRanges is like ExpandRange but for many keys. The expanded keys are
+// returned in the order of the given keys.
+//
+// max limits the number of ids of all ranges together. If it is exceeded, an
+// InvalidRangeError is returned for the range that exceeded it.
+func ExpandRanges(keys []string, max int) ([]string, error) {
+	var expanded []string
+	var rangeIDs int
+	for i, k := range keys {
+		if !strings.Contains(k, "[") {
+			if expanded != nil {
+				expanded = append(expanded, k)
+			}
+			continue
+		}
+
+		if expanded == nil {
+			expanded = append(make([]string, 0, len(keys)), keys[:i]...)
+		}
+
+		rangeKeys, err := ExpandRange(k, max-rangeIDs)
+		if err != nil {
+			var rangeErr InvalidRangeError
+			if errors.As(err, &rangeErr) && rangeIDs > 0 {
+				rangeErr.msg = "all ranges together have more then " + strconv.Itoa(max) + " ids"
+				return nil, rangeErr
+			}
+			return nil, err
+		}
+		rangeIDs += len(rangeKeys)
+		expanded = append(expanded, rangeKeys...)
+	}
+
+	if expanded == nil {
+		return keys, nil
+	}
+	return expanded, nil
+}
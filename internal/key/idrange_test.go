@@ -0,0 +1,101 @@
+package key_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+func TestExpandRange(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		key    string
+		expect []string
+	}{
+		{"range", "user/[1-3]/name", []string{"user/1/name", "user/2/name", "user/3/name"}},
+		{"single id", "user/[5-5]/name", []string{"user/5/name"}},
+		{"no range", "user/1/name", []string{"user/1/name"}},
+		{"namespace", "prod/user/[1-2]/name", []string{"prod/user/1/name", "prod/user/2/name"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := key.ExpandRange(tt.key, 10)
+			if err != nil {
+				t.Fatalf("ExpandRange() returned an unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("Got %v, expected %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestExpandRangeMax(t *testing.T) {
+	got, err := key.ExpandRange("user/[1-1000]/name", key.DefaultMaxRange)
+	if err != nil {
+		t.Fatalf("ExpandRange() returned an unexpected error: %v", err)
+	}
+
+	if len(got) != 1000 || got[0] != "user/1/name" || got[999] != "user/1000/name" {
+		t.Errorf("Got %d keys from %s to %s, expected user/1/name to user/1000/name", len(got), got[0], got[len(got)-1])
+	}
+}
+
+func TestExpandRangeInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		key       string
+		wantRange bool
+	}{
+		{"reversed", "user/[10-1]/name", true},
+		{"out of bounds", "user/[1-1001]/name", true},
+		{"overflow", "user/[0-9223372036854775807]/name", false},
+		{"max int32", "user/[0-2147483647]/name", true},
+		{"not closed", "user/[1-3/name", false},
+		{"no end", "user/[1]/name", false},
+		{"no number", "user/[a-b]/name", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := key.ExpandRange(tt.key, key.DefaultMaxRange)
+
+			if tt.wantRange {
+				var rangeErr key.InvalidRangeError
+				if !errors.As(err, &rangeErr) {
+					t.Errorf("ExpandRange() returned error `%v`, expected an InvalidRangeError", err)
+				}
+				return
+			}
+
+			var invalid key.InvalidKeyError
+			if !errors.As(err, &invalid) {
+				t.Errorf("ExpandRange() returned error `%v`, expected an InvalidKeyError", err)
+			}
+		})
+	}
+}
+
+func TestExpandRanges(t *testing.T) {
+	got, err := key.ExpandRanges([]string{"motion/1/title", "user/[1-2]/name", "user/1/email"}, 10)
+	if err != nil {
+		t.Fatalf("ExpandRanges() returned an unexpected error: %v", err)
+	}
+
+	expect := []string{"motion/1/title", "user/1/name", "user/2/name", "user/1/email"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Got %v, expected %v", got, expect)
+	}
+}
+
+func TestExpandRangesTotal(t *testing.T) {
+	if _, err := key.ExpandRanges([]string{"user/[1-6]/name", "user/[1-5]/email"}, 11); err != nil {
+		t.Errorf("ExpandRanges() returned an unexpected error: %v", err)
+	}
+
+	_, err := key.ExpandRanges([]string{"user/[1-6]/name", "user/[1-6]/email"}, 11)
+	var rangeErr key.InvalidRangeError
+	if !errors.As(err, &rangeErr) {
+		t.Errorf("ExpandRanges() returned error `%v` for 12 ids with max 11, expected an InvalidRangeError", err)
+	}
+}
@@ -0,0 +1,147 @@
+package key
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// KeyIndex is a sorted index of keys. It can be used to find all keys with a
+// prefix, for example all keys of a collection.
+//
+// Added and removed keys are collected and merged into the sorted list on the
+// next call to Range. So Add and Remove are cheap, even for a big index.
+//
+// The zero value is an empty index. It is save for concurrent use.
+type KeyIndex struct {
+	mu sync.RWMutex
+
+	// keys is sorted. It can contain keys from removed.
+	keys []string
+
+	// added holds keys that are not in keys.
+	added map[string]struct{}
+
+	// removed holds keys that are in keys but were removed.
+	removed map[string]struct{}
+}
+
+// Add inserts a key into the index. Adding a key that is already in the index
+// does nothing.
+func (x *KeyIndex) Add(key string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if _, ok := x.removed[key]; ok {
+		delete(x.removed, key)
+		return
+	}
+
+	if x.sorted(key) {
+		return
+	}
+
+	if x.added == nil {
+		x.added = make(map[string]struct{})
+	}
+	x.added[key] = struct{}{}
+}
+
+// Remove deletes a key from the index.
+func (x *KeyIndex) Remove(key string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if _, ok := x.added[key]; ok {
+		delete(x.added, key)
+		return
+	}
+
+	if !x.sorted(key) {
+		return
+	}
+
+	if x.removed == nil {
+		x.removed = make(map[string]struct{})
+	}
+	x.removed[key] = struct{}{}
+}
+
+// Range returns all keys, that start with the prefix, in sorted order.
+func (x *KeyIndex) Range(prefix string) []string {
+	x.mu.RLock()
+	if len(x.added) > 0 || len(x.removed) > 0 {
+		x.mu.RUnlock()
+		x.mu.Lock()
+		x.merge()
+		x.mu.Unlock()
+		x.mu.RLock()
+	}
+	defer x.mu.RUnlock()
+
+	start := sort.SearchStrings(x.keys, prefix)
+	end := start + sort.Search(len(x.keys)-start, func(i int) bool {
+		return !strings.HasPrefix(x.keys[start+i], prefix)
+	})
+
+	keys := make([]string, 0, end-start)
+	for _, key := range x.keys[start:end] {
+		// A key can be removed between merge() and RLock().
+		if _, ok := x.removed[key]; ok {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Count returns the number of keys in the index.
+func (x *KeyIndex) Count() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return len(x.keys) + len(x.added) - len(x.removed)
+}
+
+// sorted returns true, if the key is in the sorted list.
+//
+// The index has to be at least in read lock.
+func (x *KeyIndex) sorted(key string) bool {
+	i := sort.SearchStrings(x.keys, key)
+	return i < len(x.keys) && x.keys[i] == key
+}
+
+// merge adds the added keys to the sorted list and deletes the removed keys
+// from it.
+//
+// The index has to be in write lock.
+func (x *KeyIndex) merge() {
+	if len(x.added) == 0 && len(x.removed) == 0 {
+		return
+	}
+
+	added := make([]string, 0, len(x.added))
+	for key := range x.added {
+		added = append(added, key)
+	}
+	sort.Strings(added)
+
+	merged := make([]string, 0, len(x.keys)+len(added)-len(x.removed))
+	i := 0
+	for _, key := range x.keys {
+		if _, ok := x.removed[key]; ok {
+			continue
+		}
+
+		for i < len(added) && added[i] < key {
+			merged = append(merged, added[i])
+			i++
+		}
+		merged = append(merged, key)
+	}
+	merged = append(merged, added[i:]...)
+
+	x.keys = merged
+	x.added = nil
+	x.removed = nil
+}
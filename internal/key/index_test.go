@@ -0,0 +1,95 @@
+package key_test
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+func TestKeyIndex(t *testing.T) {
+	var x key.KeyIndex
+	for _, k := range []string{"user/2/name", "motion/1/title", "user/1/name", "user/10/name", "user/1/name"} {
+		x.Add(k)
+	}
+	x.Remove("user/10/name")
+	x.Remove("user/3/name")
+
+	if got := x.Count(); got != 3 {
+		t.Errorf("Count() returned %d, expected 3", got)
+	}
+
+	for _, tt := range []struct {
+		prefix string
+		expect []string
+	}{
+		{"", []string{"motion/1/title", "user/1/name", "user/2/name"}},
+		{"user/", []string{"user/1/name", "user/2/name"}},
+		{"user/1", []string{"user/1/name"}},
+		{"motion/1/title", []string{"motion/1/title"}},
+		{"topic/", []string{}},
+		{"zzz", []string{}},
+	} {
+		t.Run(tt.prefix, func(t *testing.T) {
+			if got := x.Range(tt.prefix); !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("Range(%q) returned %v, expected %v", tt.prefix, got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestKeyIndexAddAfterRange(t *testing.T) {
+	var x key.KeyIndex
+	x.Add("user/1/name")
+	x.Add("user/3/name")
+	x.Range("")
+
+	x.Remove("user/1/name")
+	x.Add("user/2/name")
+	x.Add("user/1/name")
+	x.Remove("user/3/name")
+
+	expect := []string{"user/1/name", "user/2/name"}
+	if got := x.Range("user/"); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Range() returned %v, expected %v", got, expect)
+	}
+
+	if got := x.Count(); got != 2 {
+		t.Errorf("Count() returned %d, expected 2", got)
+	}
+}
+
+func BenchmarkKeyIndexAdd(b *testing.B) {
+	var x key.KeyIndex
+	for i := 0; i < 1000000; i++ {
+		x.Add(fmt.Sprintf("user/%07d/name", i))
+	}
+	x.Range("")
+
+	keys := make([]string, b.N)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("motion/%d/title", rand.Int())
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		x.Add(keys[n])
+	}
+}
+
+func BenchmarkKeyIndexRange(b *testing.B) {
+	var x key.KeyIndex
+	for i := 0; i < 1000000; i++ {
+		x.Add(fmt.Sprintf("user/%07d/name", i))
+	}
+
+	for _, prefix := range []string{"user/0000001/", "user/00001", "user/001"} {
+		b.Run(prefix, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				x.Range(prefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Package key parses the keys of the autoupdate service.
+//
+// A key has the form collection/id/field. It can have a namespace as prefix
+// to separate the keys of different environments.
+//
+// A key with an id range like user/[1-100]/name stands for many keys. Parse
+// and Validate only accept single keys, so ranges have to be expanded with
+// ExpandRange or ExpandRanges first.
+package key
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Key is a parsed key.
+type Key struct {
+	Collection string
+	ID         int
+	Field      string
+}
+
+// Parse parses a key in the form collection/id/field.
+func Parse(key string) (Key, error) {
+	return parseKey("", key)
+}
+
+// Validate checks, that all keys have the form collection/id/field. It returns
+// an InvalidKeyError for the first invalid key.
+func Validate(keys ...string) error {
+	for _, k := range keys {
+		if _, err := Parse(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k Key) String() string {
+	return k.Collection + "/" + strconv.Itoa(k.ID) + "/" + k.Field
+}
+
+// Namespace is a prefix for keys. It separates the keys of different
+// environments, for example `production/user/1/name`.
+//
+// The empty namespace means, that the keys have no prefix.
+type Namespace string
+
+// Parse parses a key with the namespace as prefix. The namespace is removed
+// from the key.
+func (n Namespace) Parse(key string) (Key, error) {
+	return parseKey(n, key)
+}
+
+// Strip removes the namespace from the key. It returns an error, if the key is
+// not valid or has not the namespace.
+func (n Namespace) Strip(key string) (string, error) {
+	k, err := parseKey(n, key)
+	if err != nil {
+		return "", err
+	}
+	return k.String(), nil
+}
+
+// Add adds the namespace as prefix to the key.
+func (n Namespace) Add(key string) string {
+	if n == "" {
+		return key
+	}
+	return string(n) + "/" + key
+}
+
+// parseKey removes the namespace from the key and parses the rest.
+func parseKey(ns Namespace, key string) (Key, error) {
+	raw := key
+	if ns != "" {
+		prefix := string(ns) + "/"
+		if !strings.HasPrefix(key, prefix) {
+			return Key{}, NamespaceError{Key: key, Namespace: string(ns)}
+		}
+		raw = key[len(prefix):]
+	}
+
+	parts := strings.Split(raw, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return Key{}, InvalidKeyError{Key: key}
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	if err != nil || id < 0 {
+		return Key{}, InvalidKeyError{Key: key}
+	}
+
+	return Key{Collection: parts[0], ID: id, Field: parts[2]}, nil
+}
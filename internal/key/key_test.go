@@ -0,0 +1,82 @@
+package key_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		key    string
+		expect key.Key
+		valid  bool
+	}{
+		{"user/1/name", key.Key{Collection: "user", ID: 1, Field: "name"}, true},
+		{"user/1", key.Key{}, false},
+		{"user/one/name", key.Key{}, false},
+		{"user/1/name/more", key.Key{}, false},
+		{"/1/name", key.Key{}, false},
+	} {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := key.Parse(tt.key)
+
+			if !tt.valid {
+				var invalid key.InvalidKeyError
+				if !errors.As(err, &invalid) {
+					t.Errorf("Parse() returned error `%v`, expected an InvalidKeyError", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse() returned an unexpected error: %v", err)
+			}
+			if got != tt.expect {
+				t.Errorf("Parse() returned %v, expected %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		namespace key.Namespace
+		key       string
+		expect    string
+		err       error
+	}{
+		{"match", "production", "production/user/1/name", "user/1/name", nil},
+		{"mismatch", "production", "staging/user/1/name", "", key.NamespaceError{}},
+		{"key without namespace", "production", "user/1/name", "", key.NamespaceError{}},
+		{"no namespace", "", "user/1/name", "user/1/name", nil},
+		{"no namespace but prefix", "", "production/user/1/name", "", key.InvalidKeyError{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.namespace.Strip(tt.key)
+
+			switch tt.err.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("Strip() returned an unexpected error: %v", err)
+				}
+			case key.NamespaceError:
+				var nsErr key.NamespaceError
+				if !errors.As(err, &nsErr) {
+					t.Fatalf("Strip() returned error `%v`, expected a NamespaceError", err)
+				}
+			case key.InvalidKeyError:
+				var invalid key.InvalidKeyError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("Strip() returned error `%v`, expected an InvalidKeyError", err)
+				}
+			}
+
+			if got != tt.expect {
+				t.Errorf("Strip() returned `%s`, expected `%s`", got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package key
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Schema holds the known fields of each collection. It can be used to reject
+// keys with a typo, that would otherwise return null.
+//
+// Fields with a $ are template fields. The $ matches any text, so the field
+// group_$_ids matches group_5_ids.
+//
+// All collections have to be registered before Validate is used.
+type Schema struct {
+	collections map[string]map[string]bool
+}
+
+// Register adds the fields of a collection to the schema. If the collection
+// is already registered, the fields are added to the existing fields.
+func (s *Schema) Register(collection string, fields []string) {
+	if s.collections == nil {
+		s.collections = make(map[string]map[string]bool)
+	}
+
+	if s.collections[collection] == nil {
+		s.collections[collection] = make(map[string]bool, len(fields))
+	}
+
+	for _, field := range fields {
+		s.collections[collection][field] = true
+	}
+}
+
+// Validate returns an UnknownCollectionError or an UnknownFieldError, if the
+// key is not in the schema.
+func (s *Schema) Validate(key Key) error {
+	fields, ok := s.collections[key.Collection]
+	if !ok {
+		return UnknownCollectionError{Key: key.String()}
+	}
+
+	if fields[key.Field] {
+		return nil
+	}
+
+	for field := range fields {
+		if matchTemplate(field, key.Field) {
+			return nil
+		}
+	}
+	return UnknownFieldError{Key: key.String()}
+}
+
+// ValidateKeys parses the keys and validates them. It returns the error for
+// the first invalid key.
+func (s *Schema) ValidateKeys(keys ...string) error {
+	for _, k := range keys {
+		parsed, err := Parse(k)
+		if err != nil {
+			return err
+		}
+
+		if err := s.Validate(parsed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchTemplate returns true, if the template field matches the field. The $
+// in the template has to be replaced by at least one character.
+func matchTemplate(template, field string) bool {
+	i := strings.Index(template, "$")
+	if i == -1 {
+		return false
+	}
+
+	prefix, suffix := template[:i], template[i+1:]
+	return len(field) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(field, prefix) &&
+		strings.HasSuffix(field, suffix)
+}
+
+// LoadSchema reads a schema from a json file. The file contains an object
+// with the collections as keys. The value of each collection has the same
+// format as the fields of a key request:
+//
+//	{"user": {"name": null, "group_ids": {"type": "relation-list", "collection": "group"}}}
+//
+// Only the names of the fields are used.
+func LoadSchema(path string) (*Schema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read schema file: %w", err)
+	}
+
+	var content map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("decode schema file %s: %w", path, err)
+	}
+
+	schema := new(Schema)
+	for collection, fields := range content {
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		schema.Register(collection, names)
+	}
+	return schema, nil
+}
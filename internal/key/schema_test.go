@@ -0,0 +1,84 @@
+package key_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	var schema key.Schema
+	schema.Register("user", []string{"name", "group_$_ids"})
+	schema.Register("motion", []string{"title"})
+
+	for _, tt := range []struct {
+		key    string
+		expect error
+	}{
+		{"user/1/name", nil},
+		{"motion/5/title", nil},
+		{"user/1/group_$_ids", nil},
+		{"user/1/group_5_ids", nil},
+		{"user/1/nmae", key.UnknownFieldError{Key: "user/1/nmae"}},
+		{"user/1/group__ids", key.UnknownFieldError{Key: "user/1/group__ids"}},
+		{"motion/1/name", key.UnknownFieldError{Key: "motion/1/name"}},
+		{"usr/1/name", key.UnknownCollectionError{Key: "usr/1/name"}},
+	} {
+		t.Run(tt.key, func(t *testing.T) {
+			k, err := key.Parse(tt.key)
+			if err != nil {
+				t.Fatalf("Parse() returned an unexpected error: %v", err)
+			}
+
+			if err := schema.Validate(k); err != tt.expect {
+				t.Errorf("Validate() returned %v, expected %v", err, tt.expect)
+			}
+		})
+	}
+}
+
+func TestSchemaValidateKeys(t *testing.T) {
+	var schema key.Schema
+	schema.Register("user", []string{"name"})
+
+	if err := schema.ValidateKeys("user/1/name", "user/2/name"); err != nil {
+		t.Errorf("ValidateKeys() returned an unexpected error: %v", err)
+	}
+
+	var invalid key.InvalidKeyError
+	if err := schema.ValidateKeys("user/1/name", "user/2"); !errors.As(err, &invalid) {
+		t.Errorf("ValidateKeys() returned %v, expected an InvalidKeyError", err)
+	}
+}
+
+func TestLoadSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "autoupdate-schema")
+	if err != nil {
+		t.Fatalf("Can not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "schema.json")
+	content := `{"user": {"name": null, "group_ids": {"type": "relation-list", "collection": "group"}}}`
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Can not write schema file: %v", err)
+	}
+
+	schema, err := key.LoadSchema(path)
+	if err != nil {
+		t.Fatalf("LoadSchema() returned an unexpected error: %v", err)
+	}
+
+	if err := schema.ValidateKeys("user/1/name", "user/1/group_ids"); err != nil {
+		t.Errorf("ValidateKeys() returned an unexpected error: %v", err)
+	}
+
+	var unknown key.UnknownFieldError
+	if err := schema.ValidateKeys("user/1/password"); !errors.As(err, &unknown) {
+		t.Errorf("ValidateKeys() returned %v, expected an UnknownFieldError", err)
+	}
+}
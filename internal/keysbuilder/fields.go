@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
@@ -36,9 +37,14 @@ const (
 )
 
 // body holds the information which keys are requested by the client.
+//
+// The optional attribute expire holds a duration for fields of the body, like
+// {"token": "30s"}. The keys of this fields are only subscribed for the
+// duration.
 type body struct {
 	ids        []int
 	collection string
+	expire     map[string]time.Duration
 	fieldsMap
 }
 
@@ -46,9 +52,10 @@ type body struct {
 // in the fields and decodes the fields accorently.
 func (b *body) UnmarshalJSON(data []byte) error {
 	var field struct {
-		IDs        []int     `json:"ids"`
-		Collection string    `json:"collection"`
-		Fields     fieldsMap `json:"fields"`
+		IDs        []int             `json:"ids"`
+		Collection string            `json:"collection"`
+		Fields     fieldsMap         `json:"fields"`
+		Expire     map[string]string `json:"expire"`
 	}
 
 	// Read and validate the data.
@@ -65,6 +72,22 @@ func (b *body) UnmarshalJSON(data []byte) error {
 		return InvalidError{msg: "no fields"}
 	}
 
+	for name, raw := range field.Expire {
+		if _, ok := field.Fields.fields[name]; !ok {
+			return InvalidError{msg: "expire for unknown field", field: name}
+		}
+
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			return InvalidError{msg: "invalid expire duration", field: name}
+		}
+
+		if b.expire == nil {
+			b.expire = make(map[string]time.Duration, len(field.Expire))
+		}
+		b.expire[name] = ttl
+	}
+
 	// Set the body fields.
 	b.ids = field.IDs
 	b.collection = field.Collection
@@ -0,0 +1,69 @@
+package keysbuilder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// KeyRequest is one nested key request in the json format of the body, that
+// is used by FromJSON.
+type KeyRequest = json.RawMessage
+
+// FlattenKeyRequest returns all keys, that are needed by the key requests.
+//
+// It is like ManyFromJSON but the values of relation fields are read from
+// baseData, that has to contain all values that the relations point to.
+// Missing values and null are skipped. The keys are returned sorted and each
+// key only once.
+func FlattenKeyRequest(krs []KeyRequest, baseData map[string]json.RawMessage) ([]string, error) {
+	body, err := json.Marshal(krs)
+	if err != nil {
+		return nil, fmt.Errorf("encode key requests: %w", err)
+	}
+
+	kb, err := ManyFromJSON(context.Background(), bytes.NewReader(body), dataValuer(baseData), 0)
+	if err != nil {
+		return nil, fmt.Errorf("build keys: %w", err)
+	}
+
+	seen := make(map[string]bool, len(kb.Keys()))
+	keys := make([]string, 0, len(kb.Keys()))
+	for _, key := range kb.Keys() {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// dataValuer implements the Valuer interface with fixed data.
+type dataValuer map[string]json.RawMessage
+
+func (d dataValuer) Value(_ context.Context, _ int, key string, value interface{}) error {
+	raw := d[key]
+	if len(raw) == 0 || string(raw) == "null" {
+		return doesNotExistError(key)
+	}
+
+	if err := json.Unmarshal(raw, value); err != nil {
+		return fmt.Errorf("decode value of key %s: %w", key, err)
+	}
+	return nil
+}
+
+// doesNotExistError tells the builder, that a relation has no value.
+type doesNotExistError string
+
+func (e doesNotExistError) Error() string {
+	return fmt.Sprintf("key %s does not exist", string(e))
+}
+
+func (e doesNotExistError) KeyDoesNotExist() bool {
+	return true
+}
@@ -0,0 +1,134 @@
+package keysbuilder_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/keysbuilder"
+)
+
+func TestFlattenKeyRequest(t *testing.T) {
+	baseData := map[string]json.RawMessage{
+		"user/1/note_id":          []byte(`5`),
+		"user/1/group_ids":        []byte(`[1,2]`),
+		"user/1/most_seen":        []byte(`"motion/3"`),
+		"user/1/group_$_ids":      []byte(`["7"]`),
+		"user/2/note_id":          []byte(`null`),
+		"group/1/permission_ids":  []byte(`[10]`),
+		"group/2/permission_ids":  []byte(`[10,11]`),
+		"user/1/group_7_ids":      []byte(`[2]`),
+		"motion/3/submitter_ids":  []byte(`[1]`),
+		"note/5/important":        []byte(`true`),
+		"permission/10/important": []byte(`true`),
+	}
+
+	for _, tt := range []struct {
+		name    string
+		request string
+		expect  []string
+	}{
+		{
+			"only fields",
+			`[{"ids": [1, 2], "collection": "user", "fields": {"name": null, "email": null}}]`,
+			[]string{"user/1/email", "user/1/name", "user/2/email", "user/2/name"},
+		},
+		{
+			"relation",
+			`[{"ids": [1], "collection": "user", "fields": {"note_id": {"type": "relation", "collection": "note", "fields": {"important": null}}}}]`,
+			[]string{"note/5/important", "user/1/note_id"},
+		},
+		{
+			"relation null",
+			`[{"ids": [2], "collection": "user", "fields": {"note_id": {"type": "relation", "collection": "note", "fields": {"important": null}}}}]`,
+			[]string{"user/2/note_id"},
+		},
+		{
+			"relation list",
+			`[{"ids": [1], "collection": "user", "fields": {"group_ids": {"type": "relation-list", "collection": "group", "fields": {"name": null}}}}]`,
+			[]string{"group/1/name", "group/2/name", "user/1/group_ids"},
+		},
+		{
+			"generic relation",
+			`[{"ids": [1], "collection": "user", "fields": {"most_seen": {"type": "generic-relation", "fields": {"title": null}}}}]`,
+			[]string{"motion/3/title", "user/1/most_seen"},
+		},
+		{
+			"multi level",
+			`[{"ids": [1], "collection": "user", "fields": {"group_ids": {
+				"type": "relation-list",
+				"collection": "group",
+				"fields": {"permission_ids": {
+					"type": "relation-list",
+					"collection": "permission",
+					"fields": {"important": null}
+				}}
+			}}}]`,
+			[]string{
+				"group/1/permission_ids",
+				"group/2/permission_ids",
+				"permission/10/important",
+				"permission/11/important",
+				"user/1/group_ids",
+			},
+		},
+		{
+			"template",
+			`[{"ids": [1], "collection": "user", "fields": {"group_$_ids": {
+				"type": "template",
+				"values": {"type": "relation-list", "collection": "group", "fields": {"name": null}}
+			}}}]`,
+			[]string{"group/2/name", "user/1/group_$_ids", "user/1/group_7_ids"},
+		},
+		{
+			"back to start",
+			`[{"ids": [1], "collection": "user", "fields": {"most_seen": {
+				"type": "generic-relation",
+				"fields": {"submitter_ids": {"type": "relation-list", "collection": "user", "fields": {"most_seen": null}}}
+			}}}]`,
+			[]string{"motion/3/submitter_ids", "user/1/most_seen"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var krs []keysbuilder.KeyRequest
+			if err := json.Unmarshal([]byte(tt.request), &krs); err != nil {
+				t.Fatalf("Invalid key request in test: %v", err)
+			}
+
+			got, err := keysbuilder.FlattenKeyRequest(krs, baseData)
+			if err != nil {
+				t.Fatalf("FlattenKeyRequest() returned an unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("Got %v, expected %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestFlattenKeyRequestInvalid(t *testing.T) {
+	baseData := map[string]json.RawMessage{
+		"user/1/note_id": []byte(`"not a number"`),
+	}
+
+	for _, tt := range []struct {
+		name    string
+		request string
+	}{
+		{"no ids", `[{"collection": "user", "fields": {"name": null}}]`},
+		{"unknown type", `[{"ids": [1], "collection": "user", "fields": {"name": {"type": "unknown"}}}]`},
+		{"invalid value", `[{"ids": [1], "collection": "user", "fields": {"note_id": {"type": "relation", "collection": "note", "fields": {"important": null}}}}]`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var krs []keysbuilder.KeyRequest
+			if err := json.Unmarshal([]byte(tt.request), &krs); err != nil {
+				t.Fatalf("Invalid key request in test: %v", err)
+			}
+
+			if _, err := keysbuilder.FlattenKeyRequest(krs, baseData); err == nil {
+				t.Errorf("FlattenKeyRequest() returned no error")
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"time"
 )
 
 const keySep = "/"
@@ -94,6 +95,28 @@ func (b *Builder) Keys() []string {
 	return b.keys
 }
 
+// RelatedKeys returns the keys, that were found by following relations. These
+// are all keys except the fields of the requested objects.
+func (b *Builder) RelatedKeys() []string {
+	requested := make(map[string]bool)
+	for _, body := range b.bodies {
+		for _, id := range body.ids {
+			fqid := buildCollectionID(body.collection, id)
+			for field := range body.fields {
+				requested[buildGenericKey(fqid, field)] = true
+			}
+		}
+	}
+
+	var related []string
+	for _, key := range b.keys {
+		if !requested[key] {
+			related = append(related, key)
+		}
+	}
+	return related
+}
+
 // buildGenericKey returns a valid key when the collection and id are already
 // together.
 //
@@ -105,3 +128,20 @@ func buildGenericKey(collectionID string, field string) string {
 func buildCollectionID(collection string, id int) string {
 	return collection + keySep + strconv.Itoa(id)
 }
+
+// KeyExpiry returns the keys, that have an expire duration in the request
+// body, with their duration.
+func (b *Builder) KeyExpiry() map[string]time.Duration {
+	var expiry map[string]time.Duration
+	for _, body := range b.bodies {
+		for name, ttl := range body.expire {
+			for _, id := range body.ids {
+				if expiry == nil {
+					expiry = make(map[string]time.Duration)
+				}
+				expiry[buildGenericKey(buildCollectionID(body.collection, id), name)] = ttl
+			}
+		}
+	}
+	return expiry
+}
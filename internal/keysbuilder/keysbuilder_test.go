@@ -532,6 +532,73 @@ func TestManyRequests(t *testing.T) {
 	}
 }
 
+func TestRelatedKeys(t *testing.T) {
+	json := `{
+		"ids": [1],
+		"collection": "user",
+		"fields": {
+			"name": null,
+			"note_id": {
+				"type": "relation",
+				"collection": "note",
+				"fields": {"important": null}
+			}
+		}
+	}`
+	valuer := &mockValuer{data: map[string]interface{}{"user/1/note_id": 1}}
+	b, err := keysbuilder.FromJSON(context.Background(), strings.NewReader(json), valuer, 1)
+	if err != nil {
+		t.Fatalf("FromJSON() returned an unexpected error: %v", err)
+	}
+
+	expect := strs("note/1/important")
+	if diff := cmpSet(set(expect...), set(b.RelatedKeys()...)); diff != nil {
+		t.Errorf("Got %v, expected %v", diff, expect)
+	}
+}
+
+func TestKeyExpiry(t *testing.T) {
+	json := `{
+		"ids": [1, 2],
+		"collection": "user",
+		"fields": {
+			"name": null,
+			"token": null
+		},
+		"expire": {"token": "30s"}
+	}`
+	b, err := keysbuilder.FromJSON(context.Background(), strings.NewReader(json), &mockValuer{}, 1)
+	if err != nil {
+		t.Fatalf("FromJSON() returned an unexpected error: %v", err)
+	}
+
+	got := b.KeyExpiry()
+	if len(got) != 2 || got["user/1/token"] != 30*time.Second || got["user/2/token"] != 30*time.Second {
+		t.Errorf("KeyExpiry() returned %v, expected user/1/token and user/2/token with 30s", got)
+	}
+}
+
+func TestKeyExpiryInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		expire string
+	}{
+		{"unknown field", `{"other": "30s"}`},
+		{"invalid duration", `{"name": "soon"}`},
+		{"negative duration", `{"name": "-1s"}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			json := `{"ids": [1], "collection": "user", "fields": {"name": null}, "expire": ` + tt.expire + `}`
+			_, err := keysbuilder.FromJSON(context.Background(), strings.NewReader(json), &mockValuer{}, 1)
+
+			var invalid keysbuilder.InvalidError
+			if !errors.As(err, &invalid) {
+				t.Errorf("FromJSON() returned error `%v`, expected an InvalidError", err)
+			}
+		})
+	}
+}
+
 func TestError(t *testing.T) {
 	json := `
 	{
@@ -1,7 +1,7 @@
 package keysbuilder
 
 import (
-	"strings"
+	"github.com/openslides/openslides-autoupdate-service/internal/key"
 )
 
 // Simple implements the autoupdate.Keysbuilder interface. It returns the keys
@@ -20,13 +20,8 @@ func (s *Simple) Keys() []string {
 	return s.K
 }
 
-// Validate checks, if the given keys are valid.
+// Validate checks, if the given keys are valid. It returns a
+// key.InvalidKeyError for the first invalid key.
 func (s *Simple) Validate() error {
-	for _, key := range s.K {
-		keyParts := strings.SplitN(key, "/", 3)
-		if len(keyParts) != 3 {
-			return InvalidError{msg: "Invalid keys"}
-		}
-	}
-	return nil
+	return key.Validate(s.K...)
 }
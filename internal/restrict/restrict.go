@@ -14,6 +14,6 @@ type Restricter struct{}
 // replaced with a new value. If the user does not have the permission to see
 // one key, it is not allowed to remove that key, the value has to be set to
 // nil.
-func (r *Restricter) Restrict(uid int, data map[string]json.RawMessage) {
-
+func (r *Restricter) Restrict(uid int, data map[string]json.RawMessage) error {
+	return nil
 }
@@ -4,12 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // MockDatastore implements the autoupdate.Datastore interface.
 type MockDatastore struct {
+	// calls is used with atomic and counts the calls to Get().
+	calls int32
+
 	changes chan []string
 	done    chan struct{}
 	DatastoreValues
@@ -35,7 +42,16 @@ func NewMockDatastore() *MockDatastore {
 // If the key ends with "_ids", "[1,2]" is returned.
 //
 // In any other case, "some value" is returned.
+//
+// If a latency was set with SimulateLatency for one of the keys, the call
+// blocks for this duration or until the context is done.
 func (d *MockDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMessage, error) {
+	atomic.AddInt32(&d.calls, 1)
+
+	if err := d.DatastoreValues.wait(ctx, keys); err != nil {
+		return nil, err
+	}
+
 	data := make(map[string]json.RawMessage, len(keys))
 	for _, key := range keys {
 		value, _, err := d.DatastoreValues.Value(key)
@@ -53,6 +69,12 @@ func (d *MockDatastore) Get(ctx context.Context, keys ...string) ([]json.RawMess
 	return values, nil
 }
 
+// CollectionIDs returns the ids of all objects of the collection, that have a
+// value for the field id in the Data attribute.
+func (d *MockDatastore) CollectionIDs(ctx context.Context, collection string) ([]int, error) {
+	return d.DatastoreValues.collectionIDs(collection), nil
+}
+
 // KeysChanged returnes keys that have changed. Blocks until keys are send with
 // the Send-method.
 func (d *MockDatastore) KeysChanged() ([]string, error) {
@@ -69,6 +91,18 @@ func (d *MockDatastore) Send(keys []string) {
 	d.changes <- keys
 }
 
+// Calls returns how often Get() was called since the mock was created or
+// since the last call to Reset().
+func (d *MockDatastore) Calls() int {
+	return int(atomic.LoadInt32(&d.calls))
+}
+
+// Reset removes all values and latencies and resets the number of calls.
+func (d *MockDatastore) Reset() {
+	d.DatastoreValues.Reset()
+	atomic.StoreInt32(&d.calls, 0)
+}
+
 // Close cleans up after the Mock is used.
 func (d *MockDatastore) Close() {
 	close(d.done)
@@ -79,6 +113,78 @@ type DatastoreValues struct {
 	mu       sync.RWMutex
 	Data     map[string]json.RawMessage
 	OnlyData bool
+
+	latency map[string]time.Duration
+}
+
+// SetKeyData sets the value of one key.
+//
+// This does not send a KeysChanged signal.
+func (d *DatastoreValues) SetKeyData(key string, value json.RawMessage) {
+	d.SetBulk(map[string]json.RawMessage{key: value})
+}
+
+// SetBulk sets the values of many keys. It is the same as calling SetKeyData
+// for each key. Other keys are not changed.
+//
+// This does not send a KeysChanged signal.
+func (d *DatastoreValues) SetBulk(data map[string]json.RawMessage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.Data == nil {
+		d.Data = make(map[string]json.RawMessage, len(data))
+	}
+
+	for key, value := range data {
+		d.Data[key] = value
+	}
+}
+
+// Reset removes all values and latencies. The attribute OnlyData is not
+// changed.
+func (d *DatastoreValues) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.Data = nil
+	d.latency = nil
+}
+
+// SimulateLatency lets the next fetch of the key block for the duration d.
+func (d *DatastoreValues) SimulateLatency(key string, dur time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.latency == nil {
+		d.latency = make(map[string]time.Duration)
+	}
+	d.latency[key] = dur
+}
+
+// wait blocks for the longest latency of the given keys or until the context
+// is done. The latencies of the keys are removed.
+func (d *DatastoreValues) wait(ctx context.Context, keys []string) error {
+	d.mu.Lock()
+	var dur time.Duration
+	for _, key := range keys {
+		if l := d.latency[key]; l > dur {
+			dur = l
+		}
+		delete(d.latency, key)
+	}
+	d.mu.Unlock()
+
+	if dur == 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(dur):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // Value returns a value for a key. If the value does not exist, the second
@@ -108,6 +214,33 @@ func (d *DatastoreValues) Value(key string) (json.RawMessage, bool, error) {
 	}
 }
 
+// collectionIDs returns the sorted ids of all objects of the collection, that
+// have a value for the field id in Data. The default values are not used.
+func (d *DatastoreValues) collectionIDs(collection string) []int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var ids []int
+	for key, value := range d.Data {
+		parts := strings.Split(key, "/")
+		if len(parts) != 3 || parts[0] != collection || parts[2] != "id" {
+			continue
+		}
+
+		if len(value) == 0 || string(value) == "null" {
+			continue
+		}
+
+		id, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
 // Update updates the values from the Datastore.
 //
 // This does not send a KeysChanged signal.
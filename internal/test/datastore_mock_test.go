@@ -0,0 +1,77 @@
+package test_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/openslides/openslides-autoupdate-service/internal/test"
+)
+
+func TestMockDatastoreSetBulk(t *testing.T) {
+	data := map[string]json.RawMessage{
+		"user/1/name":  []byte(`"hugo"`),
+		"user/2/name":  []byte(`"emanuel"`),
+		"motion/1/foo": []byte(`null`),
+	}
+	keys := test.Str("user/1/name", "user/2/name", "motion/1/foo")
+
+	single := test.NewMockDatastore()
+	defer single.Close()
+	for k, v := range data {
+		single.SetKeyData(k, v)
+	}
+
+	bulk := test.NewMockDatastore()
+	defer bulk.Close()
+	bulk.SetBulk(data)
+
+	expect, err := single.Get(context.Background(), keys...)
+	if err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	got, err := bulk.Get(context.Background(), keys...)
+	if err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("SetBulk() values: %s, SetKeyData() values: %s", got, expect)
+	}
+
+	data["user/1/name"] = []byte(`"changed"`)
+	if v, _, _ := bulk.Value("user/1/name"); string(v) != `"hugo"` {
+		t.Errorf("Changing the map after SetBulk() changed the value to %s", v)
+	}
+}
+
+func TestMockDatastoreReset(t *testing.T) {
+	ds := test.NewMockDatastore()
+	defer ds.Close()
+	ds.OnlyData = true
+	ds.SetBulk(map[string]json.RawMessage{"user/1/name": []byte(`"hugo"`)})
+
+	if _, err := ds.Get(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	ds.Reset()
+
+	if got := ds.Calls(); got != 0 {
+		t.Errorf("Calls() returned %d after Reset(), expected 0", got)
+	}
+
+	if _, exists, _ := ds.Value("user/1/name"); exists {
+		t.Errorf("Value of user/1/name exists after Reset()")
+	}
+
+	if _, err := ds.Get(context.Background(), "user/1/name"); err != nil {
+		t.Fatalf("Get() returned an unexpected error: %v", err)
+	}
+
+	if got := ds.Calls(); got != 1 {
+		t.Errorf("Calls() returned %d, expected 1", got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 )
 
@@ -12,8 +13,13 @@ type getManyRequest struct {
 	Keys []string `json:"requests"`
 }
 
+type getAllRequest struct {
+	Collection string `json:"collection"`
+}
+
 // DatastoreServer simulates the Datastore-Service. Only the methods required by the
-// autoupdate-service are supported. This is currently only the getMany method.
+// autoupdate-service are supported. This are the getMany and the getAll
+// method. getAll only returns the field id of the objects in Data.
 //
 // Has to be created with NewDatastoreServer.
 type DatastoreServer struct {
@@ -26,6 +32,11 @@ type DatastoreServer struct {
 func NewDatastoreServer() *DatastoreServer {
 	ts := new(DatastoreServer)
 	ts.TS = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/get_all") {
+			ts.serveGetAll(w, r)
+			return
+		}
+
 		var data getManyRequest
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
 			http.Error(w, fmt.Sprintf("Invalid json input: %v", err), http.StatusBadRequest)
@@ -33,6 +44,10 @@ func NewDatastoreServer() *DatastoreServer {
 		}
 		defer r.Body.Close()
 
+		if err := ts.DatastoreValues.wait(r.Context(), data.Keys); err != nil {
+			return
+		}
+
 		responceData := make(map[string]map[string]map[string]json.RawMessage)
 		for _, key := range data.Keys {
 			value, exist, err := ts.DatastoreValues.Value(key)
@@ -62,3 +77,22 @@ func NewDatastoreServer() *DatastoreServer {
 	}))
 	return ts
 }
+
+// serveGetAll returns the ids of all objects of a collection in the format of
+// the getAll method.
+func (ts *DatastoreServer) serveGetAll(w http.ResponseWriter, r *http.Request) {
+	var data getAllRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid json input: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	responceData := make(map[string]map[string]int)
+	for _, id := range ts.DatastoreValues.collectionIDs(data.Collection) {
+		responceData[strconv.Itoa(id)] = map[string]int{"id": id}
+	}
+
+	json.NewEncoder(w).Encode(responceData)
+	ts.RequestCount++
+}
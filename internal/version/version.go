@@ -0,0 +1,16 @@
+// Package version holds the build information of the service.
+//
+// The values are set at build time with ldflags. See the Makefile target
+// build.
+package version
+
+var (
+	// Version is the released version of the service.
+	Version = "dev"
+
+	// Commit is the git commit the service was built from.
+	Commit = "unknown"
+
+	// BuildTime is the time, the service was built.
+	BuildTime = "unknown"
+)